@@ -3,6 +3,7 @@ CREATE TABLE "users" (
   "hashed_password" varchar NOT NULL,
   "full_name" varchar NOT NULL,
   "email" varchar UNIQUE NOT NULL,
+  "role" varchar NOT NULL DEFAULT 'depositor',
   "password_changed_at" timestamptz NOT NULL DEFAULT '0001-01-01 00:00:00Z',
   "created_at" timestamptz NOT NULL DEFAULT (now())
 );
@@ -53,3 +54,419 @@ ALTER TABLE "entries" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id"
 ALTER TABLE "transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
 
 ALTER TABLE "transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+CREATE TABLE "sessions" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "refresh_token" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "is_blocked" boolean NOT NULL DEFAULT false,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+ALTER TABLE "sessions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "revoked_tokens" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "revoked_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "user_revocations" (
+  "username" varchar PRIMARY KEY,
+  "revoked_before" timestamptz NOT NULL,
+  "expires_at" timestamptz NOT NULL
+);
+
+CREATE INDEX ON "revoked_tokens" ("expires_at");
+
+CREATE INDEX ON "sessions" ("username");
+
+COMMENT ON COLUMN "user_revocations"."revoked_before" IS 'Tokens issued at or before this time are rejected';
+
+ALTER TABLE "revoked_tokens" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "user_revocations" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "totp_secrets" (
+  "username" varchar PRIMARY KEY,
+  "encrypted_secret" bytea NOT NULL,
+  "is_enabled" boolean NOT NULL DEFAULT false,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "enabled_at" timestamptz
+);
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "hashed_code" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "hashed_code");
+
+COMMENT ON COLUMN "totp_secrets"."last_used_step" IS 'Time step of the last accepted code, older codes are rejected to prevent replays';
+
+ALTER TABLE "totp_secrets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "totp_secrets" ("username") ON DELETE CASCADE;
+
+CREATE TABLE "login_throttles" (
+  "scope" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "failed_attempts" integer NOT NULL DEFAULT 1,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+  "locked_until" timestamptz,
+  PRIMARY KEY ("scope", "subject")
+);
+
+COMMENT ON COLUMN "login_throttles"."scope" IS 'Either username or client_ip';
+
+COMMENT ON COLUMN "login_throttles"."locked_until" IS 'Login attempts are rejected until this time';
+
+CREATE TABLE "password_resets" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "hashed_token" varchar UNIQUE NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "email_outbox" (
+  "id" bigserial PRIMARY KEY,
+  "to_address" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "body" text NOT NULL,
+  "attempts" integer NOT NULL DEFAULT 0,
+  "last_error" varchar,
+  "sent_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "password_resets" ("username");
+
+CREATE INDEX ON "email_outbox" ("sent_at");
+
+ALTER TABLE "password_resets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "verify_emails" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "hashed_secret_code" varchar NOT NULL,
+  "is_used" bool NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL DEFAULT (now() + interval '15 minutes')
+);
+
+CREATE INDEX ON "verify_emails" ("username");
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+-- Users who registered before emails were verified keep access to their accounts
+ALTER TABLE "users" ADD COLUMN "is_email_verified" bool NOT NULL DEFAULT true;
+
+ALTER TABLE "users" ALTER COLUMN "is_email_verified" SET DEFAULT false;
+
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar UNIQUE NOT NULL,
+  "hashed_key" varchar NOT NULL,
+  "scopes" varchar NOT NULL,
+  "expires_at" timestamptz,
+  "last_used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("username");
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'public part of the key, used to look it up';
+
+COMMENT ON COLUMN "api_keys"."scopes" IS 'space separated list of scopes';
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "oauth_clients" (
+  "id" varchar PRIMARY KEY,
+  "owner" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "hashed_secret" varchar,
+  "redirect_uris" varchar NOT NULL,
+  "scopes" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE "oauth_authorization_codes" (
+  "hashed_code" varchar PRIMARY KEY,
+  "client_id" varchar NOT NULL,
+  "username" varchar NOT NULL,
+  "redirect_uri" varchar NOT NULL,
+  "scopes" varchar NOT NULL,
+  "code_challenge" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "oauth_clients" ("owner");
+
+COMMENT ON COLUMN "oauth_clients"."hashed_secret" IS 'null for public clients';
+
+COMMENT ON COLUMN "oauth_clients"."redirect_uris" IS 'space separated list of redirect URIs';
+
+COMMENT ON COLUMN "oauth_authorization_codes"."code_challenge" IS 'PKCE S256 code challenge';
+
+ALTER TABLE "oauth_clients" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("client_id") REFERENCES "oauth_clients" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "oauth_authorization_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "webauthn_credentials" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "credential_id" bytea UNIQUE NOT NULL,
+  "public_key" bytea NOT NULL,
+  "sign_count" bigint NOT NULL DEFAULT 0,
+  "last_used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webauthn_credentials" ("username");
+
+COMMENT ON COLUMN "webauthn_credentials"."public_key" IS 'COSE encoded public key';
+
+COMMENT ON COLUMN "webauthn_credentials"."sign_count" IS 'signature counter of the authenticator, 0 if it does not count';
+
+ALTER TABLE "webauthn_credentials" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "transfer_challenges" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'awaiting_confirmation',
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "confirmed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "transfer_challenges" ("username");
+
+COMMENT ON COLUMN "transfer_challenges"."status" IS 'awaiting_confirmation or confirmed';
+
+COMMENT ON COLUMN "transfer_challenges"."transfer_id" IS 'transfer executed once the challenge was confirmed';
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE TABLE "dpop_proofs" (
+  "jti" varchar PRIMARY KEY,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "dpop_proofs" ("expires_at");
+
+COMMENT ON TABLE "dpop_proofs" IS 'DPoP proofs already used, kept until they are too old to be accepted';
+
+ALTER TABLE "sessions" ADD COLUMN "last_seen_at" timestamptz NOT NULL DEFAULT (now());
+
+CREATE INDEX ON "sessions" ("username", "user_agent");
+
+COMMENT ON COLUMN "sessions"."last_seen_at" IS 'last time the refresh token of the session was used';
+
+CREATE TABLE "idempotency_keys" (
+  "username" varchar NOT NULL,
+  "key" varchar NOT NULL,
+  "request_hash" varchar NOT NULL,
+  "response" jsonb NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("username", "key")
+);
+
+CREATE INDEX ON "idempotency_keys" ("expires_at");
+
+COMMENT ON COLUMN "idempotency_keys"."request_hash" IS 'sha256 of the request, so that the key cannot be reused for another request';
+
+COMMENT ON COLUMN "idempotency_keys"."response" IS 'response replayed to the retries of the request';
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "accounts" ADD COLUMN "overdraft_limit" bigint NOT NULL DEFAULT 0 CHECK ("overdraft_limit" >= 0);
+
+COMMENT ON COLUMN "accounts"."overdraft_limit" IS 'how far below zero transfers can take the balance';
+
+CREATE TABLE "fx_rates" (
+  "id" bigserial PRIMARY KEY,
+  "base_currency" varchar NOT NULL,
+  "quote_currency" varchar NOT NULL,
+  "rate" bigint NOT NULL CHECK ("rate" > 0),
+  "effective_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "fx_rates" ("base_currency", "quote_currency", "effective_at");
+
+COMMENT ON COLUMN "fx_rates"."rate" IS 'amount of quote currency for one unit of base currency, scaled by 10^8';
+
+COMMENT ON COLUMN "fx_rates"."effective_at" IS 'the rate applies from this time until the next rate of the currency pair';
+
+CREATE TABLE "fx_quotes" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "fx_rate_id" bigint NOT NULL,
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" bigint NOT NULL,
+  "spread_bps" integer NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "fx_quotes"."rate" IS 'rate locked for the transfer, spread included, scaled by 10^8';
+
+COMMENT ON COLUMN "fx_quotes"."spread_bps" IS 'spread taken on the rate, in basis points';
+
+COMMENT ON COLUMN "fx_quotes"."used_at" IS 'a quote locks its rate for a single transfer';
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("fx_rate_id") REFERENCES "fx_rates" ("id");
+
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "fx_rate" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "fx_spread_bps" integer;
+
+ALTER TABLE "transfers" ADD COLUMN "fx_quote_id" uuid;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'amount debited from the sender, in its currency';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited to the recipient, in its currency';
+
+COMMENT ON COLUMN "transfers"."fx_rate" IS 'rate of the currency conversion, spread included, scaled by 10^8';
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fx_quote_id") REFERENCES "fx_quotes" ("id");
+
+ALTER TABLE "transfer_challenges" ADD COLUMN "fx_quote_id" uuid;
+
+COMMENT ON COLUMN "transfer_challenges"."fx_quote_id" IS 'quote converting the amount of a cross-currency transfer';
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("fx_quote_id") REFERENCES "fx_quotes" ("id");
+
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "currency" varchar NOT NULL,
+  "execute_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "failure_reason" varchar,
+  "executed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfers" ("username");
+
+CREATE INDEX ON "scheduled_transfers" ("execute_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "scheduled_transfers"."status" IS 'pending, completed, failed or canceled';
+
+COMMENT ON COLUMN "scheduled_transfers"."transfer_id" IS 'transfer executed once the scheduled transfer completed';
+
+COMMENT ON COLUMN "scheduled_transfers"."failure_reason" IS 'why the transfer could not be executed, if it failed';
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE TABLE "standing_orders" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "currency" varchar NOT NULL,
+  "frequency" varchar NOT NULL,
+  "repeat_every" integer NOT NULL DEFAULT 1 CHECK ("repeat_every" > 0),
+  "day_of_month" integer,
+  "start_at" timestamptz NOT NULL,
+  "end_at" timestamptz,
+  "max_occurrences" integer,
+  "occurrences" integer NOT NULL DEFAULT 0,
+  "next_execution_at" timestamptz,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "standing_orders" ("username");
+
+CREATE INDEX ON "standing_orders" ("next_execution_at") WHERE "status" = 'active';
+
+COMMENT ON COLUMN "standing_orders"."frequency" IS 'weekly, monthly or last_business_day';
+
+COMMENT ON COLUMN "standing_orders"."repeat_every" IS 'number of weeks or months between occurrences';
+
+COMMENT ON COLUMN "standing_orders"."day_of_month" IS 'day of monthly standing orders';
+
+COMMENT ON COLUMN "standing_orders"."occurrences" IS 'number of runs generated so far';
+
+COMMENT ON COLUMN "standing_orders"."next_execution_at" IS 'time of the next run, null once the standing order has ended';
+
+COMMENT ON COLUMN "standing_orders"."status" IS 'active, paused, canceled or completed';
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD COLUMN "standing_order_id" bigint;
+
+COMMENT ON COLUMN "scheduled_transfers"."standing_order_id" IS 'standing order the transfer is a run of';
+
+CREATE INDEX ON "scheduled_transfers" ("standing_order_id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("standing_order_id") REFERENCES "standing_orders" ("id");
+
+ALTER TABLE "oauth_clients" ADD COLUMN "is_resource_server" bool NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "oauth_clients"."is_resource_server" IS 'set by an admin to let the client introspect tokens';
+
+ALTER TABLE "idempotency_keys" ADD COLUMN "transfer_challenge_id" uuid;
+
+COMMENT ON COLUMN "idempotency_keys"."transfer_challenge_id" IS 'challenge of a transfer awaiting step-up, the response is then the challenge';
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("transfer_challenge_id") REFERENCES "transfer_challenges" ("id");
@@ -7,6 +7,7 @@ import (
 
 	db "github.com/JMustang/OldBank/db/sqlc"
 	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 )
@@ -67,17 +68,19 @@ func (server *Server) getAccount(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
-	if account.Owner != authPayload.Username {
+	if account.Owner != authPayload.Username && !hasRole(authPayload, util.BankerRole, util.AdminRole) {
 		err := errors.New("account doesn't belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
 	}
 
 	ctx.JSON(http.StatusOK, account)
 }
 
 type listAccountRequest struct {
-	PageID   int32 `form:"page_id" binding:"required,min=1"`
-	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+	Owner    string `form:"owner" binding:"omitempty,alphanum"`
+	PageID   int32  `form:"page_id" binding:"required,min=1"`
+	PageSize int32  `form:"page_size" binding:"required,min=5,max=10"`
 }
 
 func (server *Server) listAccounts(ctx *gin.Context) {
@@ -88,8 +91,18 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 	}
 
 	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	owner := authPayload.Username
+	if req.Owner != "" && req.Owner != authPayload.Username {
+		if !hasRole(authPayload, util.BankerRole, util.AdminRole) {
+			err := errors.New("only bankers can list accounts of other users")
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		owner = req.Owner
+	}
+
 	arg := db.ListAccountsParams{
-		Owner:  authPayload.Username,
+		Owner:  owner,
 		Limit:  req.PageSize,
 		Offset: (req.PageID - 1) * req.PageSize,
 	}
@@ -102,3 +115,41 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, accounts)
 }
+
+type updateOverdraftLimitURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type updateOverdraftLimitRequest struct {
+	OverdraftLimit *int64 `json:"overdraft_limit" binding:"required,min=0"`
+}
+
+// updateOverdraftLimit sets how far below zero transfers can take the balance of an account. Reserved to bankers.
+func (server *Server) updateOverdraftLimit(ctx *gin.Context) {
+	var uri updateOverdraftLimitURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateOverdraftLimitRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.store.UpdateAccountOverdraftLimit(ctx, db.UpdateAccountOverdraftLimitParams{
+		ID:             uri.ID,
+		OverdraftLimit: *req.OverdraftLimit,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+}
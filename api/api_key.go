@@ -0,0 +1,227 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// apiKeyPrefix identifies API keys among the credentials of the authorization header
+	apiKeyPrefix       = "obk_"
+	apiKeyIDLength     = 8
+	apiKeySecretLength = 32
+)
+
+var (
+	errInvalidAPIKey   = errors.New("api key is invalid")
+	errExpiredAPIKey   = errors.New("api key has expired")
+	errAPIKeyForbidden = errors.New("api keys cannot be used to access this resource")
+
+	errScopedTokenForbidden = errors.New("scope-limited tokens cannot be used to access this resource")
+)
+
+type apiKeyResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	rsp := apiKeyResponse{
+		ID:        apiKey.ID,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    util.SplitScopes(apiKey.Scopes),
+		CreatedAt: apiKey.CreatedAt,
+	}
+	if apiKey.ExpiresAt.Valid {
+		rsp.ExpiresAt = &apiKey.ExpiresAt.Time
+	}
+	if apiKey.LastUsedAt.Valid {
+		rsp.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	return rsp
+}
+
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,scope"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	stepUpRequest
+}
+
+type createAPIKeyResponse struct {
+	// Key is only returned once, it cannot be retrieved afterwards
+	Key    string         `json:"key"`
+	APIKey apiKeyResponse `json:"api_key"`
+}
+
+// createAPIKey creates an API key acting on behalf of the authenticated user, restricted to the requested scopes.
+// A key can move money until it expires, so the user must re-authenticate to create one.
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		err := errors.New("expires_at must be in the future")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.reauthenticate(ctx, authPayload.Username, req.stepUpRequest) {
+		return
+	}
+
+	keyID, err := util.RandomSecret(apiKeyIDLength)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	secret, err := util.RandomSecret(apiKeySecretLength)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	prefix := apiKeyPrefix + keyID
+	key := fmt.Sprintf("%s_%s", prefix, secret)
+
+	arg := db.CreateApiKeyParams{
+		Username:  authPayload.Username,
+		Name:      req.Name,
+		Prefix:    prefix,
+		HashedKey: util.HashSecret(key),
+		Scopes:    util.JoinScopes(req.Scopes),
+	}
+	if req.ExpiresAt != nil {
+		arg.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	apiKey, err := server.store.CreateApiKey(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := createAPIKeyResponse{
+		Key:    key,
+		APIKey: newAPIKeyResponse(apiKey),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	apiKeys, err := server.store.ListApiKeys(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]apiKeyResponse, len(apiKeys))
+	for i, apiKey := range apiKeys {
+		rsp[i] = newAPIKeyResponse(apiKey)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type deleteAPIKeyRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteAPIKey(ctx *gin.Context) {
+	var req deleteAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	rows, err := server.store.DeleteApiKey(ctx, db.DeleteApiKeyParams{
+		ID:       req.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// isAPIKey returns true if the credential of the authorization header is an API key rather than a token
+func isAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// apiKeyAuthenticator authenticates the requests carrying an API key instead of a token
+type apiKeyAuthenticator struct {
+	store db.Store
+}
+
+// authenticate returns a payload with the scopes of the API key, acting on behalf of its user.
+// It returns errInvalidAPIKey or errExpiredAPIKey if the key cannot be used.
+func (authenticator *apiKeyAuthenticator) authenticate(ctx *gin.Context, key string) (*token.Payload, error) {
+	prefix, _, found := strings.Cut(key[len(apiKeyPrefix):], "_")
+	if !found {
+		return nil, errInvalidAPIKey
+	}
+
+	apiKey, err := authenticator.store.GetApiKeyByPrefix(ctx, apiKeyPrefix+prefix)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(util.HashSecret(key)), []byte(apiKey.HashedKey)) != 1 {
+		return nil, errInvalidAPIKey
+	}
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return nil, errExpiredAPIKey
+	}
+
+	user, err := authenticator.store.GetUser(ctx, apiKey.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	err = authenticator.store.UpdateApiKeyLastUsed(ctx, apiKey.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &token.Payload{
+		Username: user.Username,
+		Role:     user.Role,
+		IssuedAt: apiKey.CreatedAt,
+		Scopes:   util.SplitScopes(apiKey.Scopes),
+	}
+	if apiKey.ExpiresAt.Valid {
+		payload.ExpiredAt = apiKey.ExpiresAt.Time
+	}
+	return payload, nil
+}
@@ -0,0 +1,447 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, password := randomUser(t)
+	scopes := []string{util.AccountsReadScope, util.TransfersWriteScope}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"name":     "payroll",
+				"scopes":   scopes,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateApiKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "payroll", arg.Name)
+						require.Equal(t, "accounts:read transfers:write", arg.Scopes)
+						require.False(t, arg.ExpiresAt.Valid)
+						return db.ApiKey{
+							ID:        1,
+							Username:  arg.Username,
+							Name:      arg.Name,
+							Prefix:    arg.Prefix,
+							HashedKey: arg.HashedKey,
+							Scopes:    arg.Scopes,
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp createAPIKeyResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, strings.HasPrefix(rsp.Key, rsp.APIKey.Prefix+"_"))
+				require.True(t, isAPIKey(rsp.Key))
+				require.Equal(t, scopes, rsp.APIKey.Scopes)
+				require.Nil(t, rsp.APIKey.ExpiresAt)
+			},
+		},
+		{
+			name: "UnsupportedScope",
+			body: gin.H{
+				"name":     "payroll",
+				"scopes":   []string{"users:write"},
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "NoScopes",
+			body: gin.H{
+				"name":     "payroll",
+				"scopes":   []string{},
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredInThePast",
+			body: gin.H{
+				"name":       "payroll",
+				"scopes":     scopes,
+				"expires_at": time.Now().Add(-time.Hour),
+				"password":   password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPassword",
+			body: gin.H{
+				"name":     "payroll",
+				"scopes":   scopes,
+				"password": "wrong-password",
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name: "MissingPassword",
+			body: gin.H{
+				"name":   "payroll",
+				"scopes": scopes,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			body: gin.H{
+				"name":     "payroll",
+				"scopes":   scopes,
+				"password": password,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/users/me/api_keys"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDeleteAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		id            int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteApiKey(gomock.Any(), gomock.Eq(db.DeleteApiKeyParams{ID: 1, Username: user.Username})).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			id:   1,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteApiKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					DeleteApiKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/me/api_keys/%d", tc.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	key, apiKey := randomAPIKey(user.Username, util.AccountsReadScope)
+
+	testCases := []struct {
+		name          string
+		key           string
+		url           string
+		buildStubs    func(store *mockdb.MockStore, apiKey db.ApiKey)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			key:  key,
+			url:  fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				expectAPIKey(store, apiKey, user)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name: "MissingScope",
+			key:  key,
+			url:  "/transfers",
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				expectAPIKey(store, apiKey, user)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "WrongSecret",
+			key:  apiKey.Prefix + "_" + util.RandomString(32),
+			url:  fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().
+					GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownKey",
+			key:  key,
+			url:  fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().
+					GetApiKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredKey",
+			key:  key,
+			url:  fmt.Sprintf("/accounts/%d", account.ID),
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				apiKey.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+				store.EXPECT().
+					GetApiKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(apiKey, nil)
+				store.EXPECT().
+					UpdateApiKeyLastUsed(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RouteWithoutScopes",
+			key:  key,
+			url:  "/users/me/api_keys",
+			buildStubs: func(store *mockdb.MockStore, apiKey db.ApiKey) {
+				store.EXPECT().
+					GetApiKeyByPrefix(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store, apiKey)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			method := http.MethodGet
+			if tc.url == "/transfers" {
+				method = http.MethodPost
+			}
+			request, err := http.NewRequest(method, tc.url, bytes.NewReader([]byte("{}")))
+			require.NoError(t, err)
+
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, tc.key))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func randomAPIKey(username string, scopes ...string) (string, db.ApiKey) {
+	prefix := apiKeyPrefix + util.RandomString(apiKeyIDLength)
+	key := prefix + "_" + util.RandomString(apiKeySecretLength)
+
+	return key, db.ApiKey{
+		ID:        util.RandomInt(1, 1000),
+		Username:  username,
+		Name:      util.RandomOwner(),
+		Prefix:    prefix,
+		HashedKey: util.HashSecret(key),
+		Scopes:    util.JoinScopes(scopes),
+		CreatedAt: time.Now(),
+	}
+}
+
+func expectAPIKey(store *mockdb.MockStore, apiKey db.ApiKey, user db.User) {
+	store.EXPECT().
+		GetApiKeyByPrefix(gomock.Any(), gomock.Eq(apiKey.Prefix)).
+		Times(1).
+		Return(apiKey, nil)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		UpdateApiKeyLastUsed(gomock.Any(), gomock.Eq(apiKey.ID)).
+		Times(1).
+		Return(nil)
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+)
+
+var errUnknownClientCertificate = errors.New("client certificate is not mapped to a service identity")
+
+// clientCertAuthenticator authenticates internal callers by the client certificate of their TLS connection
+type clientCertAuthenticator struct {
+	// identities are the service identities by common name of their certificate
+	identities map[string]util.ServiceIdentity
+}
+
+// newClientCertAuthenticator maps client certificates to the service identities of TLS_SERVICE_IDENTITIES.
+// It returns nil if no service identity is configured.
+func newClientCertAuthenticator(config util.Config) (*clientCertAuthenticator, error) {
+	identities, err := util.ParseServiceIdentities(config.TLSServiceIdentities)
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, nil
+	}
+
+	clientAuth, err := tlsClientAuth(config.TLSClientAuth)
+	if err != nil {
+		return nil, err
+	}
+	if clientAuth == tls.NoClientCert {
+		return nil, errors.New("service identities require TLS client auth")
+	}
+
+	return &clientCertAuthenticator{identities: identities}, nil
+}
+
+// verifiedClientCertificate returns the client certificate of the request, if its chain was verified during the handshake
+func verifiedClientCertificate(request *http.Request) *x509.Certificate {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 || len(request.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return request.TLS.PeerCertificates[0]
+}
+
+// authenticate returns a payload with the service identity mapped to the common name of the certificate,
+// limited to its scopes and valid as long as the certificate.
+// It returns errUnknownClientCertificate if the certificate is not mapped.
+func (authenticator *clientCertAuthenticator) authenticate(certificate *x509.Certificate) (*token.Payload, error) {
+	identity, ok := authenticator.identities[certificate.Subject.CommonName]
+	if !ok {
+		return nil, errUnknownClientCertificate
+	}
+
+	payload := &token.Payload{
+		Username:  identity.Username(),
+		Role:      identity.Role,
+		IssuedAt:  certificate.NotBefore,
+		ExpiredAt: certificate.NotAfter,
+		Scopes:    identity.Scopes,
+	}
+	return payload, nil
+}
+
+// reservesUsername returns true if the username is the name of a service identity,
+// so that users cannot pass for a service
+func (authenticator *clientCertAuthenticator) reservesUsername(username string) bool {
+	for _, identity := range authenticator.identities {
+		if strings.EqualFold(identity.Name, username) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	authorizationTypeDPoP = "dpop"
+	dpopHeaderKey         = "DPoP"
+	// dpopThumbprintKey holds the thumbprint of the key the issued tokens must be bound to
+	dpopThumbprintKey      = "dpop_thumbprint"
+	defaultDPoPProofMaxAge = time.Minute
+	oauthErrorInvalidDPoP  = "invalid_dpop_proof"
+)
+
+var (
+	errMissingDPoPProof = errors.New("exactly one DPoP proof must be provided")
+	errDPoPKeyMismatch  = errors.New("token is bound to another DPoP key")
+)
+
+// dpopVerifier checks the DPoP proofs (RFC 9449) sent by clients to prove they hold the key of their tokens
+type dpopVerifier struct {
+	replayCache token.ReplayCache
+	maxAge      time.Duration
+	clockSkew   time.Duration
+	// externalURL is the URL clients reach the server at, when a proxy in front of it terminates TLS
+	// or rewrites the host. It is empty if the server is reached directly.
+	externalURL string
+}
+
+// parseDPoPExternalURL checks the configured external URL of the server, and drops its trailing slash
+// so that the path of the requests can be appended to it
+func parseDPoPExternalURL(rawURL string) (string, error) {
+	if rawURL == "" {
+		return "", nil
+	}
+
+	externalURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	if (externalURL.Scheme != "http" && externalURL.Scheme != "https") || externalURL.Host == "" ||
+		externalURL.RawQuery != "" || externalURL.Fragment != "" {
+		return "", fmt.Errorf("%s is not an absolute http or https URL without query", rawURL)
+	}
+	return strings.TrimSuffix(rawURL, "/"), nil
+}
+
+// verify checks the DPoP proof of the request. accessToken is the token the request is
+// authenticated with, it is empty for the requests obtaining new tokens.
+// Errors wrapping token.ErrInvalidDPoPProof are the fault of the client.
+func (verifier *dpopVerifier) verify(ctx *gin.Context, accessToken string) (*token.DPoPProof, error) {
+	values := ctx.Request.Header.Values(dpopHeaderKey)
+	if len(values) != 1 {
+		return nil, fmt.Errorf("%w: %v", token.ErrInvalidDPoPProof, errMissingDPoPProof)
+	}
+
+	proof, err := token.ParseDPoPProof(values[0])
+	if err != nil {
+		return nil, err
+	}
+
+	maxAge := verifier.maxAge
+	if maxAge <= 0 {
+		maxAge = defaultDPoPProofMaxAge
+	}
+	err = proof.Verify(ctx.Request.Method, verifier.requestURL(ctx), accessToken, maxAge, verifier.clockSkew)
+	if err != nil {
+		return nil, err
+	}
+
+	// The proof is remembered for as long as Verify would accept its iat
+	fresh, err := verifier.replayCache.Use(ctx, proof.ID, proof.IssuedAt.Add(maxAge+verifier.clockSkew))
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, fmt.Errorf("%w: proof has already been used", token.ErrInvalidDPoPProof)
+	}
+	return proof, nil
+}
+
+// requestURL rebuilds the URL the client sent the request to, without its query, to be compared with htu.
+// Behind a proxy, the scheme and host the client used are those of the external URL.
+func (verifier *dpopVerifier) requestURL(ctx *gin.Context) string {
+	if verifier.externalURL != "" {
+		return verifier.externalURL + ctx.Request.URL.Path
+	}
+
+	scheme := "http"
+	if ctx.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + ctx.Request.Host + ctx.Request.URL.Path
+}
+
+// bindDPoPKey lets the routes issuing tokens bind them to the key of a DPoP proof, if the client sent one.
+// errorBody formats the rejection of invalid proofs.
+func (server *Server) bindDPoPKey(errorBody func(err error) gin.H) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.GetHeader(dpopHeaderKey) == "" {
+			ctx.Next()
+			return
+		}
+
+		proof, err := server.dpopVerifier.verify(ctx, "")
+		if err != nil {
+			if errors.Is(err, token.ErrInvalidDPoPProof) {
+				ctx.AbortWithStatusJSON(http.StatusBadRequest, errorBody(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorBody(err))
+			return
+		}
+
+		ctx.Set(dpopThumbprintKey, proof.Thumbprint)
+		ctx.Next()
+	}
+}
+
+func oauthDPoPErrorResponse(err error) gin.H {
+	if errors.Is(err, token.ErrInvalidDPoPProof) {
+		return oauthErrorResponse(oauthErrorInvalidDPoP, err)
+	}
+	return oauthErrorResponse(oauthErrorServerError, err)
+}
+
+// createBoundToken creates a token bound to the DPoP key of the request, if the client sent a proof
+func (server *Server) createBoundToken(ctx *gin.Context, payload *token.Payload) (string, error) {
+	if thumbprint := ctx.GetString(dpopThumbprintKey); thumbprint != "" {
+		payload.Confirmation = &token.Confirmation{JWKThumbprint: thumbprint}
+	}
+	return server.tokenMaker.CreateTokenFromPayload(payload)
+}
+
+// tokenType is the token_type returned with an access token
+func tokenType(payload *token.Payload) string {
+	if payload.IsDPoPBound() {
+		return "DPoP"
+	}
+	return "Bearer"
+}
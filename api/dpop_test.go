@@ -0,0 +1,509 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testDPoPOrigin = "http://bank.example"
+
+func TestDPoPAuthMiddleware(t *testing.T) {
+	key := randomDPoPKey(t)
+	otherKey := randomDPoPKey(t)
+	authURL := testDPoPOrigin + "/auth"
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken := createDPoPBoundToken(t, tokenMaker, key)
+				addDPoPAuthorization(t, request, key, accessToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "BearerType",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken := createDPoPBoundToken(t, tokenMaker, key)
+				addDPoPAuthorization(t, request, key, accessToken)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingProof",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken := createDPoPBoundToken(t, tokenMaker, key)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeDPoP, accessToken))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Equal(t, `DPoP error="invalid_dpop_proof"`, recorder.Header().Get("WWW-Authenticate"))
+			},
+		},
+		{
+			name: "OtherKey",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken := createDPoPBoundToken(t, tokenMaker, key)
+				addDPoPAuthorization(t, request, otherKey, accessToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ProofForOtherToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken := createDPoPBoundToken(t, tokenMaker, key)
+				addDPoPAuthorization(t, request, key, createDPoPBoundToken(t, tokenMaker, key))
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeDPoP, accessToken))
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ProofForOtherURL",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken := createDPoPBoundToken(t, tokenMaker, key)
+				proof, err := token.NewDPoPProof(key, http.MethodGet, testDPoPOrigin+"/accounts", accessToken)
+				require.NoError(t, err)
+				request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeDPoP, accessToken))
+				request.Header.Set(dpopHeaderKey, proof)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnboundToken",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				accessToken, _, err := tokenMaker.CreateToken("user", util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				addDPoPAuthorization(t, request, key, accessToken)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+
+			server.router.GET(
+				"/auth",
+				authMiddleware(server.tokenMaker, server.revocationStore, server.dpopVerifier, nil, nil),
+				func(ctx *gin.Context) {
+					ctx.JSON(http.StatusOK, gin.H{})
+				},
+			)
+
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest(http.MethodGet, authURL, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestDPoPAuthMiddlewareReplayedProof(t *testing.T) {
+	server := newTestServer(t, nil)
+	key := randomDPoPKey(t)
+
+	server.router.GET(
+		"/auth",
+		authMiddleware(server.tokenMaker, server.revocationStore, server.dpopVerifier, nil, nil),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	accessToken := createDPoPBoundToken(t, server.tokenMaker, key)
+	request, err := http.NewRequest(http.MethodGet, testDPoPOrigin+"/auth", nil)
+	require.NoError(t, err)
+	addDPoPAuthorization(t, request, key, accessToken)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// The same proof cannot be sent twice
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestDPoPAuthMiddlewareBehindProxy(t *testing.T) {
+	server := newTestServer(t, nil)
+	key := randomDPoPKey(t)
+
+	// The proxy terminates TLS, so the server receives plain http requests
+	externalURL, err := parseDPoPExternalURL("https://bank.example/")
+	require.NoError(t, err)
+	server.dpopVerifier.externalURL = externalURL
+
+	server.router.GET(
+		"/auth",
+		authMiddleware(server.tokenMaker, server.revocationStore, server.dpopVerifier, nil, nil),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, gin.H{})
+		},
+	)
+
+	testCases := []struct {
+		name         string
+		proofURL     string
+		expectedCode int
+	}{
+		{
+			name:         "ExternalURL",
+			proofURL:     "https://bank.example/auth",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "InternalURL",
+			proofURL:     testDPoPOrigin + "/auth",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			accessToken := createDPoPBoundToken(t, server.tokenMaker, key)
+			request, err := http.NewRequest(http.MethodGet, testDPoPOrigin+"/auth", nil)
+			require.NoError(t, err)
+
+			proof, err := token.NewDPoPProof(key, http.MethodGet, tc.proofURL, accessToken)
+			require.NoError(t, err)
+			request.Header.Set(dpopHeaderKey, proof)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeDPoP, accessToken))
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
+
+func TestParseDPoPExternalURL(t *testing.T) {
+	externalURL, err := parseDPoPExternalURL("")
+	require.NoError(t, err)
+	require.Empty(t, externalURL)
+
+	externalURL, err = parseDPoPExternalURL("https://bank.example/api/")
+	require.NoError(t, err)
+	require.Equal(t, "https://bank.example/api", externalURL)
+
+	for _, rawURL := range []string{"bank.example", "ftp://bank.example", "https://bank.example?a=b", "https://"} {
+		_, err = parseDPoPExternalURL(rawURL)
+		require.Error(t, err, rawURL)
+	}
+}
+
+func TestLoginUserWithDPoP(t *testing.T) {
+	user, password := randomUser(t)
+	key := randomDPoPKey(t)
+	loginURL := testDPoPOrigin + "/users/login"
+
+	testCases := []struct {
+		name          string
+		setupProof    func(t *testing.T, request *http.Request)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OK",
+			setupProof: func(t *testing.T, request *http.Request) {
+				addDPoPProof(t, request, key, "")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ResetLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "DPoP", rsp.TokenType)
+
+				payload, err := tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.True(t, payload.IsDPoPBound())
+				require.Equal(t, dpopThumbprint(t, key), payload.Confirmation.JWKThumbprint)
+			},
+		},
+		{
+			name:       "WithoutProof",
+			setupProof: func(t *testing.T, request *http.Request) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ResetLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "Bearer", rsp.TokenType)
+
+				payload, err := tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.False(t, payload.IsDPoPBound())
+			},
+		},
+		{
+			name: "InvalidProof",
+			setupProof: func(t *testing.T, request *http.Request) {
+				proof, err := token.NewDPoPProof(key, http.MethodPost, testDPoPOrigin+"/users/login/mfa", "")
+				require.NoError(t, err)
+				request.Header.Set(dpopHeaderKey, proof)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{
+				"username": user.Username,
+				"password": password,
+			})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, loginURL, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupProof(t, request)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func TestRenewDPoPBoundAccessToken(t *testing.T) {
+	user, _ := randomUser(t)
+	key := randomDPoPKey(t)
+	otherKey := randomDPoPKey(t)
+	renewURL := testDPoPOrigin + "/tokens/renew_access"
+
+	testCases := []struct {
+		name          string
+		setupProof    func(t *testing.T, request *http.Request)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OK",
+			setupProof: func(t *testing.T, request *http.Request) {
+				addDPoPProof(t, request, key, "")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TouchSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "DPoP", rsp.TokenType)
+
+				payload, err := tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.True(t, payload.IsDPoPBound())
+				require.Equal(t, dpopThumbprint(t, key), payload.Confirmation.JWKThumbprint)
+			},
+		},
+		{
+			name:       "MissingProof",
+			setupProof: func(t *testing.T, request *http.Request) {},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TouchSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "OtherKey",
+			setupProof: func(t *testing.T, request *http.Request) {
+				addDPoPProof(t, request, otherKey, "")
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					TouchSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+			server := newTestServer(t, store)
+
+			payload, err := token.NewPayload(user.Username, user.Role, time.Hour)
+			require.NoError(t, err)
+			payload.Purpose = token.PurposeRefresh
+			payload.Confirmation = &token.Confirmation{JWKThumbprint: dpopThumbprint(t, key)}
+			refreshToken, err := server.tokenMaker.CreateTokenFromPayload(payload)
+			require.NoError(t, err)
+
+			store.EXPECT().
+				GetSession(gomock.Any(), gomock.Eq(payload.ID)).
+				Times(1).
+				Return(db.Session{
+					ID:           payload.ID,
+					Username:     user.Username,
+					RefreshToken: refreshToken,
+					ExpiresAt:    payload.ExpiredAt,
+				}, nil)
+
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, renewURL, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+			tc.setupProof(t, request)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, server.tokenMaker)
+		})
+	}
+}
+
+func randomDPoPKey(t *testing.T) ed25519.PrivateKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	return key
+}
+
+func dpopThumbprint(t *testing.T, key ed25519.PrivateKey) string {
+	thumbprint, err := token.JWKThumbprint(token.JSONWebKey{
+		KeyType: "OKP",
+		Curve:   "Ed25519",
+		X:       base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+	})
+	require.NoError(t, err)
+	return thumbprint
+}
+
+func createDPoPBoundToken(t *testing.T, tokenMaker token.Maker, key ed25519.PrivateKey) string {
+	payload, err := token.NewPayload("user", util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload.Confirmation = &token.Confirmation{JWKThumbprint: dpopThumbprint(t, key)}
+
+	accessToken, err := tokenMaker.CreateTokenFromPayload(payload)
+	require.NoError(t, err)
+	return accessToken
+}
+
+// addDPoPProof adds a proof of the key for the request, for accessToken if it is not empty
+func addDPoPProof(t *testing.T, request *http.Request, key ed25519.PrivateKey, accessToken string) {
+	proof, err := token.NewDPoPProof(key, request.Method, request.URL.String(), accessToken)
+	require.NoError(t, err)
+	request.Header.Set(dpopHeaderKey, proof)
+}
+
+func addDPoPAuthorization(t *testing.T, request *http.Request, key ed25519.PrivateKey, accessToken string) {
+	addDPoPProof(t, request, key, accessToken)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeDPoP, accessToken))
+}
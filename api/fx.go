@@ -0,0 +1,172 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const defaultFXQuoteDuration = time.Minute
+
+var errFxQuoteNotFound = errors.New("fx quote not found, expired or already used")
+
+type createFxRateRequest struct {
+	BaseCurrency  string `json:"base_currency" binding:"required,currency"`
+	QuoteCurrency string `json:"quote_currency" binding:"required,currency,nefield=BaseCurrency"`
+	// Rate is the amount of quote currency for one unit of base currency, scaled by util.FXRateScale
+	Rate        int64      `json:"rate" binding:"required,gt=0"`
+	EffectiveAt *time.Time `json:"effective_at"`
+}
+
+// createFxRate publishes the exchange rate of a currency pair, effective from now unless another time is given.
+// Reserved to bankers.
+func (server *Server) createFxRate(ctx *gin.Context) {
+	var req createFxRateRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	effectiveAt := time.Now()
+	if req.EffectiveAt != nil {
+		effectiveAt = *req.EffectiveAt
+	}
+
+	fxRate, err := server.store.CreateFxRate(ctx, db.CreateFxRateParams{
+		BaseCurrency:  req.BaseCurrency,
+		QuoteCurrency: req.QuoteCurrency,
+		Rate:          req.Rate,
+		EffectiveAt:   effectiveAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, fxRate)
+}
+
+type createFxQuoteRequest struct {
+	FromCurrency string `json:"from_currency" binding:"required,currency"`
+	ToCurrency   string `json:"to_currency" binding:"required,currency,nefield=FromCurrency"`
+	// Amount is optional, to preview how much the recipient would get
+	Amount int64 `json:"amount" binding:"omitempty,gt=0"`
+}
+
+type fxQuoteResponse struct {
+	ID           uuid.UUID `json:"id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	Rate         int64     `json:"rate"`
+	SpreadBps    int32     `json:"spread_bps"`
+	Amount       int64     `json:"amount,omitempty"`
+	ToAmount     int64     `json:"to_amount,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// createFxQuote locks the current exchange rate of a currency pair, spread included, for a single transfer of the user.
+func (server *Server) createFxQuote(ctx *gin.Context) {
+	var req createFxQuoteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fxRate, err := server.store.GetFxRate(ctx, db.GetFxRateParams{
+		BaseCurrency:  req.FromCurrency,
+		QuoteCurrency: req.ToCurrency,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			err := fmt.Errorf("no exchange rate from %s to %s", req.FromCurrency, req.ToCurrency)
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rate := util.ApplySpread(fxRate.Rate, server.config.FXSpreadBps)
+	var toAmount int64
+	if req.Amount > 0 {
+		toAmount, err = util.ConvertAmount(req.Amount, rate)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	duration := server.config.FXQuoteDuration
+	if duration <= 0 {
+		duration = defaultFXQuoteDuration
+	}
+
+	quoteID, err := uuid.NewRandom()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	quote, err := server.store.CreateFxQuote(ctx, db.CreateFxQuoteParams{
+		ID:           quoteID,
+		Username:     authPayload.Username,
+		FxRateID:     fxRate.ID,
+		FromCurrency: req.FromCurrency,
+		ToCurrency:   req.ToCurrency,
+		Rate:         rate,
+		SpreadBps:    server.config.FXSpreadBps,
+		ExpiresAt:    time.Now().Add(duration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := fxQuoteResponse{
+		ID:           quote.ID,
+		FromCurrency: quote.FromCurrency,
+		ToCurrency:   quote.ToCurrency,
+		Rate:         quote.Rate,
+		SpreadBps:    quote.SpreadBps,
+		Amount:       req.Amount,
+		ToAmount:     toAmount,
+		ExpiresAt:    quote.ExpiresAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// validFxQuote returns the quote of a cross-currency transfer, if it was given to the user for the currency
+// of the sender and can still be used. The transfer itself makes sure that the quote is only used once.
+func (server *Server) validFxQuote(ctx *gin.Context, username string, req transferRequest) (db.FxQuote, bool) {
+	quote, err := server.store.GetFxQuote(ctx, uuid.MustParse(req.QuoteID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errFxQuoteNotFound))
+			return quote, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return quote, false
+	}
+
+	if quote.Username != username || quote.UsedAt.Valid || time.Now().After(quote.ExpiresAt) {
+		ctx.JSON(http.StatusNotFound, errorResponse(errFxQuoteNotFound))
+		return quote, false
+	}
+
+	if quote.FromCurrency != req.Currency {
+		err := fmt.Errorf("fx quote currency mismatch: %s vs %s", quote.FromCurrency, req.Currency)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return quote, false
+	}
+
+	return quote, true
+}
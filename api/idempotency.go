@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	idempotencyKeyHeader          = "Idempotency-Key"
+	idempotentReplayedHeader      = "Idempotent-Replayed"
+	maxIdempotencyKeyLength       = 255
+	defaultIdempotencyKeyDuration = 24 * time.Hour
+)
+
+var (
+	errInvalidIdempotencyKey  = fmt.Errorf("idempotency key must be at most %d characters", maxIdempotencyKeyLength)
+	errIdempotencyKeyReused   = errors.New("idempotency key was already used for a different request")
+	errIdempotencyKeyConflict = errors.New("a request with the same idempotency key is being processed")
+)
+
+// idempotencyKey returns the idempotency key of the request, or nil if the client did not send one.
+// It writes a bad request response if the key is invalid.
+func (server *Server) idempotencyKey(ctx *gin.Context, username string, req transferRequest) (*db.IdempotencyKeyParams, bool) {
+	key := ctx.GetHeader(idempotencyKeyHeader)
+	if key == "" {
+		return nil, true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidIdempotencyKey))
+		return nil, false
+	}
+
+	duration := server.config.IdempotencyKeyDuration
+	if duration <= 0 {
+		duration = defaultIdempotencyKeyDuration
+	}
+
+	return &db.IdempotencyKeyParams{
+		Username:    username,
+		Key:         key,
+		RequestHash: hashTransferRequest(req),
+		ExpiresAt:   time.Now().Add(duration),
+	}, true
+}
+
+// hashTransferRequest hashes the fields of the request rather than its body,
+// so that retries serializing the same request differently are still recognized.
+func hashTransferRequest(req transferRequest) string {
+	return util.HashSecret(fmt.Sprintf("%d:%d:%d:%s:%s", req.FromAccountID, req.ToAccountID, req.Amount, req.Currency, req.QuoteID))
+}
+
+// replayTransfer writes the response recorded under the idempotency key, if any: the transfer,
+// or the challenge of a transfer awaiting step-up. It returns false if the key was not used yet, or has expired.
+func (server *Server) replayTransfer(ctx *gin.Context, key *db.IdempotencyKeyParams) bool {
+	record, err := server.store.GetIdempotencyKey(ctx, db.GetIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	if record.RequestHash != key.RequestHash {
+		ctx.JSON(http.StatusUnprocessableEntity, errorResponse(errIdempotencyKeyReused))
+		return true
+	}
+
+	if record.TransferChallengeID.Valid {
+		var challenge db.TransferChallenge
+		err = json.Unmarshal(record.Response, &challenge)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return true
+		}
+
+		ctx.Header(idempotentReplayedHeader, "true")
+		ctx.JSON(http.StatusAccepted, newTransferChallengeResponse(challenge))
+		return true
+	}
+
+	var result db.TransferTxResult
+	err = json.Unmarshal(record.Response, &result)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return true
+	}
+
+	ctx.Header(idempotentReplayedHeader, "true")
+	ctx.JSON(http.StatusOK, result)
+	return true
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+type introspectTokenRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// introspectTokenResponse describes a token in the RFC 7662 format.
+// Only Active is set for inactive tokens, so that nothing is revealed about them.
+type introspectTokenResponse struct {
+	Active   bool   `json:"active"`
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	Username string `json:"username,omitempty"`
+	Role     string `json:"role,omitempty"`
+	Exp      int64  `json:"exp,omitempty"`
+	Iat      int64  `json:"iat,omitempty"`
+	Nbf      int64  `json:"nbf,omitempty"`
+	Sub      string `json:"sub,omitempty"`
+	Jti      string `json:"jti,omitempty"`
+	Iss      string `json:"iss,omitempty"`
+	Aud      string `json:"aud,omitempty"`
+	// Cnf is the DPoP key the token is bound to (RFC 9449)
+	Cnf *token.Confirmation `json:"cnf,omitempty"`
+}
+
+// introspectToken lets internal services check if a token is active. They authenticate with their client
+// certificate, or as a confidential OAuth client flagged as a resource server by an admin.
+func (server *Server) introspectToken(ctx *gin.Context) {
+	var req introspectTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidRequest, err))
+		return
+	}
+
+	if !server.isServiceClientCertificate(ctx) {
+		client, ok := server.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+		if !ok {
+			return
+		}
+		if !client.HashedSecret.Valid || !client.IsResourceServer {
+			err := errors.New("only resource servers can introspect tokens")
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrorInvalidClient, err))
+			return
+		}
+	}
+
+	payload, active, err := server.activeToken(ctx, req.Token)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+
+	rsp := introspectTokenResponse{Active: active}
+	if active {
+		rsp.Scope = util.JoinScopes(payload.Scopes)
+		rsp.ClientID = payload.ClientID
+		rsp.Username = payload.Username
+		rsp.Role = payload.Role
+		rsp.Exp = payload.ExpiredAt.Unix()
+		rsp.Iat = payload.IssuedAt.Unix()
+		rsp.Sub = payload.Username
+		rsp.Jti = payload.ID.String()
+		rsp.Iss = payload.Issuer
+		rsp.Aud = payload.Audience
+		rsp.Cnf = payload.Confirmation
+		if !payload.NotBefore.IsZero() {
+			rsp.Nbf = payload.NotBefore.Unix()
+		}
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// isServiceClientCertificate returns true if the request comes with the verified client certificate of a service identity
+func (server *Server) isServiceClientCertificate(ctx *gin.Context) bool {
+	certificate := verifiedClientCertificate(ctx.Request)
+	if certificate == nil || server.clientCerts == nil {
+		return false
+	}
+
+	_, err := server.clientCerts.authenticate(certificate)
+	return err == nil
+}
+
+// activeToken verifies a token the same way it is checked when used.
+// Refresh tokens are only active as long as their session is.
+func (server *Server) activeToken(ctx *gin.Context, tokenString string) (*token.Payload, bool, error) {
+	payload, err := server.tokenMaker.VerifyToken(tokenString)
+	if err != nil || (payload.Purpose != "" && payload.Purpose != token.PurposeRefresh) {
+		return nil, false, nil
+	}
+
+	revoked, err := server.revocationStore.IsRevoked(ctx, payload)
+	if err != nil || revoked {
+		return nil, false, err
+	}
+
+	if payload.Purpose != token.PurposeRefresh {
+		return payload, true, nil
+	}
+
+	session, err := server.store.GetSession(ctx, payload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	active := !session.IsBlocked &&
+		session.Username == payload.Username &&
+		session.RefreshToken == tokenString &&
+		time.Now().Before(session.ExpiresAt)
+	return payload, active, nil
+}
@@ -0,0 +1,313 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntrospectTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, clientSecret := randomOAuthClient(user.Username, true)
+	client.IsResourceServer = true
+	confidentialClient, confidentialClientSecret := randomOAuthClient(user.Username, true)
+	publicClient, _ := randomOAuthClient(user.Username, false)
+	serviceCertificate := &x509.Certificate{Subject: pkix.Name{CommonName: "ops.internal"}}
+
+	testCases := []struct {
+		name          string
+		buildToken    func(t *testing.T, server *Server) (string, *token.Payload, db.Session)
+		setupClient   func(request *http.Request, form url.Values)
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload)
+	}{
+		{
+			name: "ActiveAccessToken",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				payload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+				payload.Scopes = []string{util.AccountsReadScope}
+				payload.ClientID = publicClient.ID
+
+				accessToken, err := server.tokenMaker.CreateTokenFromPayload(payload)
+				require.NoError(t, err)
+				return accessToken, payload, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(client.ID, clientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, client)
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				rsp := requireIntrospection(t, recorder, true)
+				require.Equal(t, util.AccountsReadScope, rsp.Scope)
+				require.Equal(t, publicClient.ID, rsp.ClientID)
+				require.Equal(t, user.Username, rsp.Username)
+				require.Equal(t, user.Username, rsp.Sub)
+				require.Equal(t, user.Role, rsp.Role)
+				require.Equal(t, payload.ID.String(), rsp.Jti)
+				require.Equal(t, payload.IssuedAt.Unix(), rsp.Iat)
+				require.Equal(t, payload.ExpiredAt.Unix(), rsp.Exp)
+			},
+		},
+		{
+			name: "ActiveRefreshToken",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				refreshToken, session := randomSession(t, server.tokenMaker, user.Username)
+				return refreshToken, nil, session
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				form.Set("client_id", client.ID)
+				form.Set("client_secret", clientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, client)
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				rsp := requireIntrospection(t, recorder, true)
+				require.Equal(t, user.Username, rsp.Username)
+				require.Empty(t, rsp.Scope)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				refreshToken, session := randomSession(t, server.tokenMaker, user.Username)
+				session.IsBlocked = true
+				return refreshToken, nil, session
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(client.ID, clientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, client)
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireIntrospection(t, recorder, false)
+			},
+		},
+		{
+			name: "RevokedToken",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				accessToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+
+				err = server.revocationStore.RevokeToken(context.Background(), payload.ID, payload.Username, payload.ExpiredAt)
+				require.NoError(t, err)
+				return accessToken, payload, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(client.ID, clientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, client)
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireIntrospection(t, recorder, false)
+			},
+		},
+		{
+			name: "ExpiredToken",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				accessToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, -time.Minute)
+				require.NoError(t, err)
+				return accessToken, payload, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(client.ID, clientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, client)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireIntrospection(t, recorder, false)
+			},
+		},
+		{
+			name: "MFAPendingToken",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				return randomMFAToken(t, server, user), nil, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(client.ID, clientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, client)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireIntrospection(t, recorder, false)
+			},
+		},
+		{
+			name: "PublicClient",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				return "token", nil, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				form.Set("client_id", publicClient.ID)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, publicClient)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauthErrorInvalidClient)
+			},
+		},
+		{
+			name: "NotResourceServer",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				return "token", nil, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(confidentialClient.ID, confidentialClientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, confidentialClient)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauthErrorInvalidClient)
+			},
+		},
+		{
+			name: "ServiceClientCertificate",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				accessToken, payload, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+				return accessToken, payload, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.TLS = &tls.ConnectionState{
+					PeerCertificates: []*x509.Certificate{serviceCertificate},
+					VerifiedChains:   [][]*x509.Certificate{{serviceCertificate}},
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				rsp := requireIntrospection(t, recorder, true)
+				require.Equal(t, user.Username, rsp.Username)
+			},
+		},
+		{
+			name: "WrongClientSecret",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				return "token", nil, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(client.ID, util.RandomString(32))
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				expectOAuthClient(store, client)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauthErrorInvalidClient)
+			},
+		},
+		{
+			name: "MissingToken",
+			buildToken: func(t *testing.T, server *Server) (string, *token.Payload, db.Session) {
+				return "", nil, db.Session{}
+			},
+			setupClient: func(request *http.Request, form url.Values) {
+				request.SetBasicAuth(client.ID, clientSecret)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder, payload *token.Payload) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidRequest)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+			server.clientCerts = &clientCertAuthenticator{
+				identities: map[string]util.ServiceIdentity{
+					"ops.internal": {Name: "ops", Role: util.AdminRole, Scopes: []string{util.AccountsReadScope}},
+				},
+			}
+
+			tokenString, payload, session := tc.buildToken(t, server)
+			tc.buildStubs(store, session)
+
+			recorder := httptest.NewRecorder()
+
+			form := url.Values{}
+			if tokenString != "" {
+				form.Set("token", tokenString)
+			}
+			request, err := http.NewRequest(http.MethodPost, "/oauth/introspect", nil)
+			require.NoError(t, err)
+			tc.setupClient(request, form)
+
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			request.Body = io.NopCloser(strings.NewReader(form.Encode()))
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder, payload)
+		})
+	}
+}
+
+func expectOAuthClient(store *mockdb.MockStore, client db.OauthClient) {
+	store.EXPECT().
+		GetOauthClient(gomock.Any(), gomock.Eq(client.ID)).
+		Times(1).
+		Return(client, nil)
+}
+
+func requireIntrospection(t *testing.T, recorder *httptest.ResponseRecorder, active bool) introspectTokenResponse {
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp introspectTokenResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, active, rsp.Active)
+	if !active {
+		require.Equal(t, introspectTokenResponse{}, rsp)
+	}
+	return rsp
+}
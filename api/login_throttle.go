@@ -0,0 +1,159 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// Scopes of the failed login attempts tracked in login_throttles
+const (
+	loginScopeUsername = "username"
+	loginScopeClientIP = "client_ip"
+)
+
+const (
+	defaultLoginMaxFailedAttempts      = 5
+	defaultLoginMaxFailedAttemptsPerIP = 20
+	defaultLoginBaseDelay              = time.Second
+	defaultLoginLockoutDuration        = 15 * time.Minute
+)
+
+var (
+	errInvalidCredentials   = errors.New("incorrect username or password")
+	errTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+)
+
+// checkLoginThrottle rejects the request with 429 if its username or client IP is locked out.
+// It returns false if the request was rejected.
+func (server *Server) checkLoginThrottle(ctx *gin.Context, username string) bool {
+	throttles, err := server.store.ListLoginThrottles(ctx, db.ListLoginThrottlesParams{
+		Username: username,
+		ClientIp: ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	var lockedUntil time.Time
+	for _, throttle := range throttles {
+		if throttle.LockedUntil.Valid && throttle.LockedUntil.Time.After(lockedUntil) {
+			lockedUntil = throttle.LockedUntil.Time
+		}
+	}
+
+	retryAfter := time.Until(lockedUntil)
+	if retryAfter > 0 {
+		ctx.Header("Retry-After", fmt.Sprint(int64(math.Ceil(retryAfter.Seconds()))))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(errTooManyLoginAttempts))
+		return false
+	}
+	return true
+}
+
+// recordLoginFailure counts a failed login attempt against the username and the client IP,
+// locking them out for a delay that grows with the number of recent failures.
+// The username is empty for passkey logins that never found out which user they were for,
+// those are only counted against the client IP.
+func (server *Server) recordLoginFailure(ctx *gin.Context, username string) error {
+	maxAttemptsPerIP := server.config.LoginMaxFailedAttemptsPerIP
+	if maxAttemptsPerIP <= 0 {
+		maxAttemptsPerIP = defaultLoginMaxFailedAttemptsPerIP
+	}
+	maxAttempts := server.config.LoginMaxFailedAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultLoginMaxFailedAttempts
+	}
+
+	if username != "" {
+		err := server.recordLoginScopeFailure(ctx, loginScopeUsername, username, maxAttempts)
+		if err != nil {
+			return err
+		}
+	}
+	return server.recordLoginScopeFailure(ctx, loginScopeClientIP, ctx.ClientIP(), maxAttemptsPerIP)
+}
+
+func (server *Server) recordLoginScopeFailure(ctx *gin.Context, scope string, subject string, maxAttempts int32) error {
+	lockoutDuration := server.loginLockoutDuration()
+	baseDelay := server.config.LoginBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultLoginBaseDelay
+	}
+
+	// Failures older than the lockout duration are forgotten
+	throttle, err := server.store.RecordLoginFailure(ctx, db.RecordLoginFailureParams{
+		Scope:       scope,
+		Subject:     subject,
+		WindowStart: time.Now().Add(-lockoutDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	delay := loginDelay(throttle.FailedAttempts, maxAttempts, baseDelay, lockoutDuration)
+	return server.store.LockLoginThrottle(ctx, db.LockLoginThrottleParams{
+		Scope:   scope,
+		Subject: subject,
+		LockedUntil: sql.NullTime{
+			Time:  time.Now().Add(delay),
+			Valid: true,
+		},
+	})
+}
+
+// loginLockoutDuration is how long failed login attempts are remembered, and the longest lockout
+func (server *Server) loginLockoutDuration() time.Duration {
+	if server.config.LoginLockoutDuration <= 0 {
+		return defaultLoginLockoutDuration
+	}
+	return server.config.LoginLockoutDuration
+}
+
+// loginDelay returns how long to wait after the given number of failed attempts:
+// the base delay doubles with every failure, until the lockout duration is reached.
+func loginDelay(failedAttempts int32, maxAttempts int32, baseDelay time.Duration, lockoutDuration time.Duration) time.Duration {
+	if failedAttempts >= maxAttempts {
+		return lockoutDuration
+	}
+
+	delay := baseDelay
+	for i := int32(1); i < failedAttempts && delay < lockoutDuration; i++ {
+		delay *= 2
+	}
+	if delay > lockoutDuration {
+		return lockoutDuration
+	}
+	return delay
+}
+
+type unlockUserURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+// unlockUser lifts the lockout of a user after too many failed login attempts.
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var uri unlockUserURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := server.store.ResetLoginThrottle(ctx, db.ResetLoginThrottleParams{
+		Scope:   loginScopeUsername,
+		Subject: uri.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
@@ -15,6 +15,11 @@ func newTestServer(t *testing.T, store db.Store) *Server {
 	config := util.Config{
 		TokenSymmetricKey:   util.RandomString(32),
 		AccessTokenDuration: time.Minute,
+		TOTPEncryptionKey:   util.RandomString(32),
+		RevocationStore:     "memory",
+		DPoPReplayCache:     "memory",
+		WebAuthnRPID:        "localhost",
+		WebAuthnOrigins:     testWebAuthnOrigin,
 	}
 
 	server, err := NewServer(config, store)
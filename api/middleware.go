@@ -16,10 +16,33 @@ const (
 	authorizationPayloadKey = "authorization_payload"
 )
 
-func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
+var (
+	errDPoPBoundToken   = errors.New("token is bound to a DPoP key and must be sent with the DPoP authorization type")
+	errDPoPUnboundToken = errors.New("token is not bound to a DPoP key")
+)
+
+// authMiddleware authenticates the request with the bearer token of the authorization header.
+// Tokens bound to a DPoP key must be sent with the DPoP authorization type and a fresh proof of the key.
+// API keys and scope-limited tokens are only accepted if apiKeys is not nil,
+// in which case the routes must check the scopes with authorizeScopes.
+// Requests without authorization header are authenticated by their verified client certificate
+// if clientCerts is not nil.
+func authMiddleware(tokenMaker token.Maker, revocationStore token.RevocationStore, dpop *dpopVerifier, apiKeys *apiKeyAuthenticator, clientCerts *clientCertAuthenticator) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
 		if len(authorizationHeader) == 0 {
+			if certificate := verifiedClientCertificate(ctx.Request); certificate != nil && clientCerts != nil {
+				payload, err := clientCerts.authenticate(certificate)
+				if err != nil {
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+					return
+				}
+
+				ctx.Set(authorizationPayloadKey, payload)
+				ctx.Next()
+				return
+			}
+
 			err := errors.New("authorization header is not provided")
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
@@ -33,12 +56,37 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 		}
 
 		authorizationType := strings.ToLower(fields[0])
-		if authorizationType != authorizationTypeBearer {
+		if authorizationType != authorizationTypeBearer && authorizationType != authorizationTypeDPoP {
 			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
 			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
 			return
 		}
 
+		if isAPIKey(fields[1]) {
+			if authorizationType == authorizationTypeDPoP {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errDPoPUnboundToken))
+				return
+			}
+			if apiKeys == nil {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errAPIKeyForbidden))
+				return
+			}
+
+			payload, err := apiKeys.authenticate(ctx, fields[1])
+			if err != nil {
+				if errors.Is(err, errInvalidAPIKey) || errors.Is(err, errExpiredAPIKey) {
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+					return
+				}
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
+			ctx.Set(authorizationPayloadKey, payload)
+			ctx.Next()
+			return
+		}
+
 		accessToken := fields[1]
 		payload, err := tokenMaker.VerifyToken(accessToken)
 		if err != nil {
@@ -46,7 +94,94 @@ func authMiddleware(tokenMaker token.Maker) gin.HandlerFunc {
 			return
 		}
 
+		if payload.Purpose != "" {
+			err := errors.New("token cannot be used to access this resource")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		if len(payload.Scopes) > 0 && apiKeys == nil {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errScopedTokenForbidden))
+			return
+		}
+
+		revoked, err := revocationStore.IsRevoked(ctx, payload)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		if revoked {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+			return
+		}
+
+		if !payload.IsDPoPBound() {
+			if authorizationType == authorizationTypeDPoP {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errDPoPUnboundToken))
+				return
+			}
+		} else {
+			if authorizationType != authorizationTypeDPoP {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errDPoPBoundToken))
+				return
+			}
+
+			proof, err := dpop.verify(ctx, accessToken)
+			if err != nil {
+				if errors.Is(err, token.ErrInvalidDPoPProof) {
+					ctx.Header("WWW-Authenticate", `DPoP error="invalid_dpop_proof"`)
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+					return
+				}
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			if proof.Thumbprint != payload.Confirmation.JWKThumbprint {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errDPoPKeyMismatch))
+				return
+			}
+		}
+
 		ctx.Set(authorizationPayloadKey, payload)
 		ctx.Next()
 	}
 }
+
+// authorizeRoles only lets through requests whose token carries one of the accessible roles.
+// It must run after authMiddleware.
+func authorizeRoles(accessibleRoles ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !hasRole(authPayload, accessibleRoles...) {
+			err := fmt.Errorf("role %s is not allowed to access this resource", authPayload.Role)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// authorizeScopes only lets through requests whose credential grants all the scopes.
+// Tokens without scopes have the full access of their user. It must run after authMiddleware.
+func authorizeScopes(scopes ...string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		if !authPayload.HasScopes(scopes...) {
+			err := fmt.Errorf("access to this resource requires the scopes: %s", strings.Join(scopes, ", "))
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+func hasRole(payload *token.Payload, roles ...string) bool {
+	for _, role := range roles {
+		if payload.Role == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,476 @@
+package api
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultOAuthCodeDuration        = 5 * time.Minute
+	defaultOAuthAccessTokenDuration = 15 * time.Minute
+	oauthClientIDLength             = 16
+	oauthClientSecretLength         = 32
+	oauthCodeLength                 = 32
+
+	oauthGrantTypeAuthCode = "authorization_code"
+)
+
+// Error codes of the OAuth 2.0 specification (RFC 6749)
+const (
+	oauthErrorInvalidRequest       = "invalid_request"
+	oauthErrorInvalidClient        = "invalid_client"
+	oauthErrorInvalidGrant         = "invalid_grant"
+	oauthErrorInvalidScope         = "invalid_scope"
+	oauthErrorUnsupportedGrantType = "unsupported_grant_type"
+	oauthErrorServerError          = "server_error"
+	oauthErrorAccessDenied         = "access_denied"
+)
+
+var (
+	errInvalidRedirectURI       = errors.New("redirect_uri is not registered for this client")
+	errInvalidAuthorizationCode = errors.New("authorization code is invalid, expired or already used")
+)
+
+// oauthErrorResponse formats an error as specified by RFC 6749
+func oauthErrorResponse(code string, err error) gin.H {
+	return gin.H{
+		"error":             code,
+		"error_description": err.Error(),
+	}
+}
+
+type createOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,scope"`
+	// Confidential clients authenticate to the token endpoint with a secret,
+	// public clients such as mobile apps only rely on PKCE
+	Confidential bool `json:"confidential"`
+}
+
+type oauthClientResponse struct {
+	ClientID       string    `json:"client_id"`
+	ClientSecret   string    `json:"client_secret,omitempty"`
+	Name           string    `json:"name"`
+	RedirectURIs   []string  `json:"redirect_uris"`
+	Scopes         []string  `json:"scopes"`
+	Confidential   bool      `json:"confidential"`
+	ResourceServer bool      `json:"resource_server"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:       client.ID,
+		Name:           client.Name,
+		RedirectURIs:   strings.Fields(client.RedirectUris),
+		Scopes:         util.SplitScopes(client.Scopes),
+		Confidential:   client.HashedSecret.Valid,
+		ResourceServer: client.IsResourceServer,
+		CreatedAt:      client.CreatedAt,
+	}
+}
+
+// createOAuthClient registers a third-party app allowed to request access to the accounts of users
+func (server *Server) createOAuthClient(ctx *gin.Context) {
+	var req createOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	clientID, err := util.RandomSecret(oauthClientIDLength)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.CreateOauthClientParams{
+		ID:           clientID,
+		Owner:        authPayload.Username,
+		Name:         req.Name,
+		RedirectUris: strings.Join(req.RedirectURIs, " "),
+		Scopes:       util.JoinScopes(req.Scopes),
+	}
+
+	var clientSecret string
+	if req.Confidential {
+		clientSecret, err = util.RandomSecret(oauthClientSecretLength)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		arg.HashedSecret = sql.NullString{String: util.HashSecret(clientSecret), Valid: true}
+	}
+
+	client, err := server.store.CreateOauthClient(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newOAuthClientResponse(client)
+	rsp.ClientSecret = clientSecret
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type updateOAuthClientResourceServerURI struct {
+	ID string `uri:"id" binding:"required"`
+}
+
+type updateOAuthClientResourceServerRequest struct {
+	ResourceServer *bool `json:"resource_server" binding:"required"`
+}
+
+// updateOAuthClientResourceServer lets an admin flag a confidential client as a resource server, allowed to introspect tokens
+func (server *Server) updateOAuthClientResourceServer(ctx *gin.Context) {
+	var uri updateOAuthClientResourceServerURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateOAuthClientResourceServerRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client, err := server.store.GetOauthClient(ctx, uri.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if *req.ResourceServer && !client.HashedSecret.Valid {
+		err := errors.New("only confidential clients can be resource servers")
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	client, err = server.store.UpdateOauthClientResourceServer(ctx, db.UpdateOauthClientResourceServerParams{
+		ID:               uri.ID,
+		IsResourceServer: *req.ResourceServer,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newOAuthClientResponse(client))
+}
+
+type authorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required,eq=code"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope" binding:"required"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" binding:"required,eq=S256"`
+}
+
+type oauthConsentResponse struct {
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name"`
+	RedirectURI string   `json:"redirect_uri"`
+	Scopes      []string `json:"scopes"`
+}
+
+// getOAuthConsent validates an authorization request and returns what the user is asked to consent to
+func (server *Server) getOAuthConsent(ctx *gin.Context) {
+	var req authorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidRequest, err))
+		return
+	}
+
+	client, scopes, ok := server.validAuthorizeRequest(ctx, req)
+	if !ok {
+		return
+	}
+
+	rsp := oauthConsentResponse{
+		ClientID:    client.ID,
+		ClientName:  client.Name,
+		RedirectURI: req.RedirectURI,
+		Scopes:      scopes,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type authorizeConsentRequest struct {
+	authorizeRequest
+	Approve bool `json:"approve"`
+}
+
+type authorizeResponse struct {
+	// RedirectTo is where the user agent must be sent back, carrying either the code or the error
+	RedirectTo string `json:"redirect_to"`
+}
+
+// authorizeOAuthClient records the consent of the user and issues an authorization code to the client
+func (server *Server) authorizeOAuthClient(ctx *gin.Context) {
+	var req authorizeConsentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidRequest, err))
+		return
+	}
+
+	client, scopes, ok := server.validAuthorizeRequest(ctx, req.authorizeRequest)
+	if !ok {
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !req.Approve {
+		params.Set("error", oauthErrorAccessDenied)
+		ctx.JSON(http.StatusOK, authorizeResponse{RedirectTo: redirectURL(req.RedirectURI, params)})
+		return
+	}
+
+	code, err := util.RandomSecret(oauthCodeLength)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+
+	duration := server.config.OAuthCodeDuration
+	if duration <= 0 {
+		duration = defaultOAuthCodeDuration
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	_, err = server.store.CreateOauthAuthorizationCode(ctx, db.CreateOauthAuthorizationCodeParams{
+		HashedCode:    util.HashSecret(code),
+		ClientID:      client.ID,
+		Username:      authPayload.Username,
+		RedirectUri:   req.RedirectURI,
+		Scopes:        util.JoinScopes(scopes),
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(duration),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+
+	params.Set("code", code)
+	ctx.JSON(http.StatusOK, authorizeResponse{RedirectTo: redirectURL(req.RedirectURI, params)})
+}
+
+// validAuthorizeRequest checks the client, redirect URI and scopes of an authorization request.
+// It returns false if the request was rejected.
+func (server *Server) validAuthorizeRequest(ctx *gin.Context, req authorizeRequest) (db.OauthClient, []string, bool) {
+	client, err := server.store.GetOauthClient(ctx, req.ClientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidClient, err))
+			return client, nil, false
+		}
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return client, nil, false
+	}
+
+	if !containsField(client.RedirectUris, req.RedirectURI) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidRequest, errInvalidRedirectURI))
+		return client, nil, false
+	}
+
+	scopes := util.SplitScopes(req.Scope)
+	for _, scope := range scopes {
+		if !containsField(client.Scopes, scope) {
+			err := fmt.Errorf("scope %s is not allowed for this client", scope)
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidScope, err))
+			return client, nil, false
+		}
+	}
+	if len(scopes) == 0 {
+		err := errors.New("at least one scope must be requested")
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidScope, err))
+		return client, nil, false
+	}
+
+	return client, scopes, true
+}
+
+type oauthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code" binding:"required"`
+	RedirectURI  string `form:"redirect_uri" binding:"required"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier" binding:"required"`
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// createOAuthToken exchanges an authorization code for a scope-limited access token
+func (server *Server) createOAuthToken(ctx *gin.Context) {
+	var req oauthTokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidRequest, err))
+		return
+	}
+
+	if req.GrantType != oauthGrantTypeAuthCode {
+		err := fmt.Errorf("grant type %s is not supported", req.GrantType)
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorUnsupportedGrantType, err))
+		return
+	}
+
+	client, ok := server.authenticateOAuthClient(ctx, req.ClientID, req.ClientSecret)
+	if !ok {
+		return
+	}
+
+	// The code is only used up once the request is known to come from the client it was issued to,
+	// so that a request with the wrong client or verifier cannot burn the code of the user
+	code, err := server.store.GetOauthAuthorizationCode(ctx, util.HashSecret(req.Code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidGrant, errInvalidAuthorizationCode))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+
+	if code.ClientID != client.ID || code.RedirectUri != req.RedirectURI {
+		err := errors.New("authorization code was issued to another client or redirect_uri")
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidGrant, err))
+		return
+	}
+	if !util.VerifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		err := errors.New("code_verifier does not match the code challenge")
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidGrant, err))
+		return
+	}
+
+	// Only one of concurrent requests with the same code gets to use it
+	code, err = server.store.UseOauthAuthorizationCode(ctx, code.HashedCode)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthErrorInvalidGrant, errInvalidAuthorizationCode))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, code.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+
+	duration := server.oauthAccessTokenDuration()
+	payload, err := token.NewPayload(user.Username, user.Role, duration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+	payload.Scopes = util.SplitScopes(code.Scopes)
+	payload.ClientID = client.ID
+
+	accessToken, err := server.createBoundToken(ctx, payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return
+	}
+
+	rsp := oauthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   tokenType(payload),
+		ExpiresIn:   int64(duration / time.Second),
+		Scope:       code.Scopes,
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) oauthAccessTokenDuration() time.Duration {
+	if server.config.OAuthAccessTokenDuration <= 0 {
+		return defaultOAuthAccessTokenDuration
+	}
+	return server.config.OAuthAccessTokenDuration
+}
+
+// authenticateOAuthClient identifies the client calling the token endpoint, with HTTP basic or form credentials.
+// Confidential clients must present their secret. It returns false if the request was rejected.
+func (server *Server) authenticateOAuthClient(ctx *gin.Context, clientID string, clientSecret string) (db.OauthClient, bool) {
+	if username, password, ok := ctx.Request.BasicAuth(); ok {
+		clientID, clientSecret = username, password
+	}
+
+	client, err := server.store.GetOauthClient(ctx, clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrorInvalidClient, errors.New("unknown client")))
+			return client, false
+		}
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthErrorServerError, err))
+		return client, false
+	}
+
+	if client.HashedSecret.Valid &&
+		subtle.ConstantTimeCompare([]byte(util.HashSecret(clientSecret)), []byte(client.HashedSecret.String)) != 1 {
+		ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthErrorInvalidClient, errors.New("invalid client credentials")))
+		return client, false
+	}
+
+	return client, true
+}
+
+// redirectURL adds the parameters to the query of the redirect URI
+func redirectURL(redirectURI string, params url.Values) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// containsField returns true if the space separated list contains the value
+func containsField(list string, value string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
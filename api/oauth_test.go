@@ -0,0 +1,867 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+const testRedirectURI = "https://budget.example.com/callback"
+
+func TestCreateOAuthClientAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKPublic",
+			body: gin.H{
+				"name":          "Budget",
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{util.AccountsReadScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOauthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateOauthClientParams) (db.OauthClient, error) {
+						require.Equal(t, user.Username, arg.Owner)
+						require.Equal(t, testRedirectURI, arg.RedirectUris)
+						require.False(t, arg.HashedSecret.Valid)
+						return newOAuthClient(arg), nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthClientResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.ClientID)
+				require.Empty(t, rsp.ClientSecret)
+				require.False(t, rsp.Confidential)
+				require.Equal(t, []string{testRedirectURI}, rsp.RedirectURIs)
+			},
+		},
+		{
+			name: "OKConfidential",
+			body: gin.H{
+				"name":          "Budget",
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{util.AccountsReadScope},
+				"confidential":  true,
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOauthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateOauthClientParams) (db.OauthClient, error) {
+						require.True(t, arg.HashedSecret.Valid)
+						return newOAuthClient(arg), nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthClientResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.ClientSecret)
+				require.True(t, rsp.Confidential)
+			},
+		},
+		{
+			name: "InvalidRedirectURI",
+			body: gin.H{
+				"name":          "Budget",
+				"redirect_uris": []string{"not a url"},
+				"scopes":        []string{util.AccountsReadScope},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOauthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnsupportedScope",
+			body: gin.H{
+				"name":          "Budget",
+				"redirect_uris": []string{testRedirectURI},
+				"scopes":        []string{"users:write"},
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CreateOauthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/oauth/clients"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestUpdateOAuthClientResourceServerAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	admin, _ := randomUser(t)
+	admin.Role = util.AdminRole
+	client, _ := randomOAuthClient(user.Username, true)
+	publicClient, _ := randomOAuthClient(user.Username, false)
+
+	testCases := []struct {
+		name          string
+		clientID      string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			clientID: client.ID,
+			body:     gin.H{"resource_server": true},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOAuthClient(store, client)
+
+				updated := client
+				updated.IsResourceServer = true
+				store.EXPECT().
+					UpdateOauthClientResourceServer(gomock.Any(), gomock.Eq(db.UpdateOauthClientResourceServerParams{
+						ID:               client.ID,
+						IsResourceServer: true,
+					})).
+					Times(1).
+					Return(updated, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthClientResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.True(t, rsp.ResourceServer)
+			},
+		},
+		{
+			name:     "PublicClient",
+			clientID: publicClient.ID,
+			body:     gin.H{"resource_server": true},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOAuthClient(store, publicClient)
+				store.EXPECT().
+					UpdateOauthClientResourceServer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NotAdmin",
+			clientID: client.ID,
+			body:     gin.H{"resource_server": true},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateOauthClientResourceServer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "MissingFlag",
+			clientID: client.ID,
+			body:     gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpdateOauthClientResourceServer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "ClientNotFound",
+			clientID: client.ID,
+			body:     gin.H{"resource_server": true},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, admin.Username, admin.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(db.OauthClient{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpdateOauthClientResourceServer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/oauth/clients/%s/resource_server", tc.clientID)
+			request, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetOAuthConsentAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(user.Username, false)
+	codeChallenge := util.CodeChallengeS256(util.RandomString(64))
+
+	validQuery := func() url.Values {
+		return url.Values{
+			"response_type":         {"code"},
+			"client_id":             {client.ID},
+			"redirect_uri":          {testRedirectURI},
+			"scope":                 {util.AccountsReadScope},
+			"state":                 {util.RandomString(8)},
+			"code_challenge":        {codeChallenge},
+			"code_challenge_method": {"S256"},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		query         func() url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: validQuery,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp oauthConsentResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, client.Name, rsp.ClientName)
+				require.Equal(t, []string{util.AccountsReadScope}, rsp.Scopes)
+			},
+		},
+		{
+			name:  "UnknownClient",
+			query: validQuery,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthClient{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidClient)
+			},
+		},
+		{
+			name: "UnregisteredRedirectURI",
+			query: func() url.Values {
+				query := validQuery()
+				query.Set("redirect_uri", "https://evil.example.com/callback")
+				return query
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidRequest)
+			},
+		},
+		{
+			name: "ScopeNotAllowed",
+			query: func() url.Values {
+				query := validQuery()
+				query.Set("scope", util.AccountsReadScope+" "+util.TransfersWriteScope)
+				return query
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidScope)
+			},
+		},
+		{
+			name: "PlainCodeChallenge",
+			query: func() url.Values {
+				query := validQuery()
+				query.Set("code_challenge_method", "plain")
+				return query
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidRequest)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/oauth/authorize?" + tc.query().Encode()
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestAuthorizeOAuthClientAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	client, _ := randomOAuthClient(user.Username, false)
+	codeChallenge := util.CodeChallengeS256(util.RandomString(64))
+	state := util.RandomString(8)
+
+	body := func(approve bool) gin.H {
+		return gin.H{
+			"response_type":         "code",
+			"client_id":             client.ID,
+			"redirect_uri":          testRedirectURI,
+			"scope":                 util.AccountsReadScope,
+			"state":                 state,
+			"code_challenge":        codeChallenge,
+			"code_challenge_method": "S256",
+			"approve":               approve,
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder, codes []db.CreateOauthAuthorizationCodeParams)
+	}{
+		{
+			name: "Approved",
+			body: body(true),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, codes []db.CreateOauthAuthorizationCodeParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				redirectTo := requireRedirect(t, recorder)
+				require.Equal(t, state, redirectTo.Query().Get("state"))
+
+				require.Len(t, codes, 1)
+				require.Equal(t, util.HashSecret(redirectTo.Query().Get("code")), codes[0].HashedCode)
+				require.Equal(t, client.ID, codes[0].ClientID)
+				require.Equal(t, user.Username, codes[0].Username)
+				require.Equal(t, codeChallenge, codes[0].CodeChallenge)
+				require.Equal(t, util.AccountsReadScope, codes[0].Scopes)
+			},
+		},
+		{
+			name: "Denied",
+			body: body(false),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Eq(client.ID)).
+					Times(1).
+					Return(client, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, codes []db.CreateOauthAuthorizationCodeParams) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				redirectTo := requireRedirect(t, recorder)
+				require.Equal(t, oauthErrorAccessDenied, redirectTo.Query().Get("error"))
+				require.Empty(t, redirectTo.Query().Get("code"))
+				require.Empty(t, codes)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			var codes []db.CreateOauthAuthorizationCodeParams
+			store.EXPECT().
+				CreateOauthAuthorizationCode(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				DoAndReturn(func(_ interface{}, arg db.CreateOauthAuthorizationCodeParams) (db.OauthAuthorizationCode, error) {
+					codes = append(codes, arg)
+					return db.OauthAuthorizationCode{}, nil
+				})
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := "/oauth/authorize"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, codes)
+		})
+	}
+}
+
+func TestCreateOAuthTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	publicClient, _ := randomOAuthClient(user.Username, false)
+	confidentialClient, clientSecret := randomOAuthClient(user.Username, true)
+	code := util.RandomString(32)
+	codeVerifier := util.RandomString(64)
+
+	authorizationCode := func(client db.OauthClient) db.OauthAuthorizationCode {
+		return db.OauthAuthorizationCode{
+			HashedCode:    util.HashSecret(code),
+			ClientID:      client.ID,
+			Username:      user.Username,
+			RedirectUri:   testRedirectURI,
+			Scopes:        util.AccountsReadScope,
+			CodeChallenge: util.CodeChallengeS256(codeVerifier),
+			ExpiresAt:     time.Now().Add(time.Minute),
+		}
+	}
+
+	form := func(client db.OauthClient) url.Values {
+		return url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {testRedirectURI},
+			"client_id":     {client.ID},
+			"code_verifier": {codeVerifier},
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		form          func() url.Values
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name: "OKPublicClient",
+			form: func() url.Values {
+				return form(publicClient)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOAuthCode(store, publicClient, authorizationCode(publicClient), true)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "no-store", recorder.Header().Get("Cache-Control"))
+
+				var rsp oauthTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, "Bearer", rsp.TokenType)
+				require.Equal(t, util.AccountsReadScope, rsp.Scope)
+
+				payload, err := tokenMaker.VerifyToken(rsp.AccessToken)
+				require.NoError(t, err)
+				require.Equal(t, user.Username, payload.Username)
+				require.Equal(t, []string{util.AccountsReadScope}, payload.Scopes)
+				require.Equal(t, publicClient.ID, payload.ClientID)
+			},
+		},
+		{
+			name: "OKConfidentialClient",
+			form: func() url.Values {
+				values := form(confidentialClient)
+				values.Set("client_secret", clientSecret)
+				return values
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOAuthCode(store, confidentialClient, authorizationCode(confidentialClient), true)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WrongClientSecret",
+			form: func() url.Values {
+				values := form(confidentialClient)
+				values.Set("client_secret", util.RandomString(32))
+				return values
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Eq(confidentialClient.ID)).
+					Times(1).
+					Return(confidentialClient, nil)
+				store.EXPECT().
+					UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusUnauthorized, oauthErrorInvalidClient)
+			},
+		},
+		{
+			name: "WrongCodeVerifier",
+			form: func() url.Values {
+				values := form(publicClient)
+				values.Set("code_verifier", util.RandomString(64))
+				return values
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOAuthCode(store, publicClient, authorizationCode(publicClient), false)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidGrant)
+			},
+		},
+		{
+			name: "CodeOfAnotherClient",
+			form: func() url.Values {
+				return form(publicClient)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				expectOAuthCode(store, publicClient, authorizationCode(confidentialClient), false)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidGrant)
+			},
+		},
+		{
+			name: "UsedCode",
+			form: func() url.Values {
+				return form(publicClient)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					GetOauthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, sql.ErrNoRows)
+				store.EXPECT().
+					UseOauthAuthorizationCode(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidGrant)
+			},
+		},
+		{
+			name: "CodeUsedConcurrently",
+			form: func() url.Values {
+				return form(publicClient)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				code := authorizationCode(publicClient)
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(publicClient, nil)
+				store.EXPECT().
+					GetOauthAuthorizationCode(gomock.Any(), gomock.Eq(code.HashedCode)).
+					Times(1).
+					Return(code, nil)
+				store.EXPECT().
+					UseOauthAuthorizationCode(gomock.Any(), gomock.Eq(code.HashedCode)).
+					Times(1).
+					Return(db.OauthAuthorizationCode{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorInvalidGrant)
+			},
+		},
+		{
+			name: "UnsupportedGrantType",
+			form: func() url.Values {
+				values := form(publicClient)
+				values.Set("grant_type", "password")
+				return values
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetOauthClient(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				requireOAuthError(t, recorder, http.StatusBadRequest, oauthErrorUnsupportedGrantType)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/oauth/token"
+			request, err := http.NewRequest(http.MethodPost, url, strings.NewReader(tc.form().Encode()))
+			require.NoError(t, err)
+			request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, server.tokenMaker)
+		})
+	}
+}
+
+func TestOAuthAccessTokenScopes(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+		Times(1).
+		Return(account, nil)
+
+	server := newTestServer(t, store)
+
+	payload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+	payload.Scopes = []string{util.AccountsReadScope}
+	payload.ClientID = util.RandomString(16)
+	accessToken, err := server.tokenMaker.CreateTokenFromPayload(payload)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		method string
+		url    string
+		code   int
+	}{
+		{http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), http.StatusOK},
+		{http.MethodPost, "/transfers", http.StatusForbidden},
+		{http.MethodGet, "/users/me/api_keys", http.StatusUnauthorized},
+	} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(tc.method, tc.url, nil)
+		require.NoError(t, err)
+
+		request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, tc.code, recorder.Code, tc.url)
+	}
+}
+
+func randomOAuthClient(owner string, confidential bool) (db.OauthClient, string) {
+	client := db.OauthClient{
+		ID:           util.RandomString(16),
+		Owner:        owner,
+		Name:         util.RandomOwner(),
+		RedirectUris: testRedirectURI,
+		Scopes:       util.AccountsReadScope,
+		CreatedAt:    time.Now(),
+	}
+
+	var clientSecret string
+	if confidential {
+		clientSecret = util.RandomString(32)
+		client.HashedSecret = sql.NullString{String: util.HashSecret(clientSecret), Valid: true}
+	}
+	return client, clientSecret
+}
+
+// expectOAuthCode expects the client to be authenticated and its code to be looked up,
+// the code is only expected to be used if used is true
+func expectOAuthCode(store *mockdb.MockStore, client db.OauthClient, code db.OauthAuthorizationCode, used bool) {
+	store.EXPECT().
+		GetOauthClient(gomock.Any(), gomock.Eq(client.ID)).
+		Times(1).
+		Return(client, nil)
+	store.EXPECT().
+		GetOauthAuthorizationCode(gomock.Any(), gomock.Eq(code.HashedCode)).
+		Times(1).
+		Return(code, nil)
+
+	call := store.EXPECT().
+		UseOauthAuthorizationCode(gomock.Any(), gomock.Eq(code.HashedCode))
+	if used {
+		call.Times(1).Return(code, nil)
+	} else {
+		call.Times(0)
+	}
+}
+
+func requireOAuthError(t *testing.T, recorder *httptest.ResponseRecorder, status int, code string) {
+	require.Equal(t, status, recorder.Code)
+
+	var rsp struct {
+		Error string `json:"error"`
+	}
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, code, rsp.Error)
+}
+
+func requireRedirect(t *testing.T, recorder *httptest.ResponseRecorder) *url.URL {
+	var rsp authorizeResponse
+	err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+
+	redirectTo, err := url.Parse(rsp.RedirectTo)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(rsp.RedirectTo, testRedirectURI+"?"))
+	return redirectTo
+}
+
+func newOAuthClient(arg db.CreateOauthClientParams) db.OauthClient {
+	return db.OauthClient{
+		ID:           arg.ID,
+		Owner:        arg.Owner,
+		Name:         arg.Name,
+		HashedSecret: arg.HashedSecret,
+		RedirectUris: arg.RedirectUris,
+		Scopes:       arg.Scopes,
+		CreatedAt:    time.Now(),
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPasswordResetTokenDuration = time.Hour
+	passwordResetTokenLength          = 32
+)
+
+var errInvalidResetToken = errors.New("password reset token is invalid or has expired")
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword emails a password reset token to the user owning the email address, once it is verified.
+// The response is the same whether the address is registered or not.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.Status(http.StatusAccepted)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// Whoever changed the email address of the account may not own it
+	if !user.IsEmailVerified {
+		ctx.Status(http.StatusAccepted)
+		return
+	}
+
+	resetToken, err := util.RandomSecret(passwordResetTokenLength)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	duration := server.config.PasswordResetTokenDuration
+	if duration <= 0 {
+		duration = defaultPasswordResetTokenDuration
+	}
+
+	_, err = server.store.CreatePasswordResetTx(ctx, db.CreatePasswordResetTxParams{
+		CreatePasswordResetParams: db.CreatePasswordResetParams{
+			Username:    user.Username,
+			HashedToken: util.HashSecret(resetToken),
+			ExpiresAt:   time.Now().Add(duration),
+		},
+		Email: db.CreateOutboxEmailParams{
+			ToAddress: user.Email,
+			Subject:   "Reset your OldBank password",
+			Body:      server.passwordResetEmailBody(user, resetToken, duration),
+		},
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (server *Server) passwordResetEmailBody(user db.User, resetToken string, duration time.Duration) string {
+	link := resetToken
+	if server.config.PasswordResetURL != "" {
+		link = fmt.Sprintf("%s?token=%s", server.config.PasswordResetURL, url.QueryEscape(resetToken))
+	}
+
+	return fmt.Sprintf(
+		"Hello %s,\n\nWe received a request to reset your password. Use the following to choose a new one:\n\n%s\n\nIt expires in %s and can only be used once. If you did not ask for it, you can ignore this email.\n",
+		user.FullName,
+		link,
+		duration,
+	)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// resetPassword sets a new password with a reset token, then logs the user out everywhere.
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		HashedToken:    util.HashSecret(req.Token),
+		HashedPassword: hashedPassword,
+		RevokeTokens:   server.revokeTokensBeforePasswordChange,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidResetToken))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// revokeTokensBeforePasswordChange rejects every token of the user issued until the password change that is being committed.
+// A Postgres revocation is written with txRevocationStore, in the transaction changing the password, so that either both
+// or none are committed. A revocation kept in memory cannot fail, and outlives a password change that is rolled back.
+// It is timed on the clock issuing the tokens rather than on PasswordChangedAt, which is set by the database,
+// so that the tokens issued right after it are never revoked when the clocks drift apart.
+// The revocation is kept until the longest lived of those tokens has expired.
+func (server *Server) revokeTokensBeforePasswordChange(ctx context.Context, txRevocationStore token.RevocationStore, username string) error {
+	revocationStore := server.revocationStore
+	if _, ok := revocationStore.(*db.SQLRevocationStore); ok {
+		revocationStore = txRevocationStore
+	}
+
+	// truncated to the precision of the database, so that storing it does not round it up past the next tokens
+	now := time.Now().Truncate(time.Microsecond)
+	return revocationStore.RevokeUserTokens(ctx, username, now, now.Add(server.longestTokenLifetime()))
+}
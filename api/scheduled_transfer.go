@@ -0,0 +1,193 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultScheduledTransferInterval = 10 * time.Second
+	// maxScheduledTransfersPerRun bounds the transfers executed at each tick, so that a backlog is shared between instances
+	maxScheduledTransfersPerRun = 100
+)
+
+var (
+	errScheduledTransferNotFound = errors.New("scheduled transfer not found or no longer pending")
+	errExecuteAtInPast           = errors.New("execute_at must be in the future")
+	errScheduledTransferStepUp   = errors.New("transfers above the step-up threshold cannot be scheduled")
+)
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64     `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64     `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64     `json:"amount" binding:"required,gt=0"`
+	Currency      string    `json:"currency" binding:"required,currency"`
+	ExecuteAt     time.Time `json:"execute_at" binding:"required"`
+}
+
+// createScheduledTransfer schedules a transfer from an account of the user, executed in the background once it is due.
+// Transfers requiring step-up confirmation cannot be scheduled, since nobody is there to confirm them when they run.
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !req.ExecuteAt.After(time.Now()) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errExecuteAtInPast))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if server.requiresStepUp(req.Amount, req.Currency) {
+		ctx.JSON(http.StatusForbidden, errorResponse(errScheduledTransferStepUp))
+		return
+	}
+
+	scheduledTransfer, err := server.store.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		Username:      authPayload.Username,
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		ExecuteAt:     req.ExecuteAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+type listScheduledTransfersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+// listScheduledTransfers lists the scheduled transfers of the user, latest first, with their outcome once executed.
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfers, err := server.store.ListScheduledTransfers(ctx, db.ListScheduledTransfersParams{
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfers)
+}
+
+type cancelScheduledTransferRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// cancelScheduledTransfer cancels a scheduled transfer of the user that was not executed yet.
+func (server *Server) cancelScheduledTransfer(ctx *gin.Context) {
+	var req cancelScheduledTransferRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	scheduledTransfer, err := server.store.CancelScheduledTransfer(ctx, db.CancelScheduledTransferParams{
+		ID:       req.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errScheduledTransferNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduledTransfer)
+}
+
+// runScheduledTransfers periodically schedules the runs of the due standing orders,
+// then executes the scheduled transfers that are due.
+func (server *Server) runScheduledTransfers() {
+	interval := server.config.ScheduledTransferInterval
+	if interval <= 0 {
+		interval = defaultScheduledTransferInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		server.generateStandingOrderRuns(context.Background())
+		server.executeScheduledTransfers(context.Background())
+	}
+}
+
+// executeScheduledTransfers executes the due scheduled transfers, up to maxScheduledTransfersPerRun,
+// and returns how many were executed. A transfer failing for any other reason than insufficient funds
+// is recorded as failed too, so that it does not block the transfers due after it.
+func (server *Server) executeScheduledTransfers(ctx context.Context) int {
+	executed := 0
+	for executed < maxScheduledTransfersPerRun {
+		result, err := server.store.ExecuteScheduledTransferTx(ctx)
+		if err != nil && result.ScheduledTransfer.ID == 0 {
+			if !errors.Is(err, db.ErrRecordNotFound) {
+				log.Println("cannot claim scheduled transfer:", err)
+			}
+			return executed
+		}
+		executed++
+
+		if err != nil {
+			log.Printf("cannot execute scheduled transfer [%d]: %v", result.ScheduledTransfer.ID, err)
+			_, failErr := server.store.FailScheduledTransfer(ctx, db.FailScheduledTransferParams{
+				ID:            result.ScheduledTransfer.ID,
+				FailureReason: sql.NullString{String: err.Error(), Valid: true},
+			})
+			// another instance may have executed the transfer in the meantime
+			if failErr != nil && !errors.Is(failErr, db.ErrRecordNotFound) {
+				log.Printf("cannot record failure of scheduled transfer [%d]: %v", result.ScheduledTransfer.ID, failErr)
+				return executed
+			}
+			continue
+		}
+
+		log.Printf("scheduled transfer [%d] %s", result.ScheduledTransfer.ID, result.ScheduledTransfer.Status)
+	}
+	return executed
+}
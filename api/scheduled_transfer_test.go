@@ -0,0 +1,374 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomScheduledTransfer(username string, fromAccount, toAccount db.Account) db.ScheduledTransfer {
+	return db.ScheduledTransfer{
+		ID:            util.RandomInt(1, 1000),
+		Username:      username,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        util.RandomMoney(),
+		Currency:      fromAccount.Currency,
+		ExecuteAt:     time.Now().Add(time.Hour).Truncate(time.Second),
+		Status:        "pending",
+	}
+}
+
+func TestCreateScheduledTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	scheduledTransfer := randomScheduledTransfer(user1.Username, account1, account2)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		username      string
+		thresholds    string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          scheduledTransfer.Amount,
+				"currency":        util.USD,
+				"execute_at":      scheduledTransfer.ExecuteAt,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+
+				arg := db.CreateScheduledTransferParams{
+					Username:      user1.Username,
+					FromAccountID: account1.ID,
+					ToAccountID:   account2.ID,
+					Amount:        scheduledTransfer.Amount,
+					Currency:      util.USD,
+					ExecuteAt:     scheduledTransfer.ExecuteAt,
+				}
+				store.EXPECT().
+					CreateScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, got db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+						require.True(t, arg.ExecuteAt.Equal(got.ExecuteAt))
+						got.ExecuteAt = arg.ExecuteAt
+						require.Equal(t, arg, got)
+						return scheduledTransfer, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotTransfer db.ScheduledTransfer
+				err := json.NewDecoder(recorder.Body).Decode(&gotTransfer)
+				require.NoError(t, err)
+				require.Equal(t, scheduledTransfer.ID, gotTransfer.ID)
+				require.Equal(t, "pending", gotTransfer.Status)
+			},
+		},
+		{
+			name: "ExecuteAtInPast",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          scheduledTransfer.Amount,
+				"currency":        util.USD,
+				"execute_at":      time.Now().Add(-time.Minute),
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingExecuteAt",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          scheduledTransfer.Amount,
+				"currency":        util.USD,
+			},
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UnauthorizedUser",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          scheduledTransfer.Amount,
+				"currency":        util.USD,
+				"execute_at":      scheduledTransfer.ExecuteAt,
+			},
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "AboveStepUpThreshold",
+			body: gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          scheduledTransfer.Amount,
+				"currency":        util.USD,
+				"execute_at":      scheduledTransfer.ExecuteAt,
+			},
+			username:   user1.Username,
+			thresholds: fmt.Sprintf("USD:%d", scheduledTransfer.Amount-1),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().CreateScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.stepUpThresholds, _ = util.ParseCurrencyAmounts(tc.thresholds)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/scheduled_transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestCancelScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	scheduledTransfer := randomScheduledTransfer(user.Username, randomAccount(user.Username), randomAccount(user.Username))
+
+	testCases := []struct {
+		name          string
+		id            int64
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			id:   scheduledTransfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CancelScheduledTransferParams{
+					ID:       scheduledTransfer.ID,
+					Username: user.Username,
+				}
+				canceled := scheduledTransfer
+				canceled.Status = "canceled"
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Eq(arg)).Times(1).Return(canceled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotTransfer db.ScheduledTransfer
+				err := json.NewDecoder(recorder.Body).Decode(&gotTransfer)
+				require.NoError(t, err)
+				require.Equal(t, "canceled", gotTransfer.Status)
+			},
+		},
+		{
+			name: "NotPending",
+			id:   scheduledTransfer.ID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CancelScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidID",
+			id:   0,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CancelScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/scheduled_transfers/%d", tc.id)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListScheduledTransfersAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(user.Username)
+
+	scheduledTransfers := make([]db.ScheduledTransfer, 5)
+	for i := range scheduledTransfers {
+		scheduledTransfers[i] = randomScheduledTransfer(user.Username, account1, account2)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	arg := db.ListScheduledTransfersParams{
+		Username: user.Username,
+		Limit:    5,
+		Offset:   5,
+	}
+	store.EXPECT().ListScheduledTransfers(gomock.Any(), gomock.Eq(arg)).Times(1).Return(scheduledTransfers, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/scheduled_transfers?page_id=2&page_size=5", nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var gotTransfers []db.ScheduledTransfer
+	err = json.NewDecoder(recorder.Body).Decode(&gotTransfers)
+	require.NoError(t, err)
+	require.Len(t, gotTransfers, len(scheduledTransfers))
+}
+
+func TestExecuteScheduledTransfers(t *testing.T) {
+	user, _ := randomUser(t)
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(user.Username)
+
+	completed := randomScheduledTransfer(user.Username, account1, account2)
+	completed.Status = "completed"
+	failed := randomScheduledTransfer(user.Username, account1, account2)
+	failed.Status = "failed"
+	broken := randomScheduledTransfer(user.Username, account1, account2)
+
+	t.Run("ExecutesDueTransfers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		gomock.InOrder(
+			store.EXPECT().ExecuteScheduledTransferTx(gomock.Any()).Times(1).
+				Return(db.ExecuteScheduledTransferTxResult{ScheduledTransfer: completed}, nil),
+			store.EXPECT().ExecuteScheduledTransferTx(gomock.Any()).Times(1).
+				Return(db.ExecuteScheduledTransferTxResult{ScheduledTransfer: failed}, nil),
+			store.EXPECT().ExecuteScheduledTransferTx(gomock.Any()).Times(1).
+				Return(db.ExecuteScheduledTransferTxResult{ScheduledTransfer: broken}, sql.ErrConnDone),
+			store.EXPECT().FailScheduledTransfer(gomock.Any(), gomock.Eq(db.FailScheduledTransferParams{
+				ID:            broken.ID,
+				FailureReason: sql.NullString{String: sql.ErrConnDone.Error(), Valid: true},
+			})).Times(1),
+			store.EXPECT().ExecuteScheduledTransferTx(gomock.Any()).Times(1).
+				Return(db.ExecuteScheduledTransferTxResult{}, db.ErrRecordNotFound),
+		)
+
+		server := newTestServer(t, store)
+		require.Equal(t, 3, server.executeScheduledTransfers(context.Background()))
+	})
+
+	t.Run("ClaimError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().ExecuteScheduledTransferTx(gomock.Any()).Times(1).
+			Return(db.ExecuteScheduledTransferTxResult{}, sql.ErrConnDone)
+		store.EXPECT().FailScheduledTransfer(gomock.Any(), gomock.Any()).Times(0)
+
+		server := newTestServer(t, store)
+		require.Zero(t, server.executeScheduledTransfers(context.Background()))
+	})
+
+	t.Run("BoundedRun", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		store := mockdb.NewMockStore(ctrl)
+		store.EXPECT().ExecuteScheduledTransferTx(gomock.Any()).Times(maxScheduledTransfersPerRun).
+			Return(db.ExecuteScheduledTransferTxResult{ScheduledTransfer: completed}, nil)
+
+		server := newTestServer(t, store)
+		require.Equal(t, maxScheduledTransfersPerRun, server.executeScheduledTransfers(context.Background()))
+	})
+}
@@ -1,38 +1,113 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"net/http"
+	"time"
 
 	db "github.com/JMustang/OldBank/db/sqlc"
 	"github.com/JMustang/OldBank/token"
 	"github.com/JMustang/OldBank/util"
+	"github.com/JMustang/OldBank/webauthn"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 )
 
+const defaultRevocationPruneInterval = 10 * time.Minute
+
 // Server serves HTTP requests for our banking servoce.
 type Server struct {
-	config     util.Config
-	store      db.Store
-	tokenMaker token.Maker
-	router     *gin.Engine
+	config          util.Config
+	store           db.Store
+	tokenMaker      token.Maker
+	revocationStore token.RevocationStore
+	dpopVerifier    *dpopVerifier
+	passwordHasher  util.PasswordHasher
+	relyingParty    webauthn.RelyingParty
+	// tlsReloader is nil if the server does not serve TLS
+	tlsReloader *tlsReloader
+	// clientCerts is nil if no client certificate is mapped to a service identity
+	clientCerts *clientCertAuthenticator
+	// stepUpThresholds are the amounts per currency above which transfers must be confirmed
+	stepUpThresholds map[string]int64
+	// dummyHashedPassword is checked against when the user does not exist, so that
+	// the response time does not reveal which usernames are registered.
+	dummyHashedPassword string
+	router              *gin.Engine
 }
 
 // NewServer creates a new HTTP server and setup routing.
 func NewServer(config util.Config, store db.Store) (*Server, error) {
-	tokenMaker, err := token.NewPasetoMaker(config.TokenSymmetricKey)
+	tokenMaker, err := newTokenMaker(config)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	revocationStore, err := newRevocationStore(config, store)
+	if err != nil {
+		return nil, err
+	}
+	replayCache, err := newReplayCache(config, store)
+	if err != nil {
+		return nil, err
+	}
+	passwordHasher, err := util.NewPasswordHasher(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+	dummyHashedPassword, err := passwordHasher.HashPassword(util.RandomString(16))
+	if err != nil {
+		return nil, err
+	}
+	tlsReloader, err := newTLSReloader(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load TLS certificate: %w", err)
+	}
+	clientCerts, err := newClientCertAuthenticator(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse service identities: %w", err)
+	}
+	stepUpThresholds, err := util.ParseCurrencyAmounts(config.StepUpThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse step-up thresholds: %w", err)
+	}
+	if config.FXSpreadBps < 0 || config.FXSpreadBps >= util.MaxSpreadBps {
+		return nil, fmt.Errorf("fx spread must be between 0 and %d basis points", util.MaxSpreadBps-1)
+	}
+
+	dpopExternalURL, err := parseDPoPExternalURL(config.DPoPExternalURL)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse DPoP external URL: %w", err)
+	}
+
+	dpop := &dpopVerifier{
+		replayCache: replayCache,
+		maxAge:      config.DPoPProofMaxAge,
+		clockSkew:   config.TokenClockSkew,
+		externalURL: dpopExternalURL,
+	}
+
 	server := &Server{
-		config:     config,
-		store:      store,
-		tokenMaker: tokenMaker,
+		config:              config,
+		store:               store,
+		tokenMaker:          tokenMaker,
+		revocationStore:     revocationStore,
+		dpopVerifier:        dpop,
+		passwordHasher:      passwordHasher,
+		relyingParty:        newRelyingParty(config),
+		tlsReloader:         tlsReloader,
+		clientCerts:         clientCerts,
+		stepUpThresholds:    stepUpThresholds,
+		dummyHashedPassword: dummyHashedPassword,
 	}
 
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
 		v.RegisterValidation("currency", validCurrency)
+		v.RegisterValidation("role", validRole)
+		v.RegisterValidation("scope", validScope)
+		v.RegisterValidation("frequency", validFrequency)
 	}
 
 	server.setupRouter()
@@ -44,21 +119,203 @@ func (server *Server) setupRouter() {
 
 	// Add router
 	router.POST("/users", server.createUser)
-	router.POST("/users/login", server.loginUser)
+	router.POST("/users/login", server.bindDPoPKey(errorResponse), server.loginUser)
+	router.POST("/users/login/mfa", server.bindDPoPKey(errorResponse), server.loginMFA)
+	router.POST("/users/login/webauthn/begin", server.beginWebAuthnLogin)
+	router.POST("/users/login/webauthn/finish", server.bindDPoPKey(errorResponse), server.finishWebAuthnLogin)
+	router.POST("/users/password/forgot", server.forgotPassword)
+	router.POST("/users/password/reset", server.resetPassword)
+	router.GET("/users/verify_email", server.verifyEmail)
+	router.POST("/tokens/renew_access", server.bindDPoPKey(errorResponse), server.renewAccessToken)
+	router.GET("/.well-known/jwks.json", server.getJSONWebKeySet)
+	router.POST("/oauth/token", server.bindDPoPKey(oauthDPoPErrorResponse), server.createOAuthToken)
+	router.POST("/oauth/introspect", server.introspectToken)
+
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocationStore, server.dpopVerifier, nil, nil))
+
+	authRoutes.POST("/users/logout", server.logoutUser)
+	authRoutes.POST("/users/logout_all", server.logoutAllSessions)
+	authRoutes.GET("/users/me/sessions", server.listSessions)
+	authRoutes.DELETE("/users/me/sessions/:id", server.deleteSession)
+	authRoutes.PATCH("/users/me", server.updateUser)
+	authRoutes.POST("/users/me/password", server.changePassword)
+	authRoutes.POST("/users/me/totp", server.enrollTOTP)
+	authRoutes.POST("/users/me/totp/confirm", server.confirmTOTP)
+	authRoutes.DELETE("/users/me/totp", server.disableTOTP)
+	authRoutes.POST("/users/me/webauthn/register/begin", server.beginWebAuthnRegistration)
+	authRoutes.POST("/users/me/webauthn/register/finish", server.finishWebAuthnRegistration)
+	authRoutes.GET("/users/me/webauthn/credentials", server.listWebAuthnCredentials)
+	authRoutes.DELETE("/users/me/webauthn/credentials/:id", server.deleteWebAuthnCredential)
+	authRoutes.POST("/transfers/challenges/:id/confirm", server.confirmTransferChallenge)
+	authRoutes.POST("/users/me/api_keys", server.createAPIKey)
+	authRoutes.GET("/users/me/api_keys", server.listAPIKeys)
+	authRoutes.DELETE("/users/me/api_keys/:id", server.deleteAPIKey)
+	authRoutes.POST("/oauth/clients", server.createOAuthClient)
+	authRoutes.GET("/oauth/authorize", server.getOAuthConsent)
+	authRoutes.POST("/oauth/authorize", server.authorizeOAuthClient)
+
+	// Back-office routes, also accessible to internal callers authenticated by their client certificate
+	backOfficeRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocationStore, server.dpopVerifier, nil, server.clientCerts))
+
+	backOfficeRoutes.PUT("/users/:username/role", authorizeRoles(util.AdminRole), server.updateUserRole)
+	backOfficeRoutes.POST("/users/:username/unlock", authorizeRoles(util.AdminRole), server.unlockUser)
+	backOfficeRoutes.PUT("/accounts/:id/overdraft_limit", authorizeRoles(util.BankerRole, util.AdminRole), server.updateOverdraftLimit)
+	backOfficeRoutes.POST("/fx/rates", authorizeRoles(util.BankerRole, util.AdminRole), server.createFxRate)
+	backOfficeRoutes.PUT("/oauth/clients/:id/resource_server", authorizeRoles(util.AdminRole), server.updateOAuthClientResourceServer)
 
-	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker))
+	// Routes also accessible with API keys, OAuth access tokens and client certificates,
+	// limited to the scopes they were granted
+	scopedRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker, server.revocationStore, server.dpopVerifier, &apiKeyAuthenticator{store: server.store}, server.clientCerts))
 
-	authRoutes.POST("/accounts", server.createAccount)
-	authRoutes.GET("/accounts/:id", server.getAccount)
-	authRoutes.GET("/accounts", server.listAccounts)
+	scopedRoutes.POST("/accounts", authorizeScopes(util.AccountsWriteScope), server.requireVerifiedEmail(), server.createAccount)
+	scopedRoutes.GET("/accounts/:id", authorizeScopes(util.AccountsReadScope), server.getAccount)
+	scopedRoutes.GET("/accounts", authorizeScopes(util.AccountsReadScope), server.listAccounts)
 
-	authRoutes.POST("/transfers", server.createTransfer)
+	scopedRoutes.POST("/fx/quotes", authorizeScopes(util.TransfersWriteScope), server.createFxQuote)
+	scopedRoutes.POST("/transfers", authorizeScopes(util.TransfersWriteScope), server.requireVerifiedEmail(), server.createTransfer)
+	scopedRoutes.POST("/scheduled_transfers", authorizeScopes(util.TransfersWriteScope), server.requireVerifiedEmail(), server.createScheduledTransfer)
+	scopedRoutes.GET("/scheduled_transfers", authorizeScopes(util.TransfersWriteScope), server.listScheduledTransfers)
+	scopedRoutes.DELETE("/scheduled_transfers/:id", authorizeScopes(util.TransfersWriteScope), server.cancelScheduledTransfer)
+
+	scopedRoutes.POST("/standing_orders", authorizeScopes(util.TransfersWriteScope), server.requireVerifiedEmail(), server.createStandingOrder)
+	scopedRoutes.GET("/standing_orders", authorizeScopes(util.TransfersWriteScope), server.listStandingOrders)
+	scopedRoutes.GET("/standing_orders/:id/runs", authorizeScopes(util.TransfersWriteScope), server.listStandingOrderRuns)
+	scopedRoutes.POST("/standing_orders/:id/pause", authorizeScopes(util.TransfersWriteScope), server.pauseStandingOrder)
+	scopedRoutes.POST("/standing_orders/:id/resume", authorizeScopes(util.TransfersWriteScope), server.requireVerifiedEmail(), server.resumeStandingOrder)
+	scopedRoutes.DELETE("/standing_orders/:id", authorizeScopes(util.TransfersWriteScope), server.cancelStandingOrder)
 	server.router = router
 }
 
-// Start runs the HTTP server on a specific address.
+// Start runs the HTTP server on a specific address, over TLS if a certificate is configured.
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	go server.pruneRevocations()
+	go server.runScheduledTransfers()
+	if server.tlsReloader == nil {
+		return server.router.Run(address)
+	}
+
+	go server.tlsReloader.watch(server.config.TLSReloadInterval)
+
+	httpServer := &http.Server{
+		Addr:      address,
+		Handler:   server.router,
+		TLSConfig: server.tlsReloader.tlsConfig(),
+	}
+	return httpServer.ListenAndServeTLS("", "")
+}
+
+// ReloadTLSCertificate reads the TLS certificate, key and client CAs from their files again.
+func (server *Server) ReloadTLSCertificate() error {
+	if server.tlsReloader == nil {
+		return errTLSDisabled
+	}
+	return server.tlsReloader.reload()
+}
+
+// newTokenMaker picks the token maker configured by TOKEN_MAKER.
+func newTokenMaker(config util.Config) (token.Maker, error) {
+	keyring, err := tokenKeyring(config)
+	if err != nil {
+		return nil, err
+	}
+
+	options := token.Options{
+		Issuer:    config.TokenIssuer,
+		Audience:  config.TokenAudience,
+		ClockSkew: config.TokenClockSkew,
+	}
+
+	switch config.TokenMaker {
+	case "", "paseto":
+		return token.NewPasetoKeyringMaker(keyring, options)
+	case "paseto_public":
+		return token.NewPasetoPublicKeyringMaker(keyring, options)
+	case "jwt":
+		return token.NewJWTKeyringMaker(keyring, options)
+	}
+	return nil, fmt.Errorf("unsupported token maker: %s", config.TokenMaker)
+}
+
+// tokenKeyring reads the keys listed in TOKEN_KEYS, falling back to the single key of the token maker.
+func tokenKeyring(config util.Config) (token.Keyring, error) {
+	if config.TokenKeys != "" {
+		return token.ParseKeyring(config.TokenActiveKeyID, config.TokenKeys)
+	}
+	if config.TokenMaker == "paseto_public" {
+		return token.NewKeyring(token.DefaultKeyID, config.TokenPrivateKey), nil
+	}
+	return token.NewKeyring(token.DefaultKeyID, config.TokenSymmetricKey), nil
+}
+
+// ReloadTokenKeys replaces the keys of the token maker, so that they can be rotated without a restart.
+func (server *Server) ReloadTokenKeys(config util.Config) error {
+	rotator, ok := server.tokenMaker.(token.KeyRotator)
+	if !ok {
+		return fmt.Errorf("token maker does not support key rotation")
+	}
+
+	keyring, err := tokenKeyring(config)
+	if err != nil {
+		return err
+	}
+	return rotator.SetKeyring(keyring)
+}
+
+// newRevocationStore picks the token revocation store configured by REVOCATION_STORE.
+func newRevocationStore(config util.Config, store db.Store) (token.RevocationStore, error) {
+	switch config.RevocationStore {
+	case "", "postgres":
+		return db.NewRevocationStore(store), nil
+	case "memory":
+		return token.NewMemoryRevocationStore(), nil
+	}
+	return nil, fmt.Errorf("unsupported revocation store: %s", config.RevocationStore)
+}
+
+// newReplayCache picks the cache of used DPoP proofs configured by DPOP_REPLAY_CACHE.
+func newReplayCache(config util.Config, store db.Store) (token.ReplayCache, error) {
+	switch config.DPoPReplayCache {
+	case "", "postgres":
+		return db.NewReplayCache(store), nil
+	case "memory":
+		return token.NewMemoryReplayCache(), nil
+	}
+	return nil, fmt.Errorf("unsupported DPoP replay cache: %s", config.DPoPReplayCache)
+}
+
+// pruneRevocations periodically deletes revocations of tokens that have already expired,
+// along with the DPoP proofs too old to be replayed, the expired idempotency keys
+// and the login throttles whose failures are forgotten and lockout is over.
+func (server *Server) pruneRevocations() {
+	interval := server.config.RevocationPruneInterval
+	if interval <= 0 {
+		interval = defaultRevocationPruneInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		err := server.revocationStore.Prune(context.Background())
+		if err != nil {
+			log.Println("cannot prune token revocations:", err)
+		}
+
+		err = server.dpopVerifier.replayCache.Prune(context.Background())
+		if err != nil {
+			log.Println("cannot prune DPoP replay cache:", err)
+		}
+
+		_, err = server.store.DeleteExpiredIdempotencyKeys(context.Background())
+		if err != nil {
+			log.Println("cannot prune idempotency keys:", err)
+		}
+
+		_, err = server.store.DeleteExpiredLoginThrottles(context.Background(), time.Now().Add(-server.loginLockoutDuration()))
+		if err != nil {
+			log.Println("cannot prune login throttles:", err)
+		}
+	}
 }
 
 func errorResponse(err error) gin.H {
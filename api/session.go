@@ -0,0 +1,195 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+var errSessionNotFound = errors.New("session not found")
+
+type logoutUserRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (server *Server) logoutUser(ctx *gin.Context) {
+	var req logoutUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	if req.RefreshToken != "" {
+		refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+		if err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		if refreshPayload.Purpose != token.PurposeRefresh {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+			return
+		}
+
+		if refreshPayload.Username != authPayload.Username {
+			err := errors.New("refresh token doesn't belong to the authenticated user")
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		_, err = server.store.BlockSession(ctx, refreshPayload.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.JSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		err = server.revocationStore.RevokeToken(ctx, refreshPayload.ID, refreshPayload.Username, server.revocationExpiry(refreshPayload.ExpiredAt))
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+	}
+
+	err := server.revocationStore.RevokeToken(ctx, authPayload.ID, authPayload.Username, server.revocationExpiry(authPayload.ExpiredAt))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (server *Server) logoutAllSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	_, err := server.store.BlockUserSessions(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// API keys outlive any revocation, so they are deleted
+	_, err = server.store.DeleteUserApiKeys(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The revocation outlives every token issued until now, refresh tokens included
+	now := time.Now()
+	err = server.revocationStore.RevokeUserTokens(ctx, authPayload.Username, now, now.Add(server.longestTokenLifetime()))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func newSessionResponse(session db.Session) sessionResponse {
+	return sessionResponse{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+}
+
+// listSessions lists the active sessions of the authenticated user, most recently seen first
+func (server *Server) listSessions(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	sessions, err := server.store.ListUserSessions(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		rsp[i] = newSessionResponse(session)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type deleteSessionRequest struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// deleteSession logs out one session of the authenticated user. Its refresh token and the access tokens
+// issued from it are revoked at once.
+func (server *Server) deleteSession(ctx *gin.Context) {
+	var req deleteSessionRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	// Sessions of other users are reported as not found, so that their IDs cannot be probed
+	session, err := server.store.BlockUserSession(ctx, db.BlockUserSessionParams{
+		ID:       uuid.MustParse(req.ID),
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errSessionNotFound))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The refresh token of the session has the session ID, and its access tokens carry it. The last of them
+	// can be renewed just before the session expires.
+	expiresAt := server.revocationExpiry(session.ExpiresAt.Add(server.config.AccessTokenDuration))
+	err = server.revocationStore.RevokeToken(ctx, session.ID, session.Username, expiresAt)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// newDeviceEmail builds the email warning a user of a login from a user agent it never logged in with
+func newDeviceEmail(user db.User) func(session db.Session) db.CreateOutboxEmailParams {
+	return func(session db.Session) db.CreateOutboxEmailParams {
+		return db.CreateOutboxEmailParams{
+			ToAddress: user.Email,
+			Subject:   "New login to your OldBank account",
+			Body: fmt.Sprintf(
+				"Hello %s,\n\nYour account was just accessed from a new device:\n\nDevice: %s\nIP address: %s\nTime: %s\n\nIf this was not you, change your password and log out this session from your account settings.\n",
+				user.FullName,
+				session.UserAgent,
+				session.ClientIp,
+				session.CreatedAt.Format(time.RFC1123),
+			),
+		}
+	}
+}
@@ -0,0 +1,572 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogoutUserAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	otherUser, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(t *testing.T, tokenMaker token.Maker) (gin.H, db.Session)
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+		revoked       bool
+	}{
+		{
+			name: "OK",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (gin.H, db.Session) {
+				return gin.H{}, db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+			revoked: true,
+		},
+		{
+			name: "OKWithRefreshToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (gin.H, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, user.Username)
+				return gin.H{"refresh_token": refreshToken}, session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+			revoked: true,
+		},
+		{
+			name: "InvalidRefreshToken",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (gin.H, db.Session) {
+				return gin.H{"refresh_token": "invalid"}, db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "RefreshTokenOfAnotherUser",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (gin.H, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, otherUser.Username)
+				return gin.H{"refresh_token": refreshToken}, session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildBody: func(t *testing.T, tokenMaker token.Maker) (gin.H, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, user.Username)
+				return gin.H{"refresh_token": refreshToken}, session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					BlockSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			body, session := tc.buildBody(t, server.tokenMaker)
+			tc.buildStubs(store, session)
+
+			accessToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+			require.NoError(t, err)
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(body)
+			require.NoError(t, err)
+
+			url := "/users/logout"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+			request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+
+			payload, err := server.tokenMaker.VerifyToken(accessToken)
+			require.NoError(t, err)
+
+			revoked, err := server.revocationStore.IsRevoked(request.Context(), payload)
+			require.NoError(t, err)
+			require.Equal(t, tc.revoked, revoked)
+
+			if refreshToken, ok := body["refresh_token"].(string); ok && tc.revoked {
+				refreshPayload, err := server.tokenMaker.VerifyToken(refreshToken)
+				require.NoError(t, err)
+
+				revoked, err = server.revocationStore.IsRevoked(request.Context(), refreshPayload)
+				require.NoError(t, err)
+				require.True(t, revoked)
+			}
+		})
+	}
+}
+
+func TestLogoutAllSessionsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+		revoked       bool
+	}{
+		{
+			name: "OK",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(int64(2), nil)
+				store.EXPECT().
+					DeleteUserApiKeys(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(int64(1), nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+			revoked: true,
+		},
+		{
+			name: "InternalError",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+
+			otherToken, otherPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+			require.NoError(t, err)
+			require.NotEmpty(t, otherToken)
+
+			url := "/users/logout_all"
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+			recorder := httptest.NewRecorder()
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+
+			revoked, err := server.revocationStore.IsRevoked(request.Context(), otherPayload)
+			require.NoError(t, err)
+			require.Equal(t, tc.revoked, revoked)
+		})
+	}
+}
+
+func TestListSessionsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	n := 3
+	sessions := make([]db.Session, n)
+	for i := range sessions {
+		sessions[i] = db.Session{
+			ID:           uuid.New(),
+			Username:     user.Username,
+			RefreshToken: util.RandomString(32),
+			UserAgent:    util.RandomString(10),
+			ClientIp:     "127.0.0.1",
+			ExpiresAt:    time.Now().Add(time.Hour),
+			CreatedAt:    time.Now(),
+			LastSeenAt:   time.Now(),
+		}
+	}
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListUserSessions(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(sessions, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), "refresh_token")
+
+				var rsp []sessionResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp, n)
+				for i, session := range sessions {
+					require.Equal(t, session.ID, rsp[i].ID)
+					require.Equal(t, session.UserAgent, rsp[i].UserAgent)
+					require.Equal(t, session.ClientIp, rsp[i].ClientIP)
+				}
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListUserSessions(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListUserSessions(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return([]db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/users/me/sessions", nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDeleteSessionAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	sessionID := uuid.New()
+
+	testCases := []struct {
+		name          string
+		sessionID     string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			sessionID: sessionID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSession(gomock.Any(), gomock.Eq(db.BlockUserSessionParams{
+						ID:       sessionID,
+						Username: user.Username,
+					})).
+					Times(1).
+					Return(db.Session{ID: sessionID, Username: user.Username, IsBlocked: true, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			sessionID: sessionID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			sessionID: "not-a-uuid",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "InternalError",
+			sessionID: sessionID.String(),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					BlockUserSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/me/sessions/%s", tc.sessionID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginNewDeviceEmail(t *testing.T) {
+	user, password := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	expectLoginThrottles(store, nil)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		ResetLoginThrottle(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return(db.TotpSecret{}, sql.ErrNoRows)
+	store.EXPECT().
+		CreateSessionTx(gomock.Any(), gomock.Any()).
+		Times(1).
+		DoAndReturn(func(_ interface{}, arg db.CreateSessionTxParams) (db.CreateSessionTxResult, error) {
+			require.Equal(t, user.Username, arg.Username)
+			require.Equal(t, "test-agent", arg.UserAgent)
+
+			session := db.Session{
+				ID:        arg.ID,
+				Username:  arg.Username,
+				UserAgent: arg.UserAgent,
+				ClientIp:  arg.ClientIp,
+				CreatedAt: time.Now(),
+			}
+			email := arg.NewDeviceEmail(session)
+			require.Equal(t, user.Email, email.ToAddress)
+			require.Contains(t, email.Body, user.FullName)
+			require.Contains(t, email.Body, "test-agent")
+
+			return db.CreateSessionTxResult{Session: session}, nil
+		})
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{
+		"username": user.Username,
+		"password": password,
+	})
+	require.NoError(t, err)
+
+	request, err := http.NewRequest(http.MethodPost, "/users/login", bytes.NewReader(data))
+	require.NoError(t, err)
+	request.Header.Set("User-Agent", "test-agent")
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestDeleteSessionRevokesRefreshToken(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	refreshToken, session := randomSession(t, server.tokenMaker, user.Username)
+	session.IsBlocked = true
+	store.EXPECT().
+		BlockUserSession(gomock.Any(), gomock.Eq(db.BlockUserSessionParams{
+			ID:       session.ID,
+			Username: user.Username,
+		})).
+		Times(1).
+		Return(session, nil)
+
+	url := fmt.Sprintf("/users/me/sessions/%s", session.ID)
+	request, err := http.NewRequest(http.MethodDelete, url, nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	// The refresh token of the deleted session is rejected afterwards, without looking up its session
+	store.EXPECT().
+		GetSession(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+	require.NoError(t, err)
+
+	request, err = http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestDeleteSessionRevokesAccessTokens(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	_, session := randomSession(t, server.tokenMaker, user.Username)
+	session.IsBlocked = true
+	store.EXPECT().
+		BlockUserSession(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(session, nil)
+
+	accessPayload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+	accessPayload.SessionID = session.ID
+	accessToken, err := server.tokenMaker.CreateTokenFromPayload(accessPayload)
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/users/me/sessions/%s", session.ID)
+	request, err := http.NewRequest(http.MethodDelete, url, nil)
+	require.NoError(t, err)
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+
+	recorder := httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+
+	// The access tokens issued from the deleted session are rejected afterwards
+	store.EXPECT().
+		ListUserSessions(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	request, err = http.NewRequest(http.MethodGet, "/users/me/sessions", nil)
+	require.NoError(t, err)
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, accessToken))
+
+	recorder = httptest.NewRecorder()
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
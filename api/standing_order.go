@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	errStandingOrderNotFound  = errors.New("standing order not found")
+	errStandingOrderNotActive = errors.New("standing order not found or not active")
+	errStandingOrderNotPaused = errors.New("standing order not found or not paused")
+	errStandingOrderEnded     = errors.New("standing order not found or already ended")
+	errStartAtInPast          = errors.New("start_at must not be in the past")
+	errNoOccurrence           = errors.New("the standing order ends before its first occurrence")
+	errStandingOrderStepUp    = errors.New("transfers above the step-up threshold cannot be made standing orders")
+)
+
+type createStandingOrderRequest struct {
+	FromAccountID int64  `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
+	Amount        int64  `json:"amount" binding:"required,gt=0"`
+	Currency      string `json:"currency" binding:"required,currency"`
+	Frequency     string `json:"frequency" binding:"required,frequency"`
+	// RepeatEvery is the number of weeks or months between occurrences, 1 by default
+	RepeatEvery int32 `json:"repeat_every" binding:"omitempty,min=1,max=12"`
+	DayOfMonth  int32 `json:"day_of_month" binding:"required_if=Frequency monthly,omitempty,min=1,max=31"`
+	// StartAt is now by default. Occurrences are at its time of day.
+	StartAt        *time.Time `json:"start_at"`
+	EndAt          *time.Time `json:"end_at"`
+	MaxOccurrences int32      `json:"max_occurrences" binding:"omitempty,min=1"`
+}
+
+// createStandingOrder sets up a recurring transfer from an account of the user. Each occurrence generates
+// a scheduled transfer, executed in the background, which records the outcome of the run.
+func (server *Server) createStandingOrder(ctx *gin.Context) {
+	var req createStandingOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	startAt := time.Now()
+	if req.StartAt != nil {
+		if req.StartAt.Before(startAt) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errStartAtInPast))
+			return
+		}
+		startAt = *req.StartAt
+	}
+	if req.RepeatEvery == 0 {
+		req.RepeatEvery = 1
+	}
+
+	recurrence := util.Recurrence{
+		Frequency:  req.Frequency,
+		Interval:   int(req.RepeatEvery),
+		DayOfMonth: int(req.DayOfMonth),
+	}
+	if err := recurrence.Validate(); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	// calendar rules are evaluated in UTC, like when the standing order is advanced
+	firstExecution := recurrence.First(startAt.UTC())
+
+	var endAt sql.NullTime
+	if req.EndAt != nil {
+		if firstExecution.After(*req.EndAt) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errNoOccurrence))
+			return
+		}
+		endAt = sql.NullTime{Time: *req.EndAt, Valid: true}
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if fromAccount.Owner != authPayload.Username {
+		err := errors.New("from account doesn't belong to the authenticated user")
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	if !valid {
+		return
+	}
+
+	if server.requiresStepUp(req.Amount, req.Currency) {
+		ctx.JSON(http.StatusForbidden, errorResponse(errStandingOrderStepUp))
+		return
+	}
+
+	arg := db.CreateStandingOrderParams{
+		Username:        authPayload.Username,
+		FromAccountID:   req.FromAccountID,
+		ToAccountID:     req.ToAccountID,
+		Amount:          req.Amount,
+		Currency:        req.Currency,
+		Frequency:       req.Frequency,
+		RepeatEvery:     req.RepeatEvery,
+		StartAt:         startAt,
+		EndAt:           endAt,
+		NextExecutionAt: sql.NullTime{Time: firstExecution, Valid: true},
+	}
+	if req.Frequency == util.MonthlyFrequency {
+		arg.DayOfMonth = sql.NullInt32{Int32: req.DayOfMonth, Valid: true}
+	}
+	if req.MaxOccurrences > 0 {
+		arg.MaxOccurrences = sql.NullInt32{Int32: req.MaxOccurrences, Valid: true}
+	}
+
+	standingOrder, err := server.store.CreateStandingOrder(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, standingOrder)
+}
+
+type listStandingOrdersRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+func (server *Server) listStandingOrders(ctx *gin.Context) {
+	var req listStandingOrdersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	standingOrders, err := server.store.ListStandingOrders(ctx, db.ListStandingOrdersParams{
+		Username: authPayload.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, standingOrders)
+}
+
+type standingOrderURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// listStandingOrderRuns lists the runs of a standing order of the user, latest first, with their outcome once executed.
+func (server *Server) listStandingOrderRuns(ctx *gin.Context) {
+	var uri standingOrderURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listStandingOrdersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	standingOrder, err := server.store.GetStandingOrder(ctx, uri.ID)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == sql.ErrNoRows || standingOrder.Username != authPayload.Username {
+		ctx.JSON(http.StatusNotFound, errorResponse(errStandingOrderNotFound))
+		return
+	}
+
+	runs, err := server.store.ListStandingOrderRuns(ctx, db.ListStandingOrderRunsParams{
+		StandingOrderID: sql.NullInt64{Int64: standingOrder.ID, Valid: true},
+		Limit:           req.PageSize,
+		Offset:          (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, runs)
+}
+
+// pauseStandingOrder stops generating runs of an active standing order of the user until it is resumed.
+func (server *Server) pauseStandingOrder(ctx *gin.Context) {
+	var uri standingOrderURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	standingOrder, err := server.store.PauseStandingOrder(ctx, db.PauseStandingOrderParams{
+		ID:       uri.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errStandingOrderNotActive))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, standingOrder)
+}
+
+// resumeStandingOrder resumes a paused standing order of the user. The occurrences missed while it was paused are skipped.
+func (server *Server) resumeStandingOrder(ctx *gin.Context) {
+	var uri standingOrderURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	result, err := server.store.ResumeStandingOrderTx(ctx, db.ResumeStandingOrderTxParams{
+		ID:       uri.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errStandingOrderNotPaused))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result.StandingOrder)
+}
+
+// cancelStandingOrder ends an active or paused standing order of the user for good.
+// Runs already generated are still executed, unless their scheduled transfer is canceled too.
+func (server *Server) cancelStandingOrder(ctx *gin.Context) {
+	var uri standingOrderURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	standingOrder, err := server.store.CancelStandingOrder(ctx, db.CancelStandingOrderParams{
+		ID:       uri.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errStandingOrderEnded))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, standingOrder)
+}
+
+// generateStandingOrderRuns schedules the runs of the due standing orders, up to maxScheduledTransfersPerRun,
+// and returns how many were generated.
+func (server *Server) generateStandingOrderRuns(ctx context.Context) int {
+	generated := 0
+	for generated < maxScheduledTransfersPerRun {
+		result, err := server.store.GenerateStandingOrderRunTx(ctx)
+		if err != nil {
+			if !errors.Is(err, db.ErrRecordNotFound) {
+				log.Println("cannot generate standing order run:", err)
+			}
+			return generated
+		}
+		generated++
+
+		log.Printf("standing order [%d] scheduled run [%d]", result.StandingOrder.ID, result.Run.ID)
+	}
+	return generated
+}
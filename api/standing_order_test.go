@@ -0,0 +1,503 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func randomStandingOrder(username string, fromAccount, toAccount db.Account) db.StandingOrder {
+	startAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	return db.StandingOrder{
+		ID:              util.RandomInt(1, 1000),
+		Username:        username,
+		FromAccountID:   fromAccount.ID,
+		ToAccountID:     toAccount.ID,
+		Amount:          util.RandomMoney(),
+		Currency:        fromAccount.Currency,
+		Frequency:       util.WeeklyFrequency,
+		RepeatEvery:     1,
+		StartAt:         startAt,
+		NextExecutionAt: sql.NullTime{Time: startAt, Valid: true},
+		Status:          "active",
+	}
+}
+
+func TestCreateStandingOrderAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	amount := util.RandomMoney()
+	startAt := time.Date(2100, time.January, 10, 9, 0, 0, 0, time.UTC)
+
+	body := func(fields gin.H) gin.H {
+		body := gin.H{
+			"from_account_id": account1.ID,
+			"to_account_id":   account2.ID,
+			"amount":          amount,
+			"currency":        util.USD,
+		}
+		for key, value := range fields {
+			body[key] = value
+		}
+		return body
+	}
+
+	validAccounts := func(store *mockdb.MockStore) {
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		username      string
+		thresholds    string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "Monthly",
+			body: body(gin.H{
+				"frequency":       util.MonthlyFrequency,
+				"day_of_month":    31,
+				"start_at":        startAt,
+				"end_at":          startAt.AddDate(1, 0, 0),
+				"max_occurrences": 12,
+			}),
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				validAccounts(store)
+
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateStandingOrderParams) (db.StandingOrder, error) {
+						require.Equal(t, user1.Username, arg.Username)
+						require.Equal(t, amount, arg.Amount)
+						require.Equal(t, util.MonthlyFrequency, arg.Frequency)
+						require.Equal(t, int32(1), arg.RepeatEvery)
+						require.Equal(t, sql.NullInt32{Int32: 31, Valid: true}, arg.DayOfMonth)
+						require.Equal(t, sql.NullInt32{Int32: 12, Valid: true}, arg.MaxOccurrences)
+						require.True(t, arg.StartAt.Equal(startAt))
+						require.True(t, arg.EndAt.Valid)
+						require.True(t, arg.NextExecutionAt.Time.Equal(time.Date(2100, time.January, 31, 9, 0, 0, 0, time.UTC)))
+						return db.StandingOrder{ID: 1, Username: arg.Username, Status: "active"}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var standingOrder db.StandingOrder
+				err := json.NewDecoder(recorder.Body).Decode(&standingOrder)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), standingOrder.ID)
+			},
+		},
+		{
+			name:     "WeeklyFromNow",
+			body:     body(gin.H{"frequency": util.WeeklyFrequency, "repeat_every": 2}),
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				validAccounts(store)
+
+				store.EXPECT().
+					CreateStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg db.CreateStandingOrderParams) (db.StandingOrder, error) {
+						require.Equal(t, int32(2), arg.RepeatEvery)
+						require.False(t, arg.DayOfMonth.Valid)
+						require.False(t, arg.EndAt.Valid)
+						require.False(t, arg.MaxOccurrences.Valid)
+						require.WithinDuration(t, time.Now(), arg.StartAt, time.Second)
+						require.True(t, arg.NextExecutionAt.Time.Equal(arg.StartAt))
+						return db.StandingOrder{}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:     "MonthlyWithoutDay",
+			body:     body(gin.H{"frequency": util.MonthlyFrequency}),
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "UnsupportedFrequency",
+			body:     body(gin.H{"frequency": "daily"}),
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "StartInPast",
+			body:     body(gin.H{"frequency": util.WeeklyFrequency, "start_at": time.Now().Add(-time.Hour)}),
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "EndsBeforeFirstOccurrence",
+			body: body(gin.H{
+				"frequency":    util.MonthlyFrequency,
+				"day_of_month": 31,
+				"start_at":     startAt,
+				"end_at":       startAt.AddDate(0, 0, 7),
+			}),
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			body:     body(gin.H{"frequency": util.LastBusinessDayFrequency}),
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "AboveStepUpThreshold",
+			body:       body(gin.H{"frequency": util.LastBusinessDayFrequency}),
+			username:   user1.Username,
+			thresholds: fmt.Sprintf("USD:%d", amount-1),
+			buildStubs: func(store *mockdb.MockStore) {
+				validAccounts(store)
+				store.EXPECT().CreateStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.stepUpThresholds, _ = util.ParseCurrencyAmounts(tc.thresholds)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/standing_orders", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestUpdateStandingOrderStatusAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	standingOrder := randomStandingOrder(user.Username, randomAccount(user.Username), randomAccount(user.Username))
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "Pause",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/standing_orders/%d/pause", standingOrder.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.PauseStandingOrderParams{
+					ID:       standingOrder.ID,
+					Username: user.Username,
+				}
+				paused := standingOrder
+				paused.Status = "paused"
+				store.EXPECT().PauseStandingOrder(gomock.Any(), gomock.Eq(arg)).Times(1).Return(paused, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "PauseNotActive",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/standing_orders/%d/pause", standingOrder.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					PauseStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "Resume",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/standing_orders/%d/resume", standingOrder.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.ResumeStandingOrderTxParams{
+					ID:       standingOrder.ID,
+					Username: user.Username,
+				}
+				store.EXPECT().
+					ResumeStandingOrderTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ResumeStandingOrderTxResult{StandingOrder: standingOrder}, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotOrder db.StandingOrder
+				err := json.NewDecoder(recorder.Body).Decode(&gotOrder)
+				require.NoError(t, err)
+				require.Equal(t, standingOrder.ID, gotOrder.ID)
+				require.Equal(t, "active", gotOrder.Status)
+			},
+		},
+		{
+			name:   "ResumeNotPaused",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/standing_orders/%d/resume", standingOrder.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ResumeStandingOrderTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ResumeStandingOrderTxResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "Cancel",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/standing_orders/%d", standingOrder.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				arg := db.CancelStandingOrderParams{
+					ID:       standingOrder.ID,
+					Username: user.Username,
+				}
+				canceled := standingOrder
+				canceled.Status = "canceled"
+				canceled.NextExecutionAt = sql.NullTime{}
+				store.EXPECT().CancelStandingOrder(gomock.Any(), gomock.Eq(arg)).Times(1).Return(canceled, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "CancelEnded",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/standing_orders/%d", standingOrder.ID),
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					CancelStandingOrder(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "InvalidID",
+			method: http.MethodDelete,
+			url:    "/standing_orders/0",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().CancelStandingOrder(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestListStandingOrderRunsAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user1.Username)
+	standingOrder := randomStandingOrder(user1.Username, account1, account2)
+
+	runs := make([]db.ScheduledTransfer, 5)
+	for i := range runs {
+		runs[i] = randomScheduledTransfer(user1.Username, account1, account2)
+		runs[i].StandingOrderID = sql.NullInt64{Int64: standingOrder.ID, Valid: true}
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(standingOrder.ID)).Times(1).Return(standingOrder, nil)
+
+				arg := db.ListStandingOrderRunsParams{
+					StandingOrderID: sql.NullInt64{Int64: standingOrder.ID, Valid: true},
+					Limit:           5,
+					Offset:          0,
+				}
+				store.EXPECT().ListStandingOrderRuns(gomock.Any(), gomock.Eq(arg)).Times(1).Return(runs, nil)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var gotRuns []db.ScheduledTransfer
+				err := json.NewDecoder(recorder.Body).Decode(&gotRuns)
+				require.NoError(t, err)
+				require.Len(t, gotRuns, len(runs))
+			},
+		},
+		{
+			name:     "StandingOrderOfAnotherUser",
+			username: user2.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetStandingOrder(gomock.Any(), gomock.Eq(standingOrder.ID)).Times(1).Return(standingOrder, nil)
+				store.EXPECT().ListStandingOrderRuns(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user1.Username,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetStandingOrder(gomock.Any(), gomock.Eq(standingOrder.ID)).
+					Times(1).
+					Return(db.StandingOrder{}, db.ErrRecordNotFound)
+				store.EXPECT().ListStandingOrderRuns(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/standing_orders/%d/runs?page_id=1&page_size=5", standingOrder.ID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, util.DepositorRole, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestGenerateStandingOrderRuns(t *testing.T) {
+	user, _ := randomUser(t)
+	account1 := randomAccount(user.Username)
+	account2 := randomAccount(user.Username)
+	standingOrder := randomStandingOrder(user.Username, account1, account2)
+	run := randomScheduledTransfer(user.Username, account1, account2)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	gomock.InOrder(
+		store.EXPECT().GenerateStandingOrderRunTx(gomock.Any()).Times(2).
+			Return(db.GenerateStandingOrderRunTxResult{StandingOrder: standingOrder, Run: run}, nil),
+		store.EXPECT().GenerateStandingOrderRunTx(gomock.Any()).Times(1).
+			Return(db.GenerateStandingOrderRunTxResult{}, db.ErrRecordNotFound),
+	)
+
+	server := newTestServer(t, store)
+	require.Equal(t, 2, server.generateStandingOrderRuns(context.Background()))
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+)
+
+const defaultTLSReloadInterval = time.Minute
+
+var errTLSDisabled = errors.New("TLS is not enabled")
+
+// tlsReloader serves the certificate and client CAs read from the files of the config.
+// The files are read again whenever they are modified, so that certificates can be renewed without a restart.
+type tlsReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	clientAuth   tls.ClientAuthType
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+	// modTimes are the modification times of the files when they were last loaded
+	modTimes []time.Time
+}
+
+// newTLSReloader loads the TLS files of the config. It returns nil if TLS is not configured.
+func newTLSReloader(config util.Config) (*tlsReloader, error) {
+	clientAuth, err := tlsClientAuth(config.TLSClientAuth)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.TLSCertFile == "" && config.TLSKeyFile == "" {
+		if clientAuth != tls.NoClientCert || config.TLSClientCAFile != "" {
+			return nil, fmt.Errorf("TLS client auth requires a TLS certificate and key")
+		}
+		return nil, nil
+	}
+	if config.TLSCertFile == "" || config.TLSKeyFile == "" {
+		return nil, fmt.Errorf("TLS requires both a certificate and a key file")
+	}
+	if clientAuth != tls.NoClientCert && config.TLSClientCAFile == "" {
+		return nil, fmt.Errorf("TLS client auth %s requires a client CA file", config.TLSClientAuth)
+	}
+
+	reloader := &tlsReloader{
+		certFile:     config.TLSCertFile,
+		keyFile:      config.TLSKeyFile,
+		clientCAFile: config.TLSClientCAFile,
+		clientAuth:   clientAuth,
+	}
+	err = reloader.reload()
+	if err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// tlsClientAuth maps the TLS_CLIENT_AUTH mode to the verification of client certificates
+func tlsClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch mode {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "optional":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("unsupported TLS client auth: %s", mode)
+}
+
+func (reloader *tlsReloader) files() []string {
+	files := []string{reloader.certFile, reloader.keyFile}
+	if reloader.clientCAFile != "" {
+		files = append(files, reloader.clientCAFile)
+	}
+	return files
+}
+
+func (reloader *tlsReloader) fileModTimes() ([]time.Time, error) {
+	files := reloader.files()
+	modTimes := make([]time.Time, len(files))
+	for i, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes[i] = info.ModTime()
+	}
+	return modTimes, nil
+}
+
+// reload reads the certificate, key and client CAs from their files.
+// The previous ones are kept if any of the files is invalid.
+func (reloader *tlsReloader) reload() error {
+	modTimes, err := reloader.fileModTimes()
+	if err != nil {
+		return err
+	}
+
+	certificate, err := tls.LoadX509KeyPair(reloader.certFile, reloader.keyFile)
+	if err != nil {
+		return fmt.Errorf("cannot load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if reloader.clientCAFile != "" {
+		data, err := os.ReadFile(reloader.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("cannot read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(data) {
+			return fmt.Errorf("no certificate found in client CA file %s", reloader.clientCAFile)
+		}
+	}
+
+	reloader.mu.Lock()
+	defer reloader.mu.Unlock()
+
+	reloader.certificate = &certificate
+	reloader.clientCAs = clientCAs
+	reloader.modTimes = modTimes
+	return nil
+}
+
+// reloadIfModified reloads the files if any of them was modified since they were last loaded.
+func (reloader *tlsReloader) reloadIfModified() (bool, error) {
+	modTimes, err := reloader.fileModTimes()
+	if err != nil {
+		return false, err
+	}
+
+	reloader.mu.RLock()
+	modified := false
+	for i, modTime := range modTimes {
+		if !modTime.Equal(reloader.modTimes[i]) {
+			modified = true
+		}
+	}
+	reloader.mu.RUnlock()
+
+	if !modified {
+		return false, nil
+	}
+	return true, reloader.reload()
+}
+
+// watch periodically reloads the files that were modified.
+func (reloader *tlsReloader) watch(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultTLSReloadInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		reloaded, err := reloader.reloadIfModified()
+		if err != nil {
+			log.Println("cannot reload TLS certificate:", err)
+			continue
+		}
+		if reloaded {
+			log.Println("TLS certificate reloaded")
+		}
+	}
+}
+
+// tlsConfig returns the config of the TLS listener, which picks up the latest certificate and client CAs at each handshake.
+func (reloader *tlsReloader) tlsConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		GetConfigForClient: reloader.getConfigForClient,
+	}
+}
+
+func (reloader *tlsReloader) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	reloader.mu.RLock()
+	defer reloader.mu.RUnlock()
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+		Certificates: []tls.Certificate{*reloader.certificate},
+		ClientAuth:   reloader.clientAuth,
+		ClientCAs:    reloader.clientCAs,
+	}, nil
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// newTestCertificate creates a certificate for the common name, self-signed if parent is nil
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{commonName},
+	}
+
+	issuer, issuerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, issuerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCertificate{certificate: certificate, key: key}
+}
+
+func (cert *testCertificate) certPEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.certificate.Raw})
+}
+
+func (cert *testCertificate) keyPEM(t *testing.T) []byte {
+	der, err := x509.MarshalECPrivateKey(cert.key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+func (cert *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, err := tls.X509KeyPair(cert.certPEM(), cert.keyPEM(t))
+	require.NoError(t, err)
+	return certificate
+}
+
+// writeTestCertificate writes the certificate and its key to files of the directory,
+// setting their modification time so that a reload notices the change.
+func writeTestCertificate(t *testing.T, dir string, cert *testCertificate, modTime time.Time) (string, string) {
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	require.NoError(t, os.WriteFile(certFile, cert.certPEM(), 0600))
+	require.NoError(t, os.WriteFile(keyFile, cert.keyPEM(t), 0600))
+	require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+	require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	return certFile, keyFile
+}
+
+func writeTestCA(t *testing.T, dir string, ca *testCertificate) string {
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, ca.certPEM(), 0600))
+	return caFile
+}
+
+func TestTLSReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "ca.oldbank.internal", nil)
+	cert1 := newTestCertificate(t, "localhost", ca)
+	cert2 := newTestCertificate(t, "localhost", ca)
+
+	certFile, keyFile := writeTestCertificate(t, dir, cert1, time.Now())
+	reloader, err := newTLSReloader(util.Config{
+		TLSCertFile:     certFile,
+		TLSKeyFile:      keyFile,
+		TLSClientCAFile: writeTestCA(t, dir, ca),
+		TLSClientAuth:   "optional",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, reloader)
+
+	servedCertificate := func() []byte {
+		config, err := reloader.getConfigForClient(nil)
+		require.NoError(t, err)
+		require.Equal(t, tls.VerifyClientCertIfGiven, config.ClientAuth)
+		require.NotNil(t, config.ClientCAs)
+		require.Len(t, config.Certificates, 1)
+		return config.Certificates[0].Certificate[0]
+	}
+	require.Equal(t, cert1.certificate.Raw, servedCertificate())
+
+	reloaded, err := reloader.reloadIfModified()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	writeTestCertificate(t, dir, cert2, time.Now().Add(time.Minute))
+	reloaded, err = reloader.reloadIfModified()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.Equal(t, cert2.certificate.Raw, servedCertificate())
+
+	// an invalid certificate keeps serving the previous one
+	require.NoError(t, os.WriteFile(certFile, []byte("invalid"), 0600))
+	require.NoError(t, os.Chtimes(certFile, time.Now().Add(2*time.Minute), time.Now().Add(2*time.Minute)))
+	reloaded, err = reloader.reloadIfModified()
+	require.Error(t, err)
+	require.True(t, reloaded)
+	require.Equal(t, cert2.certificate.Raw, servedCertificate())
+}
+
+func TestNewTLSReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "ca.oldbank.internal", nil)
+	certFile, keyFile := writeTestCertificate(t, dir, newTestCertificate(t, "localhost", ca), time.Now())
+	caFile := writeTestCA(t, dir, ca)
+
+	reloader, err := newTLSReloader(util.Config{})
+	require.NoError(t, err)
+	require.Nil(t, reloader)
+
+	reloader, err = newTLSReloader(util.Config{TLSCertFile: certFile, TLSKeyFile: keyFile})
+	require.NoError(t, err)
+	require.NotNil(t, reloader)
+	require.Equal(t, tls.NoClientCert, reloader.clientAuth)
+
+	invalidConfigs := map[string]util.Config{
+		"MissingKey":         {TLSCertFile: certFile},
+		"MissingCertificate": {TLSClientCAFile: caFile, TLSClientAuth: "require"},
+		"MissingClientCA":    {TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientAuth: "require"},
+		"UnsupportedAuth":    {TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile, TLSClientAuth: "always"},
+		"InvalidClientCA":    {TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: keyFile, TLSClientAuth: "require"},
+		"FileNotFound":       {TLSCertFile: filepath.Join(dir, "missing.pem"), TLSKeyFile: keyFile},
+	}
+	for name, config := range invalidConfigs {
+		_, err := newTLSReloader(config)
+		require.Error(t, err, name)
+	}
+}
+
+func TestNewClientCertAuthenticator(t *testing.T) {
+	authenticator, err := newClientCertAuthenticator(util.Config{})
+	require.NoError(t, err)
+	require.Nil(t, authenticator)
+
+	_, err = newClientCertAuthenticator(util.Config{TLSServiceIdentities: "ops.internal:ops:admin:accounts:read"})
+	require.Error(t, err)
+
+	_, err = newClientCertAuthenticator(util.Config{TLSServiceIdentities: "ops.internal:ops", TLSClientAuth: "require"})
+	require.Error(t, err)
+
+	authenticator, err = newClientCertAuthenticator(util.Config{TLSServiceIdentities: "ops.internal:ops:admin:accounts:read", TLSClientAuth: "require"})
+	require.NoError(t, err)
+	require.Equal(t, util.ServiceIdentity{Name: "ops", Role: util.AdminRole, Scopes: []string{util.AccountsReadScope}}, authenticator.identities["ops.internal"])
+}
+
+func TestAuthMiddlewareClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "ca.oldbank.internal", nil)
+	otherCA := newTestCertificate(t, "ca.other.internal", nil)
+	certFile, keyFile := writeTestCertificate(t, dir, newTestCertificate(t, "localhost", ca), time.Now())
+
+	config := util.Config{
+		TLSCertFile:          certFile,
+		TLSKeyFile:           keyFile,
+		TLSClientCAFile:      writeTestCA(t, dir, ca),
+		TLSClientAuth:        "optional",
+		TLSServiceIdentities: "ops.internal:ops:admin:accounts:read",
+	}
+	reloader, err := newTLSReloader(config)
+	require.NoError(t, err)
+	clientCerts, err := newClientCertAuthenticator(config)
+	require.NoError(t, err)
+
+	server := newTestServer(t, nil)
+	authPath := "/auth"
+	server.router.GET(
+		authPath,
+		authMiddleware(server.tokenMaker, server.revocationStore, server.dpopVerifier, nil, clientCerts),
+		func(ctx *gin.Context) {
+			ctx.JSON(http.StatusOK, ctx.MustGet(authorizationPayloadKey))
+		},
+	)
+
+	httpServer := httptest.NewUnstartedServer(server.router)
+	httpServer.TLS = reloader.tlsConfig()
+	httpServer.StartTLS()
+	defer httpServer.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca.certificate)
+
+	testCases := []struct {
+		name          string
+		clientCert    *testCertificate
+		setupAuth     func(t *testing.T, request *http.Request)
+		checkResponse func(t *testing.T, response *http.Response, err error)
+	}{
+		{
+			name:       "OK",
+			clientCert: newTestCertificate(t, "ops.internal", ca),
+			setupAuth:  func(t *testing.T, request *http.Request) {},
+			checkResponse: func(t *testing.T, response *http.Response, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, response.StatusCode)
+
+				var payload token.Payload
+				err = json.NewDecoder(response.Body).Decode(&payload)
+				require.NoError(t, err)
+				require.Equal(t, "service:ops", payload.Username)
+				require.Equal(t, util.AdminRole, payload.Role)
+				require.Equal(t, []string{util.AccountsReadScope}, payload.Scopes)
+			},
+		},
+		{
+			name:       "AuthorizationHeaderTakesPrecedence",
+			clientCert: newTestCertificate(t, "ops.internal", ca),
+			setupAuth: func(t *testing.T, request *http.Request) {
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "user", util.DepositorRole, time.Minute)
+			},
+			checkResponse: func(t *testing.T, response *http.Response, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, response.StatusCode)
+
+				var payload token.Payload
+				err = json.NewDecoder(response.Body).Decode(&payload)
+				require.NoError(t, err)
+				require.Equal(t, "user", payload.Username)
+			},
+		},
+		{
+			name:       "UnknownCertificate",
+			clientCert: newTestCertificate(t, "unknown.internal", ca),
+			setupAuth:  func(t *testing.T, request *http.Request) {},
+			checkResponse: func(t *testing.T, response *http.Response, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+		{
+			name:      "NoCertificate",
+			setupAuth: func(t *testing.T, request *http.Request) {},
+			checkResponse: func(t *testing.T, response *http.Response, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+		{
+			// the client does not send a certificate that is not issued by the client CAs of the server
+			name:       "UntrustedCertificate",
+			clientCert: newTestCertificate(t, "ops.internal", otherCA),
+			setupAuth:  func(t *testing.T, request *http.Request) {},
+			checkResponse: func(t *testing.T, response *http.Response, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig := &tls.Config{RootCAs: rootCAs, ServerName: "localhost"}
+			if tc.clientCert != nil {
+				tlsConfig.Certificates = []tls.Certificate{tc.clientCert.tlsCertificate(t)}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+			request, err := http.NewRequest(http.MethodGet, httpServer.URL+authPath, nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request)
+
+			response, err := client.Do(request)
+			if err == nil {
+				defer response.Body.Close()
+			}
+			tc.checkResponse(t, response, err)
+		})
+	}
+}
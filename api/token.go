@@ -0,0 +1,156 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/gin-gonic/gin"
+)
+
+type renewAccessTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type renewAccessTokenResponse struct {
+	TokenType            string    `json:"token_type"`
+	AccessToken          string    `json:"access_token"`
+	AccessTokenExpiresAt time.Time `json:"access_token_expires_at"`
+}
+
+func (server *Server) renewAccessToken(ctx *gin.Context) {
+	var req renewAccessTokenRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	refreshPayload, err := server.tokenMaker.VerifyToken(req.RefreshToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if refreshPayload.Purpose != token.PurposeRefresh {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+		return
+	}
+
+	revoked, err := server.revocationStore.IsRevoked(ctx, refreshPayload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return
+	}
+
+	session, err := server.store.GetSession(ctx, refreshPayload.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if session.IsBlocked {
+		err := errors.New("blocked session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.Username != refreshPayload.Username {
+		err := errors.New("incorrect session user")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if session.RefreshToken != req.RefreshToken {
+		err := errors.New("mismatched session token")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		err := errors.New("expired session")
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// A bound refresh token can only be used by the holder of its key, which the new access token is bound to
+	if refreshPayload.IsDPoPBound() && ctx.GetString(dpopThumbprintKey) != refreshPayload.Confirmation.JWKThumbprint {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errDPoPKeyMismatch))
+		return
+	}
+
+	err = server.store.TouchSession(ctx, db.TouchSessionParams{
+		ID:       session.ID,
+		ClientIp: ctx.ClientIP(),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessPayload, err := token.NewPayload(
+		refreshPayload.Username,
+		refreshPayload.Role,
+		server.config.AccessTokenDuration,
+	)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	accessPayload.SessionID = session.ID
+
+	accessToken, err := server.createBoundToken(ctx, accessPayload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := renewAccessTokenResponse{
+		TokenType:            tokenType(accessPayload),
+		AccessToken:          accessToken,
+		AccessTokenExpiresAt: accessPayload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// longestTokenLifetime is how long a revocation of all the tokens of a user has to be kept
+// for every one of them to have expired, including the clock skew they are accepted with
+func (server *Server) longestTokenLifetime() time.Duration {
+	return max(
+		server.config.AccessTokenDuration,
+		server.config.RefreshTokenDuration,
+		server.oauthAccessTokenDuration(),
+		server.mfaTokenDuration(),
+		server.relyingParty.Timeout,
+	) + server.config.TokenClockSkew
+}
+
+// revocationExpiry is how long the revocation of a token expiring at expiredAt has to be kept,
+// since the token is accepted until then
+func (server *Server) revocationExpiry(expiredAt time.Time) time.Time {
+	return expiredAt.Add(server.config.TokenClockSkew)
+}
+
+type jsonWebKeySetResponse struct {
+	Keys []token.JSONWebKey `json:"keys"`
+}
+
+// getJSONWebKeySet publishes the public keys verifying access tokens, so that
+// other services can check them without being able to mint new ones.
+func (server *Server) getJSONWebKeySet(ctx *gin.Context) {
+	rsp := jsonWebKeySetResponse{Keys: []token.JSONWebKey{}}
+	if provider, ok := server.tokenMaker.(token.PublicKeyProvider); ok {
+		rsp.Keys = provider.PublicKeys()
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildSession  func(t *testing.T, tokenMaker token.Maker) (string, db.Session)
+		buildStubs    func(store *mockdb.MockStore, session db.Session)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return randomSession(t, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+				store.EXPECT().
+					TouchSession(gomock.Any(), gomock.Eq(db.TouchSessionParams{
+						ID:       session.ID,
+						ClientIp: "",
+					})).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp renewAccessTokenResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+			},
+		},
+		{
+			name: "AccessToken",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				accessToken, _, err := tokenMaker.CreateToken(user.Username, user.Role, time.Hour)
+				require.NoError(t, err)
+				return accessToken, db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidToken",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return "invalid", db.Session{}
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SessionNotFound",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return randomSession(t, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(db.Session{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "BlockedSession",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, user.Username)
+				session.IsBlocked = true
+				return refreshToken, session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "IncorrectSessionUser",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, user.Username)
+				session.Username = "unauthorized"
+				return refreshToken, session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MismatchedSessionToken",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, user.Username)
+				session.RefreshToken = "mismatched"
+				return refreshToken, session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredSession",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				refreshToken, session := randomSession(t, tokenMaker, user.Username)
+				session.ExpiresAt = time.Now().Add(-time.Minute)
+				return refreshToken, session
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Eq(session.ID)).
+					Times(1).
+					Return(session, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			buildSession: func(t *testing.T, tokenMaker token.Maker) (string, db.Session) {
+				return randomSession(t, tokenMaker, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, session db.Session) {
+				store.EXPECT().
+					GetSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Session{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			refreshToken, session := tc.buildSession(t, server.tokenMaker)
+			tc.buildStubs(store, session)
+
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			url := "/tokens/renew_access"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func randomSession(t *testing.T, tokenMaker token.Maker, username string) (string, db.Session) {
+	payload, err := token.NewPayload(username, util.DepositorRole, time.Hour)
+	require.NoError(t, err)
+	payload.Purpose = token.PurposeRefresh
+
+	refreshToken, err := tokenMaker.CreateTokenFromPayload(payload)
+	require.NoError(t, err)
+
+	session := db.Session{
+		ID:           payload.ID,
+		Username:     username,
+		RefreshToken: refreshToken,
+		ExpiresAt:    payload.ExpiredAt,
+	}
+	return refreshToken, session
+}
+
+func TestLongestTokenLifetime(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.config.TokenClockSkew = 30 * time.Second
+
+	// OAuth access tokens outlive the access tokens of the tests by default
+	require.Equal(t, defaultOAuthAccessTokenDuration+30*time.Second, server.longestTokenLifetime())
+
+	server.config.RefreshTokenDuration = 24 * time.Hour
+	require.Equal(t, 24*time.Hour+30*time.Second, server.longestTokenLifetime())
+
+	// Tokens are accepted until the clock skew after they expire, and so have to stay revoked
+	expiredAt := time.Now()
+	require.Equal(t, expiredAt.Add(30*time.Second), server.revocationExpiry(expiredAt))
+}
+
+func TestGetJSONWebKeySetAPI(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        func(config *util.Config)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "SymmetricMaker",
+			config: func(config *util.Config) {},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp jsonWebKeySetResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.Keys)
+				require.Empty(t, rsp.Keys)
+			},
+		},
+		{
+			name: "PublicMaker",
+			config: func(config *util.Config) {
+				config.TokenMaker = "paseto_public"
+				config.TokenPrivateKey = hex.EncodeToString([]byte(util.RandomString(32)))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp jsonWebKeySetResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.Keys, 1)
+				require.Equal(t, "OKP", rsp.Keys[0].KeyType)
+				require.NotEmpty(t, rsp.Keys[0].X)
+				require.NotEmpty(t, rsp.Keys[0].KeyID)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+
+			config := util.Config{
+				TokenSymmetricKey:   util.RandomString(32),
+				AccessTokenDuration: time.Minute,
+				RevocationStore:     "memory",
+			}
+			tc.config(&config)
+
+			server, err := NewServer(config, store)
+			require.NoError(t, err)
+
+			recorder := httptest.NewRecorder()
+
+			url := "/.well-known/jwks.json"
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultTOTPIssuer       = "OldBank"
+	defaultMFATokenDuration = 5 * time.Minute
+	recoveryCodeCount       = 10
+	recoveryCodeLength      = 10
+)
+
+var (
+	errTOTPAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	errTOTPNotEnabled     = errors.New("two-factor authentication is not enabled")
+	errInvalidTOTPCode    = errors.New("invalid two-factor authentication code")
+)
+
+type enrollTOTPResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// enrollTOTP generates a new TOTP secret for the user. It is only enabled once confirmed with a valid code.
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	totpSecret, err := server.store.GetTotpSecret(ctx, authPayload.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && totpSecret.IsEnabled {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTOTPAlreadyEnabled))
+		return
+	}
+
+	secret, err := util.GenerateTOTPSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	encryptedSecret, err := util.EncryptSecret(server.config.TOTPEncryptionKey, []byte(secret))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.UpsertTotpSecret(ctx, db.UpsertTotpSecretParams{
+		Username:        authPayload.Username,
+		EncryptedSecret: encryptedSecret,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	issuer := server.config.TOTPIssuer
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+
+	rsp := enrollTOTPResponse{
+		Secret: secret,
+		URI:    util.TOTPURI(issuer, authPayload.Username, secret),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type confirmTOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type confirmTOTPResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// confirmTOTP enables the enrolled TOTP secret and returns the recovery codes of the user.
+// The recovery codes are only stored hashed, so this is the only time they are shown.
+func (server *Server) confirmTOTP(ctx *gin.Context) {
+	var req confirmTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	totpSecret, err := server.store.GetTotpSecret(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if totpSecret.IsEnabled {
+		ctx.JSON(http.StatusForbidden, errorResponse(errTOTPAlreadyEnabled))
+		return
+	}
+
+	valid, err := server.verifyTOTPCode(ctx, totpSecret, req.Code)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTOTPCode))
+		return
+	}
+
+	recoveryCodes := make([]string, recoveryCodeCount)
+	hashedRecoveryCodes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		recoveryCodes[i], err = util.RandomSecret(recoveryCodeLength)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		hashedRecoveryCodes[i] = util.HashSecret(recoveryCodes[i])
+	}
+
+	_, err = server.store.EnableTotpTx(ctx, db.EnableTotpTxParams{
+		Username:            authPayload.Username,
+		HashedRecoveryCodes: hashedRecoveryCodes,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := confirmTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type disableTOTPRequest struct {
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// disableTOTP turns two-factor authentication off, which requires a valid code or recovery code.
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var req disableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.checkLoginThrottle(ctx, authPayload.Username) {
+		return
+	}
+
+	totpSecret, err := server.store.GetTotpSecret(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errTOTPNotEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !totpSecret.IsEnabled {
+		ctx.JSON(http.StatusNotFound, errorResponse(errTOTPNotEnabled))
+		return
+	}
+
+	valid, err := server.verifySecondFactor(ctx, totpSecret, req.Code, req.RecoveryCode)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		// Guessing the code of a stolen session is throttled like guessing it at login
+		err = server.recordLoginFailure(ctx, authPayload.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTOTPCode))
+		return
+	}
+
+	// The recovery codes of the user are deleted with the secret
+	err = server.store.DeleteTotpSecret(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type loginMFARequiredResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+func (server *Server) mfaTokenDuration() time.Duration {
+	if server.config.MFATokenDuration <= 0 {
+		return defaultMFATokenDuration
+	}
+	return server.config.MFATokenDuration
+}
+
+// requireSecondFactor answers a login with a short-lived token that can only be exchanged
+// for an access token together with a valid second factor.
+func (server *Server) requireSecondFactor(ctx *gin.Context, user db.User) {
+	payload, err := token.NewPayload(user.Username, user.Role, server.mfaTokenDuration())
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	payload.Purpose = token.PurposeMFAPending
+
+	mfaToken, err := server.tokenMaker.CreateTokenFromPayload(payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := loginMFARequiredResponse{
+		MFARequired:       true,
+		MFAToken:          mfaToken,
+		MFATokenExpiresAt: payload.ExpiredAt,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type loginMFARequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// loginMFA completes the login of a user with two-factor authentication enabled.
+func (server *Server) loginMFA(ctx *gin.Context) {
+	var req loginMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	mfaPayload, err := server.tokenMaker.VerifyToken(req.MFAToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+	if mfaPayload.Purpose != token.PurposeMFAPending {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrInvalidToken))
+		return
+	}
+
+	revoked, err := server.revocationStore.IsRevoked(ctx, mfaPayload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return
+	}
+
+	if !server.checkLoginThrottle(ctx, mfaPayload.Username) {
+		return
+	}
+
+	totpSecret, err := server.store.GetTotpSecret(ctx, mfaPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errTOTPNotEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !totpSecret.IsEnabled {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errTOTPNotEnabled))
+		return
+	}
+
+	valid, err := server.verifySecondFactor(ctx, totpSecret, req.Code, req.RecoveryCode)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !valid {
+		// Codes are short, so guessing them is throttled like guessing passwords
+		err = server.recordLoginFailure(ctx, mfaPayload.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTOTPCode))
+		return
+	}
+
+	// The MFA token can only be exchanged once
+	err = server.revocationStore.RevokeToken(ctx, mfaPayload.ID, mfaPayload.Username, server.revocationExpiry(mfaPayload.ExpiredAt))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, mfaPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := server.newLoginSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// verifySecondFactor checks the TOTP code if one is given, otherwise it consumes the recovery code.
+func (server *Server) verifySecondFactor(ctx *gin.Context, totpSecret db.TotpSecret, code string, recoveryCode string) (bool, error) {
+	if code != "" {
+		return server.verifyTOTPCode(ctx, totpSecret, code)
+	}
+
+	rows, err := server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+		Username:   totpSecret.Username,
+		HashedCode: util.HashSecret(recoveryCode),
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// verifyTOTPCode checks the code against the secret of the user, rejecting codes that were already used.
+func (server *Server) verifyTOTPCode(ctx *gin.Context, totpSecret db.TotpSecret, code string) (bool, error) {
+	secret, err := util.DecryptSecret(server.config.TOTPEncryptionKey, totpSecret.EncryptedSecret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := util.ValidateTOTPCode(string(secret), code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	rows, err := server.store.UpdateTotpLastUsedStep(ctx, db.UpdateTotpLastUsedStepParams{
+		Step:     step,
+		Username: totpSecret.Username,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
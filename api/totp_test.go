@@ -0,0 +1,707 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				store.EXPECT().
+					UpsertTotpSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp enrollTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.Secret)
+				require.Contains(t, rsp.URI, rsp.Secret)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{Username: user.Username, IsEnabled: true}, nil)
+				store.EXPECT().
+					UpsertTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NoAuthorization",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					UpsertTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InternalError",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrConnDone)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusInternalServerError, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := "/users/me/totp"
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestConfirmTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(secret string) gin.H
+		buildStubs    func(store *mockdb.MockStore, totpSecret db.TotpSecret)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UpdateTotpLastUsedStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.EnableTotpTxParams) (db.EnableTotpTxResult, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Len(t, arg.HashedRecoveryCodes, recoveryCodeCount)
+						return db.EnableTotpTxResult{}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp confirmTOTPResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Len(t, rsp.RecoveryCodes, recoveryCodeCount)
+			},
+		},
+		{
+			name: "InvalidCode",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": staleTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ReplayedCode",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UpdateTotpLastUsedStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": "123456"}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyEnabled",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				totpSecret.IsEnabled = true
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					EnableTotpTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidBody",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": "12ab"}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			secret, totpSecret := randomTotpSecret(t, server.config.TOTPEncryptionKey, user.Username)
+			tc.buildStubs(store, totpSecret)
+
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.buildBody(secret))
+			require.NoError(t, err)
+
+			url := "/users/me/totp/confirm"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDisableTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(secret string) gin.H
+		buildStubs    func(store *mockdb.MockStore, totpSecret db.TotpSecret)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UpdateTotpLastUsedStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					DeleteTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "OKWithRecoveryCode",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"recovery_code": "recoverycode"}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{
+						Username:   user.Username,
+						HashedCode: util.HashSecret("recoverycode"),
+					})).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					DeleteTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": staleTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					DeleteTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LockedOut",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, []db.LoginThrottle{
+					{
+						Scope:       loginScopeUsername,
+						Subject:     user.Username,
+						LockedUntil: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+					},
+				})
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnabled",
+			buildBody: func(secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				totpSecret.IsEnabled = false
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					DeleteTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCode",
+			buildBody: func(secret string) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			secret, totpSecret := randomTotpSecret(t, server.config.TOTPEncryptionKey, user.Username)
+			totpSecret.IsEnabled = true
+			tc.buildStubs(store, totpSecret)
+
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.buildBody(secret))
+			require.NoError(t, err)
+
+			url := "/users/me/totp"
+			request, err := http.NewRequest(http.MethodDelete, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginMFAAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(t *testing.T, server *Server, secret string) gin.H
+		buildStubs    func(store *mockdb.MockStore, totpSecret db.TotpSecret)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildBody: func(t *testing.T, server *Server, secret string) gin.H {
+				return gin.H{
+					"mfa_token": randomMFAToken(t, server, user),
+					"code":      currentTOTPCode(t, secret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UpdateTotpLastUsedStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "OKWithRecoveryCode",
+			buildBody: func(t *testing.T, server *Server, secret string) gin.H {
+				return gin.H{
+					"mfa_token":     randomMFAToken(t, server, user),
+					"recovery_code": "recoverycode",
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			buildBody: func(t *testing.T, server *Server, secret string) gin.H {
+				return gin.H{
+					"mfa_token":     randomMFAToken(t, server, user),
+					"recovery_code": "recoverycode",
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			buildBody: func(t *testing.T, server *Server, secret string) gin.H {
+				return gin.H{
+					"mfa_token": randomMFAToken(t, server, user),
+					"code":      staleTOTPCode(t, secret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "AccessTokenInsteadOfMFAToken",
+			buildBody: func(t *testing.T, server *Server, secret string) gin.H {
+				accessToken, _, err := server.tokenMaker.CreateToken(user.Username, user.Role, time.Minute)
+				require.NoError(t, err)
+				return gin.H{
+					"mfa_token": accessToken,
+					"code":      currentTOTPCode(t, secret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ReusedMFAToken",
+			buildBody: func(t *testing.T, server *Server, secret string) gin.H {
+				mfaToken := randomMFAToken(t, server, user)
+				payload, err := server.tokenMaker.VerifyToken(mfaToken)
+				require.NoError(t, err)
+
+				err = server.revocationStore.RevokeToken(context.Background(), payload.ID, payload.Username, payload.ExpiredAt)
+				require.NoError(t, err)
+
+				return gin.H{
+					"mfa_token": mfaToken,
+					"code":      currentTOTPCode(t, secret),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCode",
+			buildBody: func(t *testing.T, server *Server, secret string) gin.H {
+				return gin.H{
+					"mfa_token": randomMFAToken(t, server, user),
+				}
+			},
+			buildStubs: func(store *mockdb.MockStore, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			secret, totpSecret := randomTotpSecret(t, server.config.TOTPEncryptionKey, user.Username)
+			totpSecret.IsEnabled = true
+			tc.buildStubs(store, totpSecret)
+
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.buildBody(t, server, secret))
+			require.NoError(t, err)
+
+			url := "/users/login/mfa"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func randomTotpSecret(t *testing.T, encryptionKey string, username string) (string, db.TotpSecret) {
+	secret, err := util.GenerateTOTPSecret()
+	require.NoError(t, err)
+
+	encryptedSecret, err := util.EncryptSecret(encryptionKey, []byte(secret))
+	require.NoError(t, err)
+
+	totpSecret := db.TotpSecret{
+		Username:        username,
+		EncryptedSecret: encryptedSecret,
+	}
+	return secret, totpSecret
+}
+
+func randomMFAToken(t *testing.T, server *Server, user db.User) string {
+	payload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+	payload.Purpose = token.PurposeMFAPending
+
+	mfaToken, err := server.tokenMaker.CreateTokenFromPayload(payload)
+	require.NoError(t, err)
+	return mfaToken
+}
+
+func currentTOTPCode(t *testing.T, secret string) string {
+	code, err := util.GenerateTOTPCode(secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func staleTOTPCode(t *testing.T, secret string) string {
+	code, err := util.GenerateTOTPCode(secret, util.TOTPStep(time.Now().Add(-time.Hour)))
+	require.NoError(t, err)
+	return code
+}
@@ -8,6 +8,7 @@ import (
 
 	db "github.com/JMustang/OldBank/db/sqlc"
 	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,8 +17,13 @@ type transferRequest struct {
 	ToAccountID   int64  `json:"to_account_id" binding:"required,min=1"`
 	Amount        int64  `json:"amount" binding:"required,gt=0"`
 	Currency      string `json:"currency" binding:"required,currency"`
+	// QuoteID is the fx quote locking the rate of a transfer to an account of another currency
+	QuoteID string `json:"quote_id" binding:"omitempty,uuid"`
 }
 
+// createTransfer moves money from an account of the user. Transfers to an account of another currency
+// are converted at the rate locked by the fx quote of the request. Retries of a request carrying an Idempotency-Key header
+// replay the response of the first transfer, or the challenge of a transfer awaiting step-up confirmation.
 func (server *Server) createTransfer(ctx *gin.Context) {
 	var req transferRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -25,31 +31,98 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		return
 	}
 
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	idempotencyKey, valid := server.idempotencyKey(ctx, authPayload.Username, req)
+	if !valid {
+		return
+	}
+	if idempotencyKey != nil && server.replayTransfer(ctx, idempotencyKey) {
+		return
+	}
+
 	fromAccount, valid := server.validAccount(ctx, req.FromAccountID, req.Currency)
 	if !valid {
 		return
 	}
 
-	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 	if fromAccount.Owner != authPayload.Username {
 		err := errors.New("from account doesn't belong to the authenticated user")
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
 		return
 	}
 
-	_, valid = server.validAccount(ctx, req.ToAccountID, req.Currency)
+	toCurrency := req.Currency
+	var quote db.FxQuote
+	if req.QuoteID != "" {
+		quote, valid = server.validFxQuote(ctx, authPayload.Username, req)
+		if !valid {
+			return
+		}
+		toAmount, err := util.ConvertAmount(req.Amount, quote.Rate)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if toAmount <= 0 {
+			ctx.JSON(http.StatusBadRequest, errorResponse(db.ErrZeroConvertedAmount))
+			return
+		}
+		toCurrency = quote.ToCurrency
+	}
+
+	_, valid = server.validAccount(ctx, req.ToAccountID, toCurrency)
 	if !valid {
 		return
 	}
 
-	arg := db.TransferTxParams{
-		FromAccountID: req.FromAccountID,
-		ToAccountID:   req.ToAccountID,
-		Amount:        req.Amount,
+	if server.requiresStepUp(req.Amount, req.Currency) {
+		server.createTransferChallenge(ctx, authPayload.Username, req, idempotencyKey)
+		return
 	}
 
-	result, err := server.store.TransferTx(ctx, arg)
+	var result db.TransferTxResult
+	var err error
+	if req.QuoteID != "" {
+		var crossCurrencyResult db.CrossCurrencyTransferTxResult
+		crossCurrencyResult, err = server.store.CrossCurrencyTransferTx(ctx, db.CrossCurrencyTransferTxParams{
+			FromAccountID:  req.FromAccountID,
+			ToAccountID:    req.ToAccountID,
+			Amount:         req.Amount,
+			QuoteID:        quote.ID,
+			Username:       authPayload.Username,
+			IdempotencyKey: idempotencyKey,
+		})
+		result = crossCurrencyResult.TransferTxResult
+	} else {
+		result, err = server.store.TransferTx(ctx, db.TransferTxParams{
+			FromAccountID:  req.FromAccountID,
+			ToAccountID:    req.ToAccountID,
+			Amount:         req.Amount,
+			IdempotencyKey: idempotencyKey,
+		})
+	}
 	if err != nil {
+		if req.QuoteID != "" && err == sql.ErrNoRows {
+			// the quote was used or expired since it was checked
+			ctx.JSON(http.StatusNotFound, errorResponse(errFxQuoteNotFound))
+			return
+		}
+		if errors.Is(err, db.ErrZeroConvertedAmount) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		if errors.Is(err, db.ErrIdempotencyKeyInUse) {
+			// a concurrent retry of the request executed the transfer first
+			if !server.replayTransfer(ctx, idempotencyKey) {
+				ctx.JSON(http.StatusConflict, errorResponse(errIdempotencyKeyConflict))
+			}
+			return
+		}
+		var insufficientFunds *db.InsufficientFundsError
+		if errors.As(err, &insufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultStepUpWindow = 5 * time.Minute
+
+	transferStatusAwaitingConfirmation = "awaiting_confirmation"
+)
+
+var errTransferChallengeNotFound = errors.New("transfer challenge not found, expired or already confirmed")
+
+// requiresStepUp returns true if the amount is above the step-up threshold of the currency
+func (server *Server) requiresStepUp(amount int64, currency string) bool {
+	threshold, ok := server.stepUpThresholds[currency]
+	return ok && amount > threshold
+}
+
+type transferChallengeResponse struct {
+	ChallengeID uuid.UUID `json:"challenge_id"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+func newTransferChallengeResponse(challenge db.TransferChallenge) transferChallengeResponse {
+	return transferChallengeResponse{
+		ChallengeID: challenge.ID,
+		Status:      challenge.Status,
+		ExpiresAt:   challenge.ExpiresAt,
+	}
+}
+
+// createTransferChallenge parks a high-value transfer until the user confirms it by re-authenticating.
+// The challenge is recorded under the idempotency key of the request, so that retries get the same challenge.
+func (server *Server) createTransferChallenge(ctx *gin.Context, username string, req transferRequest, idempotencyKey *db.IdempotencyKeyParams) {
+	window := server.config.StepUpWindow
+	if window <= 0 {
+		window = defaultStepUpWindow
+	}
+
+	challengeID, err := uuid.NewRandom()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	var fxQuoteID uuid.NullUUID
+	if req.QuoteID != "" {
+		fxQuoteID = uuid.NullUUID{UUID: uuid.MustParse(req.QuoteID), Valid: true}
+	}
+
+	result, err := server.store.CreateTransferChallengeTx(ctx, db.CreateTransferChallengeTxParams{
+		CreateTransferChallengeParams: db.CreateTransferChallengeParams{
+			ID:            challengeID,
+			Username:      username,
+			FromAccountID: req.FromAccountID,
+			ToAccountID:   req.ToAccountID,
+			Amount:        req.Amount,
+			Currency:      req.Currency,
+			ExpiresAt:     time.Now().Add(window),
+			FxQuoteID:     fxQuoteID,
+		},
+		IdempotencyKey: idempotencyKey,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrIdempotencyKeyInUse) {
+			// a concurrent retry of the request created the challenge first
+			if !server.replayTransfer(ctx, idempotencyKey) {
+				ctx.JSON(http.StatusConflict, errorResponse(errIdempotencyKeyConflict))
+			}
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, newTransferChallengeResponse(result.TransferChallenge))
+}
+
+type confirmTransferChallengeURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+// stepUpRequest re-authenticates the user of a sensitive operation with their password, or a TOTP code instead
+type stepUpRequest struct {
+	Password string `json:"password" binding:"required_without=Code"`
+	Code     string `json:"code" binding:"required_without=Password,omitempty,len=6,numeric"`
+}
+
+// confirmTransferChallenge executes a parked transfer once the user has re-authenticated with
+// their password or a TOTP code. It is not available to API keys or OAuth clients, which cannot
+// re-authenticate the user.
+func (server *Server) confirmTransferChallenge(ctx *gin.Context) {
+	var uri confirmTransferChallengeURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req stepUpRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	challengeID := uuid.MustParse(uri.ID)
+
+	challenge, err := server.store.GetTransferChallenge(ctx, challengeID)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == sql.ErrNoRows ||
+		challenge.Username != authPayload.Username ||
+		challenge.Status != transferStatusAwaitingConfirmation ||
+		time.Now().After(challenge.ExpiresAt) {
+		ctx.JSON(http.StatusNotFound, errorResponse(errTransferChallengeNotFound))
+		return
+	}
+
+	if !server.reauthenticate(ctx, authPayload.Username, req) {
+		return
+	}
+
+	result, err := server.store.ConfirmTransferChallengeTx(ctx, db.ConfirmTransferChallengeTxParams{
+		ID:       challenge.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errTransferChallengeNotFound))
+			return
+		}
+		if errors.Is(err, db.ErrZeroConvertedAmount) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		// the challenge stays pending, so that it can be confirmed again once the account is funded
+		var insufficientFunds *db.InsufficientFundsError
+		if errors.As(err, &insufficientFunds) {
+			ctx.JSON(http.StatusUnprocessableEntity, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// reauthenticate checks the password or TOTP code of the user before a sensitive operation.
+// Guessing them with a stolen session is throttled like guessing them at login.
+// It responds with an error and returns false if the user could not be re-authenticated.
+func (server *Server) reauthenticate(ctx *gin.Context, username string, req stepUpRequest) bool {
+	if !server.checkLoginThrottle(ctx, username) {
+		return false
+	}
+
+	valid, err := server.verifyStepUp(ctx, username, req)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !valid {
+		err = server.recordLoginFailure(ctx, username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return false
+		}
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return false
+	}
+	return true
+}
+
+// verifyStepUp checks the password of the user, or the TOTP code if one is given instead.
+func (server *Server) verifyStepUp(ctx *gin.Context, username string, req stepUpRequest) (bool, error) {
+	if req.Code != "" {
+		totpSecret, err := server.store.GetTotpSecret(ctx, username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return false, nil
+			}
+			return false, err
+		}
+		if !totpSecret.IsEnabled {
+			return false, nil
+		}
+		return server.verifyTOTPCode(ctx, totpSecret, req.Code)
+	}
+
+	user, err := server.store.GetUser(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	return util.CheckPassword(req.Password, user.HashedPassword) == nil, nil
+}
@@ -0,0 +1,596 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testStepUpThreshold = int64(1000)
+
+func TestTransferStepUp(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	testCases := []struct {
+		name          string
+		amount        int64
+		thresholds    string
+		buildStubs    func(store *mockdb.MockStore, amount int64)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "AboveThreshold",
+			amount:     testStepUpThreshold + 1,
+			thresholds: fmt.Sprintf("USD:%d", testStepUpThreshold),
+			buildStubs: func(store *mockdb.MockStore, amount int64) {
+				store.EXPECT().
+					CreateTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateTransferChallengeTxParams) (db.CreateTransferChallengeTxResult, error) {
+						require.Nil(t, arg.IdempotencyKey)
+						require.Equal(t, user1.Username, arg.Username)
+						require.Equal(t, account1.ID, arg.FromAccountID)
+						require.Equal(t, account2.ID, arg.ToAccountID)
+						require.Equal(t, amount, arg.Amount)
+						require.Equal(t, util.USD, arg.Currency)
+						require.WithinDuration(t, time.Now().Add(defaultStepUpWindow), arg.ExpiresAt, time.Second)
+
+						challenge := db.TransferChallenge{
+							ID:        arg.ID,
+							Status:    transferStatusAwaitingConfirmation,
+							ExpiresAt: arg.ExpiresAt,
+						}
+						return db.CreateTransferChallengeTxResult{TransferChallenge: challenge}, nil
+					})
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+
+				var rsp transferChallengeResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotZero(t, rsp.ChallengeID)
+				require.Equal(t, transferStatusAwaitingConfirmation, rsp.Status)
+			},
+		},
+		{
+			name:       "AtThreshold",
+			amount:     testStepUpThreshold,
+			thresholds: fmt.Sprintf("USD:%d", testStepUpThreshold),
+			buildStubs: func(store *mockdb.MockStore, amount int64) {
+				store.EXPECT().
+					CreateTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "NoThresholdForCurrency",
+			amount:     testStepUpThreshold + 1,
+			thresholds: fmt.Sprintf("EUR:%d", testStepUpThreshold),
+			buildStubs: func(store *mockdb.MockStore, amount int64) {
+				store.EXPECT().
+					CreateTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					TransferTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+			store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+			tc.buildStubs(store, tc.amount)
+
+			server := newTestServer(t, store)
+			server.stepUpThresholds, _ = util.ParseCurrencyAmounts(tc.thresholds)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(gin.H{
+				"from_account_id": account1.ID,
+				"to_account_id":   account2.ID,
+				"amount":          tc.amount,
+				"currency":        util.USD,
+			})
+			require.NoError(t, err)
+
+			url := "/transfers"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestTransferStepUpIdempotencyKey(t *testing.T) {
+	amount := testStepUpThreshold + 1
+	key := util.RandomString(32)
+
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account1.Currency = util.USD
+	account2.Currency = util.USD
+
+	req := transferRequest{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+		Currency:      util.USD,
+	}
+	challenge := db.TransferChallenge{
+		ID:            uuid.New(),
+		Username:      user1.Username,
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+		Currency:      util.USD,
+		Status:        transferStatusAwaitingConfirmation,
+		ExpiresAt:     time.Now().Add(defaultStepUpWindow),
+	}
+	response, err := json.Marshal(challenge)
+	require.NoError(t, err)
+
+	record := db.IdempotencyKey{
+		Username:            user1.Username,
+		Key:                 key,
+		RequestHash:         hashTransferRequest(req),
+		Response:            response,
+		ExpiresAt:           time.Now().Add(time.Hour),
+		TransferChallengeID: uuid.NullUUID{UUID: challenge.ID, Valid: true},
+	}
+	getKeyArg := db.GetIdempotencyKeyParams{
+		Username: user1.Username,
+		Key:      key,
+	}
+
+	requireChallenge := func(t *testing.T, recorder *httptest.ResponseRecorder) {
+		require.Equal(t, http.StatusAccepted, recorder.Code)
+
+		var rsp transferChallengeResponse
+		err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+		require.NoError(t, err)
+		require.Equal(t, challenge.ID, rsp.ChallengeID)
+		require.Equal(t, transferStatusAwaitingConfirmation, rsp.Status)
+	}
+
+	testCases := []struct {
+		name          string
+		buildStubs    func(store *mockdb.MockStore)
+		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FirstRequest",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, db.ErrRecordNotFound)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CreateTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateTransferChallengeTxParams) (db.CreateTransferChallengeTxResult, error) {
+						require.NotNil(t, arg.IdempotencyKey)
+						require.Equal(t, user1.Username, arg.IdempotencyKey.Username)
+						require.Equal(t, key, arg.IdempotencyKey.Key)
+						require.Equal(t, record.RequestHash, arg.IdempotencyKey.RequestHash)
+						return db.CreateTransferChallengeTxResult{TransferChallenge: challenge}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireChallenge(t, recorder)
+				require.Empty(t, recorder.Header().Get(idempotentReplayedHeader))
+			},
+		},
+		{
+			name: "Retry",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(record, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().CreateTransferChallengeTx(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireChallenge(t, recorder)
+				require.Equal(t, "true", recorder.Header().Get(idempotentReplayedHeader))
+			},
+		},
+		{
+			name: "ConcurrentRetry",
+			buildStubs: func(store *mockdb.MockStore) {
+				gomock.InOrder(
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(db.IdempotencyKey{}, db.ErrRecordNotFound),
+					store.EXPECT().GetIdempotencyKey(gomock.Any(), gomock.Eq(getKeyArg)).Times(1).Return(record, nil),
+				)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+				store.EXPECT().
+					CreateTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateTransferChallengeTxResult{}, db.ErrIdempotencyKeyInUse)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				requireChallenge(t, recorder)
+				require.Equal(t, "true", recorder.Header().Get(idempotentReplayedHeader))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.stepUpThresholds, _ = util.ParseCurrencyAmounts(fmt.Sprintf("USD:%d", testStepUpThreshold))
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(req)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.Header.Set(idempotencyKeyHeader, key)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user1.Username, user1.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, recorder)
+		})
+	}
+}
+
+func TestConfirmTransferChallengeAPI(t *testing.T) {
+	user, password := randomUser(t)
+	otherUser, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(t *testing.T, secret string) gin.H
+		buildStubs    func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OKWithPassword",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				expectTransferChallenge(store, challenge)
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+
+				arg := db.ConfirmTransferChallengeTxParams{
+					ID:       challenge.ID,
+					Username: user.Username,
+				}
+				store.EXPECT().
+					ConfirmTransferChallengeTx(gomock.Any(), gomock.Eq(arg)).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OKWithTOTPCode",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				expectTransferChallenge(store, challenge)
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(totpSecret, nil)
+				store.EXPECT().
+					UpdateTotpLastUsedStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					ConfirmTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPassword",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": "wrong" + password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				expectTransferChallenge(store, challenge)
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					ConfirmTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name: "TOTPNotEnabled",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"code": currentTOTPCode(t, secret)}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				expectTransferChallenge(store, challenge)
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetTotpSecret(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.TotpSecret{}, sql.ErrNoRows)
+				expectLoginFailure(store)
+				store.EXPECT().
+					ConfirmTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				requireInvalidCredentials(t, recorder)
+			},
+		},
+		{
+			name: "LockedOut",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				expectTransferChallenge(store, challenge)
+				expectLoginThrottles(store, []db.LoginThrottle{
+					{
+						Scope:       loginScopeUsername,
+						Subject:     user.Username,
+						LockedUntil: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+					},
+				})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "OtherUserChallenge",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				challenge.Username = otherUser.Username
+				expectTransferChallenge(store, challenge)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredChallenge",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				challenge.ExpiresAt = time.Now().Add(-time.Second)
+				expectTransferChallenge(store, challenge)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyConfirmed",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				challenge.Status = "confirmed"
+				expectTransferChallenge(store, challenge)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "ConfirmedConcurrently",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				expectTransferChallenge(store, challenge)
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ConfirmTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ConfirmTransferChallengeTxResult{}, sql.ErrNoRows)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{"password": password}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				expectTransferChallenge(store, challenge)
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					ConfirmTransferChallengeTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ConfirmTransferChallengeTxResult{}, &db.InsufficientFundsError{AccountID: challenge.FromAccountID})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCredentials",
+			buildBody: func(t *testing.T, secret string) gin.H {
+				return gin.H{}
+			},
+			buildStubs: func(store *mockdb.MockStore, challenge db.TransferChallenge, totpSecret db.TotpSecret) {
+				store.EXPECT().
+					GetTransferChallenge(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			challenge := randomTransferChallenge(user.Username)
+			secret, totpSecret := randomTotpSecret(t, server.config.TOTPEncryptionKey, user.Username)
+			totpSecret.IsEnabled = true
+			tc.buildStubs(store, challenge, totpSecret)
+
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.buildBody(t, secret))
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/transfers/challenges/%s/confirm", challenge.ID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestConfirmTransferChallengeWithAPIKey(t *testing.T) {
+	user, password := randomUser(t)
+	key, _ := randomAPIKey(user.Username, util.TransfersWriteScope)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		GetTransferChallenge(gomock.Any(), gomock.Any()).
+		Times(0)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	data, err := json.Marshal(gin.H{"password": password})
+	require.NoError(t, err)
+
+	url := fmt.Sprintf("/transfers/challenges/%s/confirm", uuid.New())
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	require.NoError(t, err)
+
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("%s %s", authorizationTypeBearer, key))
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func randomTransferChallenge(username string) db.TransferChallenge {
+	return db.TransferChallenge{
+		ID:            uuid.New(),
+		Username:      username,
+		FromAccountID: util.RandomInt(1, 1000),
+		ToAccountID:   util.RandomInt(1, 1000),
+		Amount:        testStepUpThreshold + util.RandomInt(1, 1000),
+		Currency:      util.USD,
+		Status:        transferStatusAwaitingConfirmation,
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+}
+
+func expectTransferChallenge(store *mockdb.MockStore, challenge db.TransferChallenge) {
+	store.EXPECT().
+		GetTransferChallenge(gomock.Any(), gomock.Eq(challenge.ID)).
+		Times(1).
+		Return(challenge, nil)
+}
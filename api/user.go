@@ -2,12 +2,16 @@ package api
 
 import (
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
 	"github.com/JMustang/OldBank/util"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
 
@@ -22,6 +26,8 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -31,11 +37,15 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		Role:              user.Role,
+		IsEmailVerified:   user.IsEmailVerified,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
 }
 
+var errReservedUsername = errors.New("username is reserved")
+
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -43,20 +53,41 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := util.HashPassword(req.Password)
+	if server.clientCerts != nil && server.clientCerts.reservesUsername(req.Username) {
+		ctx.JSON(http.StatusForbidden, errorResponse(errReservedUsername))
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.Password)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	secretCode, err := util.RandomSecret(verifyEmailSecretLength)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	duration := server.config.VerifyEmailDuration
+	if duration <= 0 {
+		duration = defaultVerifyEmailDuration
+	}
+
+	arg := db.CreateUserTxParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		HashedSecretCode: util.HashSecret(secretCode),
+		ExpiredAt:        time.Now().Add(duration),
 	}
+	arg.VerificationEmail = server.verificationEmail(arg.CreateUserParams, secretCode)
 
-	user, err := server.store.CreateUser(ctx, arg)
+	result, err := server.store.CreateUserTx(ctx, arg)
 	if err != nil {
 		if pqErr, ok := err.(*pq.Error); ok {
 			switch pqErr.Code.Name() {
@@ -69,7 +100,7 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	rsp := newUserResponse(user)
+	rsp := newUserResponse(result.User)
 	ctx.JSON(http.StatusOK, rsp)
 }
 
@@ -79,8 +110,13 @@ type loginUserRequest struct {
 }
 
 type loginUserResponse struct {
-	AccessToken string       `json:"access_token"`
-	User        userResponse `json:"user"`
+	SessionID             uuid.UUID    `json:"session_id"`
+	TokenType             string       `json:"token_type"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
 }
 
 func (server *Server) loginUser(ctx *gin.Context) {
@@ -90,7 +126,161 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	if !server.checkLoginThrottle(ctx, req.Username) {
+		return
+	}
+
 	user, err := server.store.GetUser(ctx, req.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// Unknown users and wrong passwords get the same response, in about the same time
+	hashedPassword := user.HashedPassword
+	if err == sql.ErrNoRows {
+		hashedPassword = server.dummyHashedPassword
+	}
+
+	err = util.CheckPassword(req.Password, hashedPassword)
+	if err != nil || user.Username == "" {
+		err = server.recordLoginFailure(ctx, req.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	err = server.store.ResetLoginThrottle(ctx, db.ResetLoginThrottleParams{
+		Scope:   loginScopeUsername,
+		Subject: user.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	server.rehashPassword(ctx, user, req.Password)
+
+	totpSecret, err := server.store.GetTotpSecret(ctx, user.Username)
+	if err != nil && err != sql.ErrNoRows {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if err == nil && totpSecret.IsEnabled {
+		server.requireSecondFactor(ctx, user)
+		return
+	}
+
+	rsp, err := server.newLoginSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// rehashPassword upgrades the hash of the password to the current algorithm and parameters.
+// It is called after a successful login, the only time the plain password is known.
+// A failure is only logged, since the old hash still works.
+func (server *Server) rehashPassword(ctx *gin.Context, user db.User, password string) {
+	if !server.passwordHasher.NeedsRehash(user.HashedPassword) {
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(password)
+	if err == nil {
+		// The old hash is matched so that a concurrent password change is not overwritten
+		_, err = server.store.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+			NewHashedPassword: hashedPassword,
+			Username:          user.Username,
+			OldHashedPassword: user.HashedPassword,
+		})
+	}
+	if err != nil {
+		log.Printf("cannot rehash password of user %s: %v", user.Username, err)
+	}
+}
+
+// newLoginSession creates the access token, refresh token and session of a user who just authenticated.
+// Both tokens are bound to the DPoP key of the client if it sent a proof.
+func (server *Server) newLoginSession(ctx *gin.Context, user db.User) (loginUserResponse, error) {
+	refreshPayload, err := token.NewPayload(user.Username, user.Role, server.config.RefreshTokenDuration)
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+	refreshPayload.Purpose = token.PurposeRefresh
+	refreshToken, err := server.createBoundToken(ctx, refreshPayload)
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+
+	// The session has the ID of its refresh token, revoking it revokes the access token as well
+	accessPayload, err := token.NewPayload(user.Username, user.Role, server.config.AccessTokenDuration)
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+	accessPayload.SessionID = refreshPayload.ID
+	accessToken, err := server.createBoundToken(ctx, accessPayload)
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+
+	result, err := server.store.CreateSessionTx(ctx, db.CreateSessionTxParams{
+		CreateSessionParams: db.CreateSessionParams{
+			ID:           refreshPayload.ID,
+			Username:     user.Username,
+			RefreshToken: refreshToken,
+			UserAgent:    ctx.Request.UserAgent(),
+			ClientIp:     ctx.ClientIP(),
+			IsBlocked:    false,
+			ExpiresAt:    refreshPayload.ExpiredAt,
+		},
+		NewDeviceEmail: newDeviceEmail(user),
+	})
+	if err != nil {
+		return loginUserResponse{}, err
+	}
+
+	rsp := loginUserResponse{
+		SessionID:             result.Session.ID,
+		TokenType:             tokenType(accessPayload),
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
+	}
+	return rsp, nil
+}
+
+type updateUserRoleURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+func (server *Server) updateUserRole(ctx *gin.Context) {
+	var uri updateUserRoleURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.UpdateUserRole(ctx, db.UpdateUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
@@ -100,24 +290,172 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
-	err = util.CheckPassword(req.Password, user.HashedPassword)
+	// Tokens carry the role they were issued with, so the user has to log in again.
+	_, err = server.store.BlockUserSessions(ctx, user.Username)
 	if err != nil {
-		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(
-		user.Username,
-		server.config.AccessTokenDuration,
-	)
+	// API keys outlive any revocation, so they are deleted
+	_, err = server.store.DeleteUserApiKeys(ctx, user.Username)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	rsp := loginUserResponse{
-		AccessToken: accessToken,
-		User:        newUserResponse(user),
+	now := time.Now()
+	err = server.revocationStore.RevokeUserTokens(ctx, user.Username, now, now.Add(server.longestTokenLifetime()))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+var errNothingToUpdate = errors.New("at least one of full_name and email must be provided")
+
+type updateUserRequest struct {
+	FullName    string `json:"full_name" binding:"omitempty,min=1"`
+	Email       string `json:"email" binding:"omitempty,email"`
+	OldPassword string `json:"old_password" binding:"required_with=Email"`
+}
+
+// updateUser changes the full name and email address of the authenticated user.
+// Changing the email address requires the current password, and the previous address is told about it.
+// A new email address is unverified until the user opens the link sent to it.
+func (server *Server) updateUser(ctx *gin.Context) {
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if req.FullName == "" && req.Email == "" {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errNothingToUpdate))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	arg := db.UpdateUserTxParams{
+		UpdateUserParams: db.UpdateUserParams{
+			Username: authPayload.Username,
+			FullName: sql.NullString{String: req.FullName, Valid: req.FullName != ""},
+			Email:    sql.NullString{String: req.Email, Valid: req.Email != ""},
+		},
+	}
+
+	if req.Email != "" {
+		// The email address receives the password reset links, so it is as sensitive as the password
+		if !server.reauthenticate(ctx, authPayload.Username, stepUpRequest{Password: req.OldPassword}) {
+			return
+		}
+
+		secretCode, err := util.RandomSecret(verifyEmailSecretLength)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		duration := server.config.VerifyEmailDuration
+		if duration <= 0 {
+			duration = defaultVerifyEmailDuration
+		}
+
+		arg.HashedSecretCode = util.HashSecret(secretCode)
+		arg.ExpiredAt = time.Now().Add(duration)
+		arg.VerificationEmail = server.emailChangeVerificationEmail(secretCode)
+		arg.EmailChangedEmail = emailChangedEmail
+	}
+
+	result, err := server.store.UpdateUserTx(ctx, arg)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(err))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := newUserResponse(result.User)
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// changePassword sets a new password for the authenticated user, who must know the old one.
+// Every token and session issued before is rejected, and a new session is returned.
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	if !server.checkLoginThrottle(ctx, authPayload.Username) {
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, authPayload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = util.CheckPassword(req.OldPassword, user.HashedPassword)
+	if err != nil {
+		// Guessing the password of a stolen session is throttled like guessing it at login
+		err = server.recordLoginFailure(ctx, user.Username)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	hashedPassword, err := server.passwordHasher.HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.ChangePasswordTx(ctx, db.ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		RevokeTokens:   server.revokeTokensBeforePasswordChange,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The new tokens stay bound to the key of the client, if the old ones were
+	if authPayload.IsDPoPBound() {
+		ctx.Set(dpopThumbprintKey, authPayload.Confirmation.JWKThumbprint)
+	}
+
+	rsp, err := server.newLoginSession(ctx, result.User)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
 	}
 	ctx.JSON(http.StatusOK, rsp)
 }
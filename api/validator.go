@@ -11,3 +11,24 @@ var validCurrency validator.Func = func(fieldLevel validator.FieldLevel) bool {
 	}
 	return false
 }
+
+var validRole validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if role, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedRole(role)
+	}
+	return false
+}
+
+var validScope validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if scope, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedScope(scope)
+	}
+	return false
+}
+
+var validFrequency validator.Func = func(fieldLevel validator.FieldLevel) bool {
+	if frequency, ok := fieldLevel.Field().Interface().(string); ok {
+		return util.IsSupportedFrequency(frequency)
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultVerifyEmailDuration = 15 * time.Minute
+	verifyEmailSecretLength    = 32
+)
+
+var (
+	errInvalidVerifyEmail = errors.New("email verification code is invalid or has expired")
+	errEmailNotVerified   = errors.New("email address must be verified to access this resource")
+)
+
+// verificationEmail builds the email delivering the secret code of a verify email
+func (server *Server) verificationEmail(user db.CreateUserParams, secretCode string) func(verifyEmail db.VerifyEmail) db.CreateOutboxEmailParams {
+	return func(verifyEmail db.VerifyEmail) db.CreateOutboxEmailParams {
+		return db.CreateOutboxEmailParams{
+			ToAddress: verifyEmail.Email,
+			Subject:   "Welcome to OldBank",
+			Body: fmt.Sprintf(
+				"Hello %s,\n\nThank you for registering with us! Please verify your email address by opening the following link:\n\n%s\n\nIt expires at %s.\n",
+				user.FullName,
+				server.verifyEmailLink(verifyEmail, secretCode),
+				verifyEmail.ExpiredAt.Format(time.RFC1123),
+			),
+		}
+	}
+}
+
+// emailChangeVerificationEmail builds the email delivering the secret code of a verify email
+// when a user changes its email address
+func (server *Server) emailChangeVerificationEmail(secretCode string) func(user db.User, verifyEmail db.VerifyEmail) db.CreateOutboxEmailParams {
+	return func(user db.User, verifyEmail db.VerifyEmail) db.CreateOutboxEmailParams {
+		return db.CreateOutboxEmailParams{
+			ToAddress: verifyEmail.Email,
+			Subject:   "Verify your new OldBank email address",
+			Body: fmt.Sprintf(
+				"Hello %s,\n\nThe email address of your account was changed to this one. Please verify it by opening the following link:\n\n%s\n\nIt expires at %s.\n",
+				user.FullName,
+				server.verifyEmailLink(verifyEmail, secretCode),
+				verifyEmail.ExpiredAt.Format(time.RFC1123),
+			),
+		}
+	}
+}
+
+// emailChangedEmail builds the email telling the previous address of a user that it was replaced
+func emailChangedEmail(oldUser db.User, user db.User) db.CreateOutboxEmailParams {
+	return db.CreateOutboxEmailParams{
+		ToAddress: oldUser.Email,
+		Subject:   "The email address of your OldBank account was changed",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nThe email address of your account was just changed to %s, and this address will no longer receive our emails.\n\nIf this was not you, change your password and contact us right away.\n",
+			oldUser.FullName,
+			user.Email,
+		),
+	}
+}
+
+func (server *Server) verifyEmailLink(verifyEmail db.VerifyEmail, secretCode string) string {
+	query := url.Values{}
+	query.Set("email_id", fmt.Sprint(verifyEmail.ID))
+	query.Set("secret_code", secretCode)
+
+	link := query.Encode()
+	if server.config.VerifyEmailURL != "" {
+		link = fmt.Sprintf("%s?%s", server.config.VerifyEmailURL, link)
+	}
+	return link
+}
+
+type verifyEmailRequest struct {
+	EmailID    int64  `form:"email_id" binding:"required,min=1"`
+	SecretCode string `form:"secret_code" binding:"required"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+// verifyEmail marks the email address of a user as verified with the secret code sent to it
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.store.VerifyEmailTx(ctx, db.VerifyEmailTxParams{
+		EmailID:          req.EmailID,
+		HashedSecretCode: util.HashSecret(req.SecretCode),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidVerifyEmail))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := verifyEmailResponse{
+		IsVerified: result.User.IsEmailVerified,
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// requireVerifiedEmail only lets through users whose email address is verified, when REQUIRE_VERIFIED_EMAIL is set.
+// It must run after authMiddleware.
+func (server *Server) requireVerifiedEmail() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !server.config.RequireVerifiedEmail {
+			ctx.Next()
+			return
+		}
+
+		authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+		user, err := server.store.GetUser(ctx, authPayload.Username)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				ctx.AbortWithStatusJSON(http.StatusNotFound, errorResponse(err))
+				return
+			}
+			ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		if !user.IsEmailVerified {
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(errEmailNotVerified))
+			return
+		}
+
+		ctx.Next()
+	}
+}
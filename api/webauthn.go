@@ -0,0 +1,441 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/JMustang/OldBank/webauthn"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultWebAuthnRPName  = "OldBank"
+	defaultWebAuthnTimeout = 5 * time.Minute
+)
+
+var (
+	errInvalidCeremonyToken      = errors.New("invalid WebAuthn session token")
+	errInvalidWebAuthnCredential = errors.New("unknown WebAuthn credential")
+	errWebAuthnCredentialExists  = errors.New("WebAuthn credential is already registered")
+)
+
+// newRelyingParty reads the WebAuthn relying party from the config
+func newRelyingParty(config util.Config) webauthn.RelyingParty {
+	rp := webauthn.RelyingParty{
+		ID:      config.WebAuthnRPID,
+		Name:    config.WebAuthnRPName,
+		Origins: strings.Fields(config.WebAuthnOrigins),
+		Timeout: config.WebAuthnTimeout,
+	}
+	if rp.Name == "" {
+		rp.Name = defaultWebAuthnRPName
+	}
+	if rp.Timeout <= 0 {
+		rp.Timeout = defaultWebAuthnTimeout
+	}
+	return rp
+}
+
+// webAuthnUserHandle is the WebAuthn user ID of a user, authenticators store it but it must not contain the username
+func webAuthnUserHandle(username string) []byte {
+	sum := sha256.Sum256([]byte(username))
+	return sum[:]
+}
+
+// createCeremonyToken creates the token carrying the challenge of a WebAuthn ceremony.
+// The random ID of the token is the challenge, so that the ceremony needs no server side state.
+func (server *Server) createCeremonyToken(username string, role string, purpose string) (string, *token.Payload, error) {
+	payload, err := token.NewPayload(username, role, server.relyingParty.Timeout)
+	if err != nil {
+		return "", nil, err
+	}
+	payload.Purpose = purpose
+
+	ceremonyToken, err := server.tokenMaker.CreateTokenFromPayload(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	return ceremonyToken, payload, nil
+}
+
+// verifyCeremonyToken checks the token of a WebAuthn ceremony and returns its payload.
+// It responds with an error and returns false if the token is invalid.
+func (server *Server) verifyCeremonyToken(ctx *gin.Context, ceremonyToken string, purpose string) (*token.Payload, bool) {
+	payload, err := server.tokenMaker.VerifyToken(ceremonyToken)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return nil, false
+	}
+	if payload.Purpose != purpose {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCeremonyToken))
+		return nil, false
+	}
+
+	revoked, err := server.revocationStore.IsRevoked(ctx, payload)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return nil, false
+	}
+	if revoked {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return nil, false
+	}
+	return payload, true
+}
+
+// consumeCeremonyToken revokes the token of a WebAuthn ceremony once its challenge was answered.
+// It responds with an error and returns false if the token was already used, by a concurrent request
+// that passed verifyCeremonyToken at the same time.
+func (server *Server) consumeCeremonyToken(ctx *gin.Context, payload *token.Payload, username string) bool {
+	consumed, err := server.revocationStore.ConsumeToken(ctx, payload.ID, username, server.revocationExpiry(payload.ExpiredAt))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+	if !consumed {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(token.ErrRevokedToken))
+		return false
+	}
+	return true
+}
+
+// ceremonyChallenge returns the challenge of a WebAuthn ceremony token
+func ceremonyChallenge(payload *token.Payload) []byte {
+	return payload.ID[:]
+}
+
+type webAuthnCredentialResponse struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newWebAuthnCredentialResponse(credential db.WebauthnCredential) webAuthnCredentialResponse {
+	rsp := webAuthnCredentialResponse{
+		ID:        credential.ID,
+		Name:      credential.Name,
+		CreatedAt: credential.CreatedAt,
+	}
+	if credential.LastUsedAt.Valid {
+		rsp.LastUsedAt = &credential.LastUsedAt.Time
+	}
+	return rsp
+}
+
+type beginWebAuthnRegistrationResponse struct {
+	SessionToken string                   `json:"session_token"`
+	Options      webauthn.CreationOptions `json:"options"`
+}
+
+// beginWebAuthnRegistration starts the registration of a passkey for the user,
+// returning the options of navigator.credentials.create().
+func (server *Server) beginWebAuthnRegistration(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	credentials, err := server.store.ListWebauthnCredentials(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	// The authenticators of the user do not register a second passkey for the same account
+	excludeCredentials := make([][]byte, len(credentials))
+	for i, credential := range credentials {
+		excludeCredentials[i] = credential.CredentialID
+	}
+
+	sessionToken, payload, err := server.createCeremonyToken(authPayload.Username, authPayload.Role, token.PurposeWebAuthnRegistration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user := webauthn.User{
+		ID:          webAuthnUserHandle(authPayload.Username),
+		Name:        authPayload.Username,
+		DisplayName: authPayload.Username,
+	}
+	rsp := beginWebAuthnRegistrationResponse{
+		SessionToken: sessionToken,
+		Options:      server.relyingParty.CreationOptions(ceremonyChallenge(payload), user, excludeCredentials),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type finishWebAuthnRegistrationRequest struct {
+	SessionToken string                          `json:"session_token" binding:"required"`
+	Name         string                          `json:"name" binding:"required,max=64"`
+	Credential   webauthn.RegistrationCredential `json:"credential"`
+	stepUpRequest
+}
+
+// finishWebAuthnRegistration verifies the new passkey created by the authenticator and stores it.
+// A passkey logs in without password nor second factor, so the user must re-authenticate to add one,
+// and is told by email that it was added.
+func (server *Server) finishWebAuthnRegistration(ctx *gin.Context) {
+	var req finishWebAuthnRegistrationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	payload, ok := server.verifyCeremonyToken(ctx, req.SessionToken, token.PurposeWebAuthnRegistration)
+	if !ok {
+		return
+	}
+	if payload.Username != authPayload.Username {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCeremonyToken))
+		return
+	}
+
+	verified, err := server.relyingParty.VerifyRegistration(ceremonyChallenge(payload), req.Credential.Response)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.reauthenticate(ctx, authPayload.Username, req.stepUpRequest) {
+		return
+	}
+
+	// The challenge can only be answered once
+	if !server.consumeCeremonyToken(ctx, payload, payload.Username) {
+		return
+	}
+
+	result, err := server.store.CreateWebauthnCredentialTx(ctx, db.CreateWebauthnCredentialTxParams{
+		CreateWebauthnCredentialParams: db.CreateWebauthnCredentialParams{
+			Username:     authPayload.Username,
+			Name:         req.Name,
+			CredentialID: verified.ID,
+			PublicKey:    verified.PublicKey,
+			SignCount:    int64(verified.SignCount),
+		},
+		NewPasskeyEmail: newPasskeyEmail,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code.Name() {
+			case "unique_violation":
+				ctx.JSON(http.StatusForbidden, errorResponse(errWebAuthnCredentialExists))
+				return
+			}
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newWebAuthnCredentialResponse(result.Credential))
+}
+
+// newPasskeyEmail builds the email telling a user that a passkey was added to their account
+func newPasskeyEmail(user db.User, credential db.WebauthnCredential) db.CreateOutboxEmailParams {
+	return db.CreateOutboxEmailParams{
+		ToAddress: user.Email,
+		Subject:   "A passkey was added to your OldBank account",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nThe passkey \"%s\" was just added to your account, it can now log in without your password.\n\nIf this was not you, remove it from your account settings and change your password.\n",
+			user.FullName,
+			credential.Name,
+		),
+	}
+}
+
+func (server *Server) listWebAuthnCredentials(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	credentials, err := server.store.ListWebauthnCredentials(ctx, authPayload.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webAuthnCredentialResponse, len(credentials))
+	for i, credential := range credentials {
+		rsp[i] = newWebAuthnCredentialResponse(credential)
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type deleteWebAuthnCredentialRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteWebAuthnCredential(ctx *gin.Context) {
+	var req deleteWebAuthnCredentialRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	rows, err := server.store.DeleteWebauthnCredential(ctx, db.DeleteWebauthnCredentialParams{
+		ID:       req.ID,
+		Username: authPayload.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusNotFound, errorResponse(sql.ErrNoRows))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type beginWebAuthnLoginRequest struct {
+	Username string `json:"username" binding:"omitempty,alphanum"`
+}
+
+type beginWebAuthnLoginResponse struct {
+	SessionToken string                  `json:"session_token"`
+	Options      webauthn.RequestOptions `json:"options"`
+}
+
+// beginWebAuthnLogin starts a passkey login, returning the options of navigator.credentials.get().
+// The user always picks one of the passkeys stored by the authenticator: the credentials of the user
+// are never listed, so that the response does not tell whether the username exists or has passkeys.
+// With a username, the login is only accepted with a passkey of that user.
+func (server *Server) beginWebAuthnLogin(ctx *gin.Context) {
+	var req beginWebAuthnLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	sessionToken, payload, err := server.createCeremonyToken(req.Username, "", token.PurposeWebAuthnLogin)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := beginWebAuthnLoginResponse{
+		SessionToken: sessionToken,
+		Options:      server.relyingParty.RequestOptions(ceremonyChallenge(payload), nil),
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type finishWebAuthnLoginRequest struct {
+	SessionToken string                       `json:"session_token" binding:"required"`
+	Credential   webauthn.AssertionCredential `json:"credential"`
+}
+
+// finishWebAuthnLogin verifies the assertion of the passkey and logs the user in.
+// Passkeys verify the user with a PIN or biometrics, so no second factor is asked for.
+func (server *Server) finishWebAuthnLogin(ctx *gin.Context) {
+	var req finishWebAuthnLoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, ok := server.verifyCeremonyToken(ctx, req.SessionToken, token.PurposeWebAuthnLogin)
+	if !ok {
+		return
+	}
+
+	// Passkey logins are throttled like password logins
+	if !server.checkLoginThrottle(ctx, payload.Username) {
+		return
+	}
+
+	credential, err := server.store.GetWebauthnCredential(ctx, req.Credential.RawID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			server.rejectWebAuthnLogin(ctx, payload.Username, errInvalidWebAuthnCredential)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// The passkey must belong to the user the login was started for, if any
+	if payload.Username != "" && payload.Username != credential.Username {
+		server.rejectWebAuthnLogin(ctx, payload.Username, errInvalidWebAuthnCredential)
+		return
+	}
+	userHandle := req.Credential.Response.UserHandle
+	if len(userHandle) > 0 && !bytes.Equal(userHandle, webAuthnUserHandle(credential.Username)) {
+		server.rejectWebAuthnLogin(ctx, credential.Username, errInvalidWebAuthnCredential)
+		return
+	}
+
+	signCount, err := server.relyingParty.VerifyAssertion(
+		ceremonyChallenge(payload),
+		credential.PublicKey,
+		uint32(credential.SignCount),
+		req.Credential.Response,
+	)
+	if err != nil {
+		server.rejectWebAuthnLogin(ctx, credential.Username, err)
+		return
+	}
+
+	// The challenge can only be answered once. The login may have been started without
+	// a username, so the token is revoked for the owner of the passkey.
+	if !server.consumeCeremonyToken(ctx, payload, credential.Username) {
+		return
+	}
+
+	// The counter is only updated if it did not change since it was read, so that
+	// two concurrent logins with a cloned authenticator cannot both succeed
+	rows, err := server.store.UpdateWebauthnCredentialSignCount(ctx, db.UpdateWebauthnCredentialSignCountParams{
+		NewSignCount: int64(signCount),
+		ID:           credential.ID,
+		OldSignCount: credential.SignCount,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if rows == 0 {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(webauthn.ErrSignCountRegressed))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, credential.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.ResetLoginThrottle(ctx, db.ResetLoginThrottleParams{
+		Scope:   loginScopeUsername,
+		Subject: user.Username,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp, err := server.newLoginSession(ctx, user)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// rejectWebAuthnLogin counts a failed passkey login against the username and the client IP, then rejects it
+func (server *Server) rejectWebAuthnLogin(ctx *gin.Context, username string, err error) {
+	if recordErr := server.recordLoginFailure(ctx, username); recordErr != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(recordErr))
+		return
+	}
+	ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+}
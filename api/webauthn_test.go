@@ -0,0 +1,752 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/JMustang/OldBank/webauthn"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebAuthnOrigin = "http://localhost:8080"
+
+func TestBeginWebAuthnRegistrationAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	_, credential := randomWebAuthnCredential(t, user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	store.EXPECT().
+		ListWebauthnCredentials(gomock.Any(), gomock.Eq(user.Username)).
+		Times(1).
+		Return([]db.WebauthnCredential{credential}, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	url := "/users/me/webauthn/register/begin"
+	request, err := http.NewRequest(http.MethodPost, url, nil)
+	require.NoError(t, err)
+
+	addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var rsp beginWebAuthnRegistrationResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+	require.NoError(t, err)
+	require.Equal(t, "localhost", rsp.Options.RP.ID)
+	require.Equal(t, user.Username, rsp.Options.User.Name)
+	require.NotContains(t, string(rsp.Options.User.ID), user.Username)
+	require.Len(t, rsp.Options.ExcludeCredentials, 1)
+	require.Equal(t, credential.CredentialID, []byte(rsp.Options.ExcludeCredentials[0].ID))
+
+	payload, err := server.tokenMaker.VerifyToken(rsp.SessionToken)
+	require.NoError(t, err)
+	require.Equal(t, token.PurposeWebAuthnRegistration, payload.Purpose)
+	require.Equal(t, ceremonyChallenge(payload), []byte(rsp.Options.Challenge))
+}
+
+func TestFinishWebAuthnRegistrationAPI(t *testing.T) {
+	user, password := randomUser(t)
+	otherUser, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H
+		buildStubs    func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return registrationBody(t, server, authenticator, user, password, testWebAuthnOrigin)
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				credentialArg := db.CreateWebauthnCredentialParams{
+					Username:     user.Username,
+					Name:         "laptop",
+					CredentialID: authenticator.CredentialID,
+					PublicKey:    authenticator.PublicKey(),
+					SignCount:    0,
+				}
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ interface{}, arg db.CreateWebauthnCredentialTxParams) (db.CreateWebauthnCredentialTxResult, error) {
+						require.Equal(t, credentialArg, arg.CreateWebauthnCredentialParams)
+
+						credential := db.WebauthnCredential{ID: 1, Username: user.Username, Name: "laptop"}
+						email := arg.NewPasskeyEmail(user, credential)
+						require.Equal(t, user.Email, email.ToAddress)
+						require.Contains(t, email.Body, "laptop")
+						return db.CreateWebauthnCredentialTxResult{Credential: credential}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp webAuthnCredentialResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.Equal(t, int64(1), rsp.ID)
+				require.Equal(t, "laptop", rsp.Name)
+			},
+		},
+		{
+			name: "OtherUserSessionToken",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return registrationBody(t, server, authenticator, otherUser, password, testWebAuthnOrigin)
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "LoginSessionToken",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				body := registrationBody(t, server, authenticator, user, password, testWebAuthnOrigin)
+				sessionToken, _, err := server.createCeremonyToken(user.Username, user.Role, token.PurposeWebAuthnLogin)
+				require.NoError(t, err)
+				body["session_token"] = sessionToken
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "WrongOrigin",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return registrationBody(t, server, authenticator, user, password, "http://evil.localhost:8080")
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "UserNotVerified",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				authenticator.SkipUserVerification = true
+				return registrationBody(t, server, authenticator, user, password, testWebAuthnOrigin)
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "AlreadyRegistered",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return registrationBody(t, server, authenticator, user, password, testWebAuthnOrigin)
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.CreateWebauthnCredentialTxResult{}, &pq.Error{Code: "23505"})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "WrongPassword",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				body := registrationBody(t, server, authenticator, user, password, testWebAuthnOrigin)
+				body["password"] = util.RandomString(8)
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingPassword",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				body := registrationBody(t, server, authenticator, user, password, testWebAuthnOrigin)
+				delete(body, "password")
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name: "MissingName",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				body := registrationBody(t, server, authenticator, user, password, testWebAuthnOrigin)
+				delete(body, "name")
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore, authenticator *webauthn.SoftwareAuthenticator) {
+				store.EXPECT().
+					CreateWebauthnCredentialTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			authenticator, err := webauthn.NewSoftwareAuthenticator()
+			require.NoError(t, err)
+			tc.buildStubs(store, authenticator)
+
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.buildBody(t, server, authenticator))
+			require.NoError(t, err)
+
+			url := "/users/me/webauthn/register/finish"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, user.Role, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestBeginWebAuthnLoginAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name     string
+		username string
+	}{
+		{
+			name:     "KnownUser",
+			username: user.Username,
+		},
+		{
+			name:     "UnknownUser",
+			username: util.RandomOwner(),
+		},
+		{
+			name: "WithoutUsername",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// The credentials of the user are never looked up, whether it exists or not
+			store := mockdb.NewMockStore(ctrl)
+			store.EXPECT().
+				ListWebauthnCredentials(gomock.Any(), gomock.Any()).
+				Times(0)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(gin.H{"username": tc.username})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/webauthn/begin", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			var rsp beginWebAuthnLoginResponse
+			err = json.Unmarshal(recorder.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+			require.NotEmpty(t, rsp.SessionToken)
+			require.Empty(t, rsp.Options.AllowCredentials)
+		})
+	}
+}
+
+func TestFinishWebAuthnLoginAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	otherUser, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		buildBody     func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H
+		buildStubs    func(store *mockdb.MockStore, credential db.WebauthnCredential)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return loginBody(t, server, authenticator, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					ResetLoginThrottle(gomock.Any(), gomock.Eq(db.ResetLoginThrottleParams{
+						Scope:   loginScopeUsername,
+						Subject: user.Username,
+					})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Eq(credential.CredentialID)).
+					Times(1).
+					Return(credential, nil)
+				arg := db.UpdateWebauthnCredentialSignCountParams{
+					NewSignCount: credential.SignCount + 1,
+					ID:           credential.ID,
+					OldSignCount: credential.SignCount,
+				}
+				store.EXPECT().
+					UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp loginUserResponse
+				err := json.Unmarshal(recorder.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotEmpty(t, rsp.AccessToken)
+				require.NotEmpty(t, rsp.RefreshToken)
+				require.Equal(t, user.Username, rsp.User.Username)
+			},
+		},
+		{
+			name: "OKWithoutUsername",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return loginBody(t, server, authenticator, "")
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					ResetLoginThrottle(gomock.Any(), gomock.Eq(db.ResetLoginThrottleParams{
+						Scope:   loginScopeUsername,
+						Subject: user.Username,
+					})).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Eq(credential.CredentialID)).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(1)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "OtherUserCredential",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return loginBody(t, server, authenticator, otherUser.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				expectLoginThrottles(store, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UnknownCredential",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return loginBody(t, server, authenticator, "")
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				expectLoginThrottles(store, nil)
+				// the user is unknown, so only the client IP is counted
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.LoginThrottle{Scope: loginScopeClientIP, FailedAttempts: 1}, nil)
+				store.EXPECT().
+					LockLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.WebauthnCredential{}, sql.ErrNoRows)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "OtherAuthenticator",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				other, err := webauthn.NewSoftwareAuthenticator()
+				require.NoError(t, err)
+				other.CredentialID = authenticator.CredentialID
+				return loginBody(t, server, other, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				expectLoginThrottles(store, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "SignCountRegressed",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				authenticator.SignCount = 1
+				return loginBody(t, server, authenticator, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				expectLoginThrottles(store, nil)
+				expectLoginFailure(store)
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ConcurrentLogin",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				return loginBody(t, server, authenticator, user.Username)
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				expectLoginThrottles(store, nil)
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(credential, nil)
+				store.EXPECT().
+					UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					CreateSessionTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MFASessionToken",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				body := loginBody(t, server, authenticator, user.Username)
+				body["session_token"] = randomMFAToken(t, server, user)
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCredential",
+			buildBody: func(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator) gin.H {
+				body := loginBody(t, server, authenticator, user.Username)
+				delete(body, "credential")
+				return body
+			},
+			buildStubs: func(store *mockdb.MockStore, credential db.WebauthnCredential) {
+				store.EXPECT().
+					GetWebauthnCredential(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			server := newTestServer(t, store)
+
+			authenticator, credential := randomWebAuthnCredential(t, user.Username)
+			tc.buildStubs(store, credential)
+
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.buildBody(t, server, authenticator))
+			require.NoError(t, err)
+
+			url := "/users/login/webauthn/finish"
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestWebAuthnLoginSessionTokenReuse(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+
+	authenticator, credential := randomWebAuthnCredential(t, user.Username)
+	expectLoginThrottles(store, nil)
+	store.EXPECT().
+		GetWebauthnCredential(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(credential, nil)
+	store.EXPECT().
+		UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(1), nil)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		ResetLoginThrottle(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		CreateSessionTx(gomock.Any(), gomock.Any()).
+		Times(1)
+
+	data, err := json.Marshal(loginBody(t, server, authenticator, ""))
+	require.NoError(t, err)
+
+	for _, code := range []int{http.StatusOK, http.StatusUnauthorized} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login/webauthn/finish", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, code, recorder.Code)
+	}
+}
+
+// racingRevocationStore never reports a token as revoked, as when concurrent requests check a token before any uses it
+type racingRevocationStore struct {
+	token.RevocationStore
+}
+
+func (store racingRevocationStore) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	return false, nil
+}
+
+func TestWebAuthnLoginConcurrentFinish(t *testing.T) {
+	user, _ := randomUser(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockStore(ctrl)
+	server := newTestServer(t, store)
+	server.revocationStore = racingRevocationStore{server.revocationStore}
+
+	authenticator, credential := randomWebAuthnCredential(t, user.Username)
+	store.EXPECT().
+		ListLoginThrottles(gomock.Any(), gomock.Any()).
+		Times(2)
+	store.EXPECT().
+		GetWebauthnCredential(gomock.Any(), gomock.Any()).
+		Times(2).
+		Return(credential, nil)
+	// Only the first finish gets past the consumption of the session token
+	store.EXPECT().
+		UpdateWebauthnCredentialSignCount(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(int64(1), nil)
+	store.EXPECT().
+		GetUser(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(user, nil)
+	store.EXPECT().
+		ResetLoginThrottle(gomock.Any(), gomock.Any()).
+		Times(1).
+		Return(nil)
+	store.EXPECT().
+		CreateSessionTx(gomock.Any(), gomock.Any()).
+		Times(1)
+
+	data, err := json.Marshal(loginBody(t, server, authenticator, user.Username))
+	require.NoError(t, err)
+
+	for _, code := range []int{http.StatusOK, http.StatusUnauthorized} {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodPost, "/users/login/webauthn/finish", bytes.NewReader(data))
+		require.NoError(t, err)
+
+		server.router.ServeHTTP(recorder, request)
+		require.Equal(t, code, recorder.Code)
+	}
+}
+
+func randomWebAuthnCredential(t *testing.T, username string) (*webauthn.SoftwareAuthenticator, db.WebauthnCredential) {
+	authenticator, err := webauthn.NewSoftwareAuthenticator()
+	require.NoError(t, err)
+	authenticator.SignCount = uint32(util.RandomInt(1, 1000))
+
+	credential := db.WebauthnCredential{
+		ID:           util.RandomInt(1, 1000),
+		Username:     username,
+		Name:         util.RandomString(6),
+		CredentialID: authenticator.CredentialID,
+		PublicKey:    authenticator.PublicKey(),
+		SignCount:    int64(authenticator.SignCount),
+	}
+	return authenticator, credential
+}
+
+// registrationBody runs the registration ceremony of the authenticator on the origin
+func registrationBody(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator, user db.User, password string, origin string) gin.H {
+	sessionToken, payload, err := server.createCeremonyToken(user.Username, user.Role, token.PurposeWebAuthnRegistration)
+	require.NoError(t, err)
+
+	options := server.relyingParty.CreationOptions(ceremonyChallenge(payload), webauthn.User{ID: webAuthnUserHandle(user.Username)}, nil)
+	credential, err := authenticator.Register(origin, options)
+	require.NoError(t, err)
+
+	return gin.H{
+		"session_token": sessionToken,
+		"name":          "laptop",
+		"credential":    credential,
+		"password":      password,
+	}
+}
+
+// loginBody runs the authentication ceremony of the authenticator, for the given user or for any user
+func loginBody(t *testing.T, server *Server, authenticator *webauthn.SoftwareAuthenticator, username string) gin.H {
+	sessionToken, payload, err := server.createCeremonyToken(username, "", token.PurposeWebAuthnLogin)
+	require.NoError(t, err)
+
+	credential, err := authenticator.Login(testWebAuthnOrigin, server.relyingParty.RequestOptions(ceremonyChallenge(payload), nil))
+	require.NoError(t, err)
+
+	return gin.H{
+		"session_token": sessionToken,
+		"credential":    credential,
+	}
+}
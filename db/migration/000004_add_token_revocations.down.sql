@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "sessions_username_idx";
+
+DROP TABLE IF EXISTS "user_revocations";
+
+DROP TABLE IF EXISTS "revoked_tokens";
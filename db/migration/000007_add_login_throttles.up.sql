@@ -0,0 +1,12 @@
+CREATE TABLE "login_throttles" (
+  "scope" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "failed_attempts" integer NOT NULL DEFAULT 1,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+  "locked_until" timestamptz,
+  PRIMARY KEY ("scope", "subject")
+);
+
+COMMENT ON COLUMN "login_throttles"."scope" IS 'Either username or client_ip';
+
+COMMENT ON COLUMN "login_throttles"."locked_until" IS 'Login attempts are rejected until this time';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "verify_emails" CASCADE;
+
+ALTER TABLE "users" DROP COLUMN "is_email_verified";
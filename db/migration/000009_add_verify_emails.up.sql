@@ -0,0 +1,18 @@
+CREATE TABLE "verify_emails" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "hashed_secret_code" varchar NOT NULL,
+  "is_used" bool NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL DEFAULT (now() + interval '15 minutes')
+);
+
+CREATE INDEX ON "verify_emails" ("username");
+
+ALTER TABLE "verify_emails" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+-- Users who registered before emails were verified keep access to their accounts
+ALTER TABLE "users" ADD COLUMN "is_email_verified" bool NOT NULL DEFAULT true;
+
+ALTER TABLE "users" ALTER COLUMN "is_email_verified" SET DEFAULT false;
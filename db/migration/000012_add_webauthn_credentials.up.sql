@@ -0,0 +1,18 @@
+CREATE TABLE "webauthn_credentials" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "credential_id" bytea UNIQUE NOT NULL,
+  "public_key" bytea NOT NULL,
+  "sign_count" bigint NOT NULL DEFAULT 0,
+  "last_used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "webauthn_credentials" ("username");
+
+COMMENT ON COLUMN "webauthn_credentials"."public_key" IS 'COSE encoded public key';
+
+COMMENT ON COLUMN "webauthn_credentials"."sign_count" IS 'signature counter of the authenticator, 0 if it does not count';
+
+ALTER TABLE "webauthn_credentials" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
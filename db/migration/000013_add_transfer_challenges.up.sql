@@ -0,0 +1,27 @@
+CREATE TABLE "transfer_challenges" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL,
+  "currency" varchar NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'awaiting_confirmation',
+  "transfer_id" bigint,
+  "expires_at" timestamptz NOT NULL,
+  "confirmed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "transfer_challenges" ("username");
+
+COMMENT ON COLUMN "transfer_challenges"."status" IS 'awaiting_confirmation or confirmed';
+
+COMMENT ON COLUMN "transfer_challenges"."transfer_id" IS 'transfer executed once the challenge was confirmed';
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
@@ -0,0 +1,9 @@
+CREATE TABLE "dpop_proofs" (
+  "jti" varchar PRIMARY KEY,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "dpop_proofs" ("expires_at");
+
+COMMENT ON TABLE "dpop_proofs" IS 'DPoP proofs already used, kept until they are too old to be accepted';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "sessions_username_user_agent_idx";
+
+ALTER TABLE "sessions" DROP COLUMN "last_seen_at";
@@ -0,0 +1,5 @@
+ALTER TABLE "sessions" ADD COLUMN "last_seen_at" timestamptz NOT NULL DEFAULT (now());
+
+CREATE INDEX ON "sessions" ("username", "user_agent");
+
+COMMENT ON COLUMN "sessions"."last_seen_at" IS 'last time the refresh token of the session was used';
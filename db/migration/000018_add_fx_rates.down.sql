@@ -0,0 +1,13 @@
+ALTER TABLE "transfer_challenges" DROP COLUMN "fx_quote_id";
+
+ALTER TABLE "transfers" DROP COLUMN "fx_quote_id";
+
+ALTER TABLE "transfers" DROP COLUMN "fx_spread_bps";
+
+ALTER TABLE "transfers" DROP COLUMN "fx_rate";
+
+ALTER TABLE "transfers" DROP COLUMN "to_amount";
+
+DROP TABLE IF EXISTS "fx_quotes";
+
+DROP TABLE IF EXISTS "fx_rates";
@@ -0,0 +1,63 @@
+CREATE TABLE "fx_rates" (
+  "id" bigserial PRIMARY KEY,
+  "base_currency" varchar NOT NULL,
+  "quote_currency" varchar NOT NULL,
+  "rate" bigint NOT NULL CHECK ("rate" > 0),
+  "effective_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "fx_rates" ("base_currency", "quote_currency", "effective_at");
+
+COMMENT ON COLUMN "fx_rates"."rate" IS 'amount of quote currency for one unit of base currency, scaled by 10^8';
+
+COMMENT ON COLUMN "fx_rates"."effective_at" IS 'the rate applies from this time until the next rate of the currency pair';
+
+CREATE TABLE "fx_quotes" (
+  "id" uuid PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "fx_rate_id" bigint NOT NULL,
+  "from_currency" varchar NOT NULL,
+  "to_currency" varchar NOT NULL,
+  "rate" bigint NOT NULL,
+  "spread_bps" integer NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "fx_quotes"."rate" IS 'rate locked for the transfer, spread included, scaled by 10^8';
+
+COMMENT ON COLUMN "fx_quotes"."spread_bps" IS 'spread taken on the rate, in basis points';
+
+COMMENT ON COLUMN "fx_quotes"."used_at" IS 'a quote locks its rate for a single transfer';
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "fx_quotes" ADD FOREIGN KEY ("fx_rate_id") REFERENCES "fx_rates" ("id");
+
+ALTER TABLE "transfers" ADD COLUMN "to_amount" bigint;
+
+UPDATE "transfers" SET "to_amount" = "amount";
+
+ALTER TABLE "transfers" ALTER COLUMN "to_amount" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD COLUMN "fx_rate" bigint;
+
+ALTER TABLE "transfers" ADD COLUMN "fx_spread_bps" integer;
+
+ALTER TABLE "transfers" ADD COLUMN "fx_quote_id" uuid;
+
+COMMENT ON COLUMN "transfers"."amount" IS 'amount debited from the sender, in its currency';
+
+COMMENT ON COLUMN "transfers"."to_amount" IS 'amount credited to the recipient, in its currency';
+
+COMMENT ON COLUMN "transfers"."fx_rate" IS 'rate of the currency conversion, spread included, scaled by 10^8';
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("fx_quote_id") REFERENCES "fx_quotes" ("id");
+
+ALTER TABLE "transfer_challenges" ADD COLUMN "fx_quote_id" uuid;
+
+COMMENT ON COLUMN "transfer_challenges"."fx_quote_id" IS 'quote converting the amount of a cross-currency transfer';
+
+ALTER TABLE "transfer_challenges" ADD FOREIGN KEY ("fx_quote_id") REFERENCES "fx_quotes" ("id");
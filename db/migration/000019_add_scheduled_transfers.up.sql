@@ -0,0 +1,32 @@
+CREATE TABLE "scheduled_transfers" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "currency" varchar NOT NULL,
+  "execute_at" timestamptz NOT NULL,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "transfer_id" bigint,
+  "failure_reason" varchar,
+  "executed_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "scheduled_transfers" ("username");
+
+CREATE INDEX ON "scheduled_transfers" ("execute_at") WHERE "status" = 'pending';
+
+COMMENT ON COLUMN "scheduled_transfers"."status" IS 'pending, completed, failed or canceled';
+
+COMMENT ON COLUMN "scheduled_transfers"."transfer_id" IS 'transfer executed once the scheduled transfer completed';
+
+COMMENT ON COLUMN "scheduled_transfers"."failure_reason" IS 'why the transfer could not be executed, if it failed';
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
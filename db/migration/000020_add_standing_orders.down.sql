@@ -0,0 +1,3 @@
+ALTER TABLE "scheduled_transfers" DROP COLUMN "standing_order_id";
+
+DROP TABLE IF EXISTS "standing_orders";
@@ -0,0 +1,48 @@
+CREATE TABLE "standing_orders" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "from_account_id" bigint NOT NULL,
+  "to_account_id" bigint NOT NULL,
+  "amount" bigint NOT NULL CHECK ("amount" > 0),
+  "currency" varchar NOT NULL,
+  "frequency" varchar NOT NULL,
+  "repeat_every" integer NOT NULL DEFAULT 1 CHECK ("repeat_every" > 0),
+  "day_of_month" integer,
+  "start_at" timestamptz NOT NULL,
+  "end_at" timestamptz,
+  "max_occurrences" integer,
+  "occurrences" integer NOT NULL DEFAULT 0,
+  "next_execution_at" timestamptz,
+  "status" varchar NOT NULL DEFAULT 'active',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "standing_orders" ("username");
+
+CREATE INDEX ON "standing_orders" ("next_execution_at") WHERE "status" = 'active';
+
+COMMENT ON COLUMN "standing_orders"."frequency" IS 'weekly, monthly or last_business_day';
+
+COMMENT ON COLUMN "standing_orders"."repeat_every" IS 'number of weeks or months between occurrences';
+
+COMMENT ON COLUMN "standing_orders"."day_of_month" IS 'day of monthly standing orders';
+
+COMMENT ON COLUMN "standing_orders"."occurrences" IS 'number of runs generated so far';
+
+COMMENT ON COLUMN "standing_orders"."next_execution_at" IS 'time of the next run, null once the standing order has ended';
+
+COMMENT ON COLUMN "standing_orders"."status" IS 'active, paused, canceled or completed';
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("from_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "standing_orders" ADD FOREIGN KEY ("to_account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "scheduled_transfers" ADD COLUMN "standing_order_id" bigint;
+
+COMMENT ON COLUMN "scheduled_transfers"."standing_order_id" IS 'standing order the transfer is a run of';
+
+CREATE INDEX ON "scheduled_transfers" ("standing_order_id");
+
+ALTER TABLE "scheduled_transfers" ADD FOREIGN KEY ("standing_order_id") REFERENCES "standing_orders" ("id");
@@ -0,0 +1 @@
+ALTER TABLE "oauth_clients" DROP COLUMN "is_resource_server";
@@ -0,0 +1,3 @@
+ALTER TABLE "oauth_clients" ADD COLUMN "is_resource_server" bool NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN "oauth_clients"."is_resource_server" IS 'set by an admin to let the client introspect tokens';
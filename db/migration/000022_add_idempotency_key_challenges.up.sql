@@ -0,0 +1,5 @@
+ALTER TABLE "idempotency_keys" ADD COLUMN "transfer_challenge_id" uuid;
+
+COMMENT ON COLUMN "idempotency_keys"."transfer_challenge_id" IS 'challenge of a transfer awaiting step-up, the response is then the challenge';
+
+ALTER TABLE "idempotency_keys" ADD FOREIGN KEY ("transfer_challenge_id") REFERENCES "transfer_challenges" ("id");
@@ -0,0 +1,11 @@
+-- name: CreateDPoPProof :execrows
+INSERT INTO dpop_proofs (
+    jti,
+    expires_at
+) VALUES (
+    $1, $2
+) ON CONFLICT (jti) DO NOTHING;
+
+-- name: DeleteExpiredDPoPProofs :execrows
+DELETE FROM dpop_proofs
+WHERE expires_at < now();
@@ -0,0 +1,27 @@
+-- name: CreateOutboxEmail :one
+INSERT INTO email_outbox (
+    to_address,
+    subject,
+    body
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: ListPendingOutboxEmails :many
+SELECT * FROM email_outbox
+WHERE sent_at IS NULL AND attempts < sqlc.arg(max_attempts)
+ORDER BY id
+LIMIT sqlc.arg(limit_count);
+
+-- name: MarkOutboxEmailSent :exec
+UPDATE email_outbox
+SET sent_at = now(),
+    attempts = attempts + 1,
+    last_error = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEmailFailed :exec
+UPDATE email_outbox
+SET attempts = attempts + 1,
+    last_error = $2
+WHERE id = $1;
@@ -0,0 +1,40 @@
+-- name: CreateFxRate :one
+INSERT INTO fx_rates (
+    base_currency,
+    quote_currency,
+    rate,
+    effective_at
+) VALUES (
+    $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetFxRate :one
+SELECT * FROM fx_rates
+WHERE base_currency = $1 AND quote_currency = $2 AND effective_at <= now()
+ORDER BY effective_at DESC
+LIMIT 1;
+
+-- name: CreateFxQuote :one
+INSERT INTO fx_quotes (
+    id,
+    username,
+    fx_rate_id,
+    from_currency,
+    to_currency,
+    rate,
+    spread_bps,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetFxQuote :one
+SELECT * FROM fx_quotes
+WHERE id = $1 LIMIT 1;
+
+-- name: UseFxQuote :one
+UPDATE fx_quotes
+SET used_at = now()
+WHERE id = $1 AND username = $2
+    AND used_at IS NULL AND expires_at > now()
+RETURNING *;
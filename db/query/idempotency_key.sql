@@ -0,0 +1,27 @@
+-- name: CreateIdempotencyKey :one
+INSERT INTO idempotency_keys (
+    username,
+    key,
+    request_hash,
+    response,
+    expires_at,
+    transfer_challenge_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) ON CONFLICT (username, key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash,
+    response = EXCLUDED.response,
+    transfer_challenge_id = EXCLUDED.transfer_challenge_id,
+    expires_at = EXCLUDED.expires_at,
+    created_at = now()
+WHERE idempotency_keys.expires_at <= now()
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys
+WHERE username = $1 AND key = $2 AND expires_at > now()
+LIMIT 1;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys
+WHERE expires_at < now();
@@ -0,0 +1,32 @@
+-- name: ListLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE (scope = 'username' AND subject = sqlc.arg(username))
+   OR (scope = 'client_ip' AND subject = sqlc.arg(client_ip));
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (
+    scope,
+    subject
+) VALUES (
+    sqlc.arg(scope), sqlc.arg(subject)
+) ON CONFLICT (scope, subject) DO UPDATE
+SET failed_attempts = CASE
+        WHEN login_throttles.last_failed_at < sqlc.arg(window_start) THEN 1
+        ELSE login_throttles.failed_attempts + 1
+    END,
+    last_failed_at = now()
+RETURNING *;
+
+-- name: LockLoginThrottle :exec
+UPDATE login_throttles
+SET locked_until = $3
+WHERE scope = $1 AND subject = $2;
+
+-- name: ResetLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE scope = $1 AND subject = $2;
+
+-- name: DeleteExpiredLoginThrottles :execrows
+DELETE FROM login_throttles
+WHERE last_failed_at < sqlc.arg(window_start)
+    AND (locked_until IS NULL OR locked_until < now());
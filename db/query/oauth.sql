@@ -0,0 +1,45 @@
+-- name: CreateOauthClient :one
+INSERT INTO oauth_clients (
+    id,
+    owner,
+    name,
+    hashed_secret,
+    redirect_uris,
+    scopes
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetOauthClient :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: UpdateOauthClientResourceServer :one
+UPDATE oauth_clients
+SET is_resource_server = $2
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateOauthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (
+    hashed_code,
+    client_id,
+    username,
+    redirect_uri,
+    scopes,
+    code_challenge,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetOauthAuthorizationCode :one
+SELECT * FROM oauth_authorization_codes
+WHERE hashed_code = $1 AND used_at IS NULL AND expires_at > now()
+LIMIT 1;
+
+-- name: UseOauthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET used_at = now()
+WHERE hashed_code = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
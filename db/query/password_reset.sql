@@ -0,0 +1,19 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+    username,
+    hashed_token,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) RETURNING *;
+
+-- name: UsePasswordReset :one
+UPDATE password_resets
+SET used_at = now()
+WHERE hashed_token = $1 AND used_at IS NULL AND expires_at > now()
+RETURNING *;
+
+-- name: CancelPasswordResets :execrows
+UPDATE password_resets
+SET used_at = now()
+WHERE username = $1 AND used_at IS NULL;
@@ -0,0 +1,36 @@
+-- name: CreateRevokedToken :execrows
+INSERT INTO revoked_tokens (
+    id,
+    username,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (id) DO NOTHING;
+
+-- name: GetRevokedToken :one
+SELECT * FROM revoked_tokens
+WHERE id = $1 LIMIT 1;
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at < now();
+
+-- name: UpsertUserRevocation :one
+INSERT INTO user_revocations (
+    username,
+    revoked_before,
+    expires_at
+) VALUES (
+    $1, $2, $3
+) ON CONFLICT (username) DO UPDATE
+SET revoked_before = GREATEST(user_revocations.revoked_before, EXCLUDED.revoked_before),
+    expires_at = GREATEST(user_revocations.expires_at, EXCLUDED.expires_at)
+RETURNING *;
+
+-- name: GetUserRevocation :one
+SELECT * FROM user_revocations
+WHERE username = $1 LIMIT 1;
+
+-- name: DeleteExpiredUserRevocations :execrows
+DELETE FROM user_revocations
+WHERE expires_at < now();
@@ -0,0 +1,55 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+    username,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    execute_at,
+    standing_order_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE username = $1
+ORDER BY execute_at DESC, id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: ListStandingOrderRuns :many
+SELECT * FROM scheduled_transfers
+WHERE standing_order_id = $1
+ORDER BY execute_at DESC, id DESC
+LIMIT $2
+OFFSET $3;
+
+-- name: CancelScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'canceled'
+WHERE id = $1 AND username = $2 AND status = 'pending'
+RETURNING *;
+
+-- name: ClaimDueScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE status = 'pending' AND execute_at <= now()
+ORDER BY execute_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: CompleteScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'completed', transfer_id = $2, executed_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: FailScheduledTransfer :one
+UPDATE scheduled_transfers
+SET status = 'failed', failure_reason = $2, executed_at = now()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
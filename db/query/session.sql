@@ -0,0 +1,51 @@
+-- name: CreateSession :one
+INSERT INTO sessions (
+    id,
+    username,
+    refresh_token,
+    user_agent,
+    client_ip,
+    is_blocked,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE id = $1 LIMIT 1;
+
+-- name: BlockSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1
+RETURNING *;
+
+-- name: BlockUserSessions :execrows
+UPDATE sessions
+SET is_blocked = true
+WHERE username = $1 AND is_blocked = false;
+
+-- name: ListUserSessions :many
+SELECT * FROM sessions
+WHERE username = $1 AND is_blocked = false AND expires_at > now()
+ORDER BY last_seen_at DESC;
+
+-- name: BlockUserSession :one
+UPDATE sessions
+SET is_blocked = true
+WHERE id = $1 AND username = $2
+RETURNING *;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_seen_at = now(),
+    client_ip = $2
+WHERE id = $1;
+
+-- name: CountUserAgentSessions :one
+SELECT
+    COUNT(*) AS session_count,
+    COUNT(*) FILTER (WHERE user_agent = $2) AS user_agent_session_count
+FROM sessions
+WHERE username = $1;
@@ -0,0 +1,61 @@
+-- name: CreateStandingOrder :one
+INSERT INTO standing_orders (
+    username,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    frequency,
+    repeat_every,
+    day_of_month,
+    start_at,
+    end_at,
+    max_occurrences,
+    next_execution_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+) RETURNING *;
+
+-- name: GetStandingOrder :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1;
+
+-- name: GetStandingOrderForUpdate :one
+SELECT * FROM standing_orders
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListStandingOrders :many
+SELECT * FROM standing_orders
+WHERE username = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: PauseStandingOrder :one
+UPDATE standing_orders
+SET status = 'paused'
+WHERE id = $1 AND username = $2 AND status = 'active'
+RETURNING *;
+
+-- name: CancelStandingOrder :one
+UPDATE standing_orders
+SET status = 'canceled', next_execution_at = NULL
+WHERE id = $1 AND username = $2 AND status IN ('active', 'paused')
+RETURNING *;
+
+-- name: ClaimDueStandingOrder :one
+SELECT * FROM standing_orders
+WHERE status = 'active' AND next_execution_at <= now()
+ORDER BY next_execution_at
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: UpdateStandingOrderSchedule :one
+UPDATE standing_orders
+SET
+    status = sqlc.arg(status),
+    occurrences = sqlc.arg(occurrences),
+    next_execution_at = sqlc.narg(next_execution_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
@@ -0,0 +1,46 @@
+-- name: UpsertTotpSecret :one
+INSERT INTO totp_secrets (
+    username,
+    encrypted_secret
+) VALUES (
+    $1, $2
+) ON CONFLICT (username) DO UPDATE
+SET encrypted_secret = EXCLUDED.encrypted_secret,
+    is_enabled = false,
+    last_used_step = 0,
+    created_at = now(),
+    enabled_at = NULL
+RETURNING *;
+
+-- name: GetTotpSecret :one
+SELECT * FROM totp_secrets
+WHERE username = $1 LIMIT 1;
+
+-- name: EnableTotpSecret :one
+UPDATE totp_secrets
+SET is_enabled = true,
+    enabled_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: UpdateTotpLastUsedStep :execrows
+UPDATE totp_secrets
+SET last_used_step = sqlc.arg(step)
+WHERE username = sqlc.arg(username) AND last_used_step < sqlc.arg(step);
+
+-- name: DeleteTotpSecret :exec
+DELETE FROM totp_secrets
+WHERE username = $1;
+
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (
+    username,
+    hashed_code
+) VALUES (
+    $1, $2
+) RETURNING *;
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = now()
+WHERE username = $1 AND hashed_code = $2 AND used_at IS NULL;
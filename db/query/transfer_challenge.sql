@@ -0,0 +1,30 @@
+-- name: CreateTransferChallenge :one
+INSERT INTO transfer_challenges (
+    id,
+    username,
+    from_account_id,
+    to_account_id,
+    amount,
+    currency,
+    fx_quote_id,
+    expires_at
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8
+) RETURNING *;
+
+-- name: GetTransferChallenge :one
+SELECT * FROM transfer_challenges
+WHERE id = $1 LIMIT 1;
+
+-- name: ConfirmTransferChallenge :one
+UPDATE transfer_challenges
+SET status = 'confirmed', confirmed_at = now()
+WHERE id = $1 AND username = $2
+    AND status = 'awaiting_confirmation' AND expires_at > now()
+RETURNING *;
+
+-- name: SetTransferChallengeTransfer :one
+UPDATE transfer_challenges
+SET transfer_id = $2
+WHERE id = $1
+RETURNING *;
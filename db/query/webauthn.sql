@@ -0,0 +1,28 @@
+-- name: CreateWebauthnCredential :one
+INSERT INTO webauthn_credentials (
+    username,
+    name,
+    credential_id,
+    public_key,
+    sign_count
+) VALUES (
+    $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetWebauthnCredential :one
+SELECT * FROM webauthn_credentials
+WHERE credential_id = $1 LIMIT 1;
+
+-- name: ListWebauthnCredentials :many
+SELECT * FROM webauthn_credentials
+WHERE username = $1
+ORDER BY id;
+
+-- name: DeleteWebauthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND username = $2;
+
+-- name: UpdateWebauthnCredentialSignCount :execrows
+UPDATE webauthn_credentials
+SET sign_count = sqlc.arg(new_sign_count), last_used_at = now()
+WHERE id = sqlc.arg(id) AND sign_count = sqlc.arg(old_sign_count);
@@ -76,6 +76,27 @@ func TestUpdateAccount(t *testing.T) {
 	require.WithinDuration(t, testAccount.account.CreatedAt, updatedAccount.CreatedAt, time.Second)
 }
 
+func TestUpdateAccountOverdraftLimit(t *testing.T) {
+	testAccount := createRandomAccount(t)
+	require.Zero(t, testAccount.account.OverdraftLimit)
+
+	arg := UpdateAccountOverdraftLimitParams{
+		ID:             testAccount.account.ID,
+		OverdraftLimit: util.RandomMoney(),
+	}
+
+	updatedAccount, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, testAccount.account.ID, updatedAccount.ID)
+	require.Equal(t, testAccount.account.Balance, updatedAccount.Balance)
+	require.Equal(t, arg.OverdraftLimit, updatedAccount.OverdraftLimit)
+
+	// the overdraft limit cannot be negative
+	arg.OverdraftLimit = -1
+	_, err = testQueries.UpdateAccountOverdraftLimit(context.Background(), arg)
+	require.Error(t, err)
+}
+
 func TestDeleteAccount(t *testing.T) {
 	testAccount := createRandomAccount(t)
 
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomApiKey(t *testing.T, user User) ApiKey {
+	arg := CreateApiKeyParams{
+		Username:  user.Username,
+		Name:      util.RandomOwner(),
+		Prefix:    "obk_" + util.RandomString(8),
+		HashedKey: util.HashSecret(util.RandomString(32)),
+		Scopes:    util.AccountsReadScope,
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true},
+	}
+
+	apiKey, err := testQueries.CreateApiKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, apiKey.Username)
+	require.Equal(t, arg.Name, apiKey.Name)
+	require.Equal(t, arg.Prefix, apiKey.Prefix)
+	require.Equal(t, arg.HashedKey, apiKey.HashedKey)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.WithinDuration(t, arg.ExpiresAt.Time, apiKey.ExpiresAt.Time, time.Second)
+	require.False(t, apiKey.LastUsedAt.Valid)
+
+	return apiKey
+}
+
+func TestGetApiKeyByPrefix(t *testing.T) {
+	apiKey1 := createRandomApiKey(t, createRandomUser(t))
+
+	apiKey2, err := testQueries.GetApiKeyByPrefix(context.Background(), apiKey1.Prefix)
+	require.NoError(t, err)
+	require.Equal(t, apiKey1.ID, apiKey2.ID)
+	require.Equal(t, apiKey1.HashedKey, apiKey2.HashedKey)
+}
+
+func TestUpdateApiKeyLastUsed(t *testing.T) {
+	apiKey := createRandomApiKey(t, createRandomUser(t))
+
+	err := testQueries.UpdateApiKeyLastUsed(context.Background(), apiKey.ID)
+	require.NoError(t, err)
+
+	usedKey, err := testQueries.GetApiKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.NoError(t, err)
+	require.True(t, usedKey.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), usedKey.LastUsedAt.Time, time.Second)
+}
+
+func TestListAndDeleteApiKeys(t *testing.T) {
+	user := createRandomUser(t)
+	otherUser := createRandomUser(t)
+	apiKey1 := createRandomApiKey(t, user)
+	apiKey2 := createRandomApiKey(t, user)
+
+	apiKeys, err := testQueries.ListApiKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 2)
+	require.Equal(t, apiKey1.ID, apiKeys[0].ID)
+	require.Equal(t, apiKey2.ID, apiKeys[1].ID)
+
+	// Keys of another user cannot be deleted
+	rows, err := testQueries.DeleteApiKey(context.Background(), DeleteApiKeyParams{ID: apiKey1.ID, Username: otherUser.Username})
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	rows, err = testQueries.DeleteApiKey(context.Background(), DeleteApiKeyParams{ID: apiKey1.ID, Username: user.Username})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	_, err = testQueries.GetApiKeyByPrefix(context.Background(), apiKey1.Prefix)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestDeleteUserApiKeys(t *testing.T) {
+	user := createRandomUser(t)
+	otherUser := createRandomUser(t)
+	createRandomApiKey(t, user)
+	createRandomApiKey(t, user)
+	otherKey := createRandomApiKey(t, otherUser)
+
+	rows, err := testQueries.DeleteUserApiKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), rows)
+
+	apiKeys, err := testQueries.ListApiKeys(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Empty(t, apiKeys)
+
+	// Keys of other users are left alone
+	_, err = testQueries.GetApiKeyByPrefix(context.Background(), otherKey.Prefix)
+	require.NoError(t, err)
+}
@@ -1,10 +1,11 @@
 package db
 
 import (
+	"database/sql"
 	"errors"
+	"fmt"
 
-	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
 )
 
 const (
@@ -12,16 +13,35 @@ const (
 	UniqueViolation     = "23505"
 )
 
-var ErrRecordNotFound = pgx.ErrNoRows
+var ErrRecordNotFound = sql.ErrNoRows
 
-var ErrUniqueViolation = &pgconn.PgError{
+// ErrIdempotencyKeyInUse is returned when the idempotency key of a transfer was already used by another transfer
+var ErrIdempotencyKeyInUse = errors.New("idempotency key is already in use")
+
+// ErrZeroConvertedAmount is returned when a cross-currency transfer is too small to credit anything at the rate of its quote
+var ErrZeroConvertedAmount = errors.New("amount is too small to convert at the rate of the quote")
+
+// InsufficientFundsError is returned when a transfer would take the balance of an account below its overdraft limit
+type InsufficientFundsError struct {
+	AccountID      int64
+	Balance        int64
+	OverdraftLimit int64
+	Amount         int64
+}
+
+func (err *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("account [%d] has insufficient funds to transfer %d: balance %d, overdraft limit %d",
+		err.AccountID, err.Amount, err.Balance, err.OverdraftLimit)
+}
+
+var ErrUniqueViolation = &pq.Error{
 	Code: UniqueViolation,
 }
 
 func ErrorCode(err error) string {
-	var pgErr *pgconn.PgError
-	if errors.As(err, &pgErr) {
-		return pgErr.Code
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code)
 	}
 	return ""
 }
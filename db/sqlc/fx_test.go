@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomFxRate(t *testing.T, baseCurrency, quoteCurrency string, effectiveAt time.Time) FxRate {
+	arg := CreateFxRateParams{
+		BaseCurrency:  baseCurrency,
+		QuoteCurrency: quoteCurrency,
+		Rate:          util.RandomInt(50_000_000, 150_000_000),
+		EffectiveAt:   effectiveAt,
+	}
+
+	fxRate, err := testQueries.CreateFxRate(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, fxRate.ID)
+	require.Equal(t, arg.BaseCurrency, fxRate.BaseCurrency)
+	require.Equal(t, arg.QuoteCurrency, fxRate.QuoteCurrency)
+	require.Equal(t, arg.Rate, fxRate.Rate)
+	require.WithinDuration(t, arg.EffectiveAt, fxRate.EffectiveAt, time.Second)
+	require.NotZero(t, fxRate.CreatedAt)
+
+	return fxRate
+}
+
+func createRandomFxQuote(t *testing.T, username string, fxRate FxRate, expiresAt time.Time) FxQuote {
+	arg := CreateFxQuoteParams{
+		ID:           uuid.New(),
+		Username:     username,
+		FxRateID:     fxRate.ID,
+		FromCurrency: fxRate.BaseCurrency,
+		ToCurrency:   fxRate.QuoteCurrency,
+		Rate:         util.ApplySpread(fxRate.Rate, 50),
+		SpreadBps:    50,
+		ExpiresAt:    expiresAt,
+	}
+
+	quote, err := testQueries.CreateFxQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, quote.ID)
+	require.Equal(t, arg.Username, quote.Username)
+	require.Equal(t, arg.FxRateID, quote.FxRateID)
+	require.Equal(t, arg.Rate, quote.Rate)
+	require.Equal(t, arg.SpreadBps, quote.SpreadBps)
+	require.False(t, quote.UsedAt.Valid)
+
+	return quote
+}
+
+func TestGetFxRate(t *testing.T) {
+	past := createRandomFxRate(t, util.USD, util.EUR, time.Now().Add(-time.Second))
+	createRandomFxRate(t, util.USD, util.EUR, time.Now().Add(time.Hour))
+
+	fxRate, err := testQueries.GetFxRate(context.Background(), GetFxRateParams{
+		BaseCurrency:  util.USD,
+		QuoteCurrency: util.EUR,
+	})
+	require.NoError(t, err)
+
+	// rates that are not effective yet are ignored
+	require.False(t, fxRate.EffectiveAt.After(time.Now()))
+	require.False(t, fxRate.EffectiveAt.Before(past.EffectiveAt))
+}
+
+func TestUseFxQuote(t *testing.T) {
+	user := createRandomUser(t)
+	fxRate := createRandomFxRate(t, util.USD, util.CAD, time.Now())
+	quote := createRandomFxQuote(t, user.Username, fxRate, time.Now().Add(time.Minute))
+
+	arg := UseFxQuoteParams{
+		ID:       quote.ID,
+		Username: user.Username,
+	}
+
+	// the quote cannot be used by another user
+	_, err := testQueries.UseFxQuote(context.Background(), UseFxQuoteParams{
+		ID:       quote.ID,
+		Username: createRandomUser(t).Username,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	usedQuote, err := testQueries.UseFxQuote(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, usedQuote.UsedAt.Valid)
+	require.WithinDuration(t, time.Now(), usedQuote.UsedAt.Time, time.Second)
+
+	// nor twice
+	_, err = testQueries.UseFxQuote(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseExpiredFxQuote(t *testing.T) {
+	user := createRandomUser(t)
+	fxRate := createRandomFxRate(t, util.USD, util.CAD, time.Now())
+	quote := createRandomFxQuote(t, user.Username, fxRate, time.Now().Add(-time.Second))
+
+	_, err := testQueries.UseFxQuote(context.Background(), UseFxQuoteParams{
+		ID:       quote.ID,
+		Username: user.Username,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	quote, err = testQueries.GetFxQuote(context.Background(), quote.ID)
+	require.NoError(t, err)
+	require.False(t, quote.UsedAt.Valid)
+}
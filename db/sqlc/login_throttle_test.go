@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordLoginFailure(t *testing.T) {
+	arg := RecordLoginFailureParams{
+		Scope:       "username",
+		Subject:     util.RandomOwner(),
+		WindowStart: time.Now().Add(-time.Minute),
+	}
+
+	throttle, err := testQueries.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Scope, throttle.Scope)
+	require.Equal(t, arg.Subject, throttle.Subject)
+	require.Equal(t, int32(1), throttle.FailedAttempts)
+	require.False(t, throttle.LockedUntil.Valid)
+
+	throttle, err = testQueries.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), throttle.FailedAttempts)
+
+	// Failures before the window are forgotten
+	arg.WindowStart = time.Now().Add(time.Minute)
+	throttle, err = testQueries.RecordLoginFailure(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), throttle.FailedAttempts)
+}
+
+func TestLockAndResetLoginThrottle(t *testing.T) {
+	username := util.RandomOwner()
+	clientIP := "192.0.2." + util.RandomString(3)
+
+	for _, arg := range []RecordLoginFailureParams{
+		{Scope: "username", Subject: username, WindowStart: time.Now()},
+		{Scope: "client_ip", Subject: clientIP, WindowStart: time.Now()},
+	} {
+		_, err := testQueries.RecordLoginFailure(context.Background(), arg)
+		require.NoError(t, err)
+	}
+
+	lockedUntil := time.Now().Add(time.Minute)
+	err := testQueries.LockLoginThrottle(context.Background(), LockLoginThrottleParams{
+		Scope:       "username",
+		Subject:     username,
+		LockedUntil: sql.NullTime{Time: lockedUntil, Valid: true},
+	})
+	require.NoError(t, err)
+
+	listArg := ListLoginThrottlesParams{
+		Username: username,
+		ClientIp: clientIP,
+	}
+
+	throttles, err := testQueries.ListLoginThrottles(context.Background(), listArg)
+	require.NoError(t, err)
+	require.Len(t, throttles, 2)
+
+	err = testQueries.ResetLoginThrottle(context.Background(), ResetLoginThrottleParams{
+		Scope:   "username",
+		Subject: username,
+	})
+	require.NoError(t, err)
+
+	throttles, err = testQueries.ListLoginThrottles(context.Background(), listArg)
+	require.NoError(t, err)
+	require.Len(t, throttles, 1)
+	require.Equal(t, "client_ip", throttles[0].Scope)
+}
+
+func TestDeleteExpiredLoginThrottles(t *testing.T) {
+	recent := RecordLoginFailureParams{Scope: "username", Subject: util.RandomOwner(), WindowStart: time.Now()}
+	locked := RecordLoginFailureParams{Scope: "username", Subject: util.RandomOwner(), WindowStart: time.Now()}
+	for _, arg := range []RecordLoginFailureParams{recent, locked} {
+		_, err := testQueries.RecordLoginFailure(context.Background(), arg)
+		require.NoError(t, err)
+	}
+
+	err := testQueries.LockLoginThrottle(context.Background(), LockLoginThrottleParams{
+		Scope:       locked.Scope,
+		Subject:     locked.Subject,
+		LockedUntil: sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true},
+	})
+	require.NoError(t, err)
+
+	// Failures within the window are kept
+	_, err = testQueries.DeleteExpiredLoginThrottles(context.Background(), time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+
+	throttles, err := testQueries.ListLoginThrottles(context.Background(), ListLoginThrottlesParams{Username: recent.Subject})
+	require.NoError(t, err)
+	require.Len(t, throttles, 1)
+
+	// Failures before the window are deleted, unless they are still locked out
+	_, err = testQueries.DeleteExpiredLoginThrottles(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	throttles, err = testQueries.ListLoginThrottles(context.Background(), ListLoginThrottlesParams{Username: recent.Subject})
+	require.NoError(t, err)
+	require.Empty(t, throttles)
+
+	throttles, err = testQueries.ListLoginThrottles(context.Background(), ListLoginThrottlesParams{Username: locked.Subject})
+	require.NoError(t, err)
+	require.Len(t, throttles, 1)
+}
@@ -3,7 +3,11 @@
 package db
 
 import (
+	"database/sql"
+	"encoding/json"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 type Account struct {
@@ -12,6 +16,39 @@ type Account struct {
 	Balance   int64     `json:"balance"`
 	Currency  string    `json:"currency"`
 	CreatedAt time.Time `json:"created_at"`
+	// how far below zero transfers can take the balance
+	OverdraftLimit int64 `json:"overdraft_limit"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// public part of the key, used to look it up
+	Prefix    string `json:"prefix"`
+	HashedKey string `json:"hashed_key"`
+	// space separated list of scopes
+	Scopes     string       `json:"scopes"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type DpopProof struct {
+	Jti       string    `json:"jti"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type EmailOutbox struct {
+	ID        int64          `json:"id"`
+	ToAddress string         `json:"to_address"`
+	Subject   string         `json:"subject"`
+	Body      string         `json:"body"`
+	Attempts  int32          `json:"attempts"`
+	LastError sql.NullString `json:"last_error"`
+	SentAt    sql.NullTime   `json:"sent_at"`
+	CreatedAt time.Time      `json:"created_at"`
 }
 
 type Entry struct {
@@ -22,13 +59,206 @@ type Entry struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type FxQuote struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	FxRateID     int64     `json:"fx_rate_id"`
+	FromCurrency string    `json:"from_currency"`
+	ToCurrency   string    `json:"to_currency"`
+	// rate locked for the transfer, spread included, scaled by 10^8
+	Rate int64 `json:"rate"`
+	// spread taken on the rate, in basis points
+	SpreadBps int32     `json:"spread_bps"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// a quote locks its rate for a single transfer
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type FxRate struct {
+	ID            int64  `json:"id"`
+	BaseCurrency  string `json:"base_currency"`
+	QuoteCurrency string `json:"quote_currency"`
+	// amount of quote currency for one unit of base currency, scaled by 10^8
+	Rate int64 `json:"rate"`
+	// the rate applies from this time until the next rate of the currency pair
+	EffectiveAt time.Time `json:"effective_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type IdempotencyKey struct {
+	Username string `json:"username"`
+	Key      string `json:"key"`
+	// sha256 of the request, so that the key cannot be reused for another request
+	RequestHash string `json:"request_hash"`
+	// response replayed to the retries of the request
+	Response  json.RawMessage `json:"response"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	CreatedAt time.Time       `json:"created_at"`
+	// challenge of a transfer awaiting step-up, the response is then the challenge
+	TransferChallengeID uuid.NullUUID `json:"transfer_challenge_id"`
+}
+
+type LoginThrottle struct {
+	// Either username or client_ip
+	Scope          string    `json:"scope"`
+	Subject        string    `json:"subject"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	LastFailedAt   time.Time `json:"last_failed_at"`
+	// Login attempts are rejected until this time
+	LockedUntil sql.NullTime `json:"locked_until"`
+}
+
+type OauthAuthorizationCode struct {
+	HashedCode  string `json:"hashed_code"`
+	ClientID    string `json:"client_id"`
+	Username    string `json:"username"`
+	RedirectUri string `json:"redirect_uri"`
+	Scopes      string `json:"scopes"`
+	// PKCE S256 code challenge
+	CodeChallenge string       `json:"code_challenge"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	UsedAt        sql.NullTime `json:"used_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID    string `json:"id"`
+	Owner string `json:"owner"`
+	Name  string `json:"name"`
+	// null for public clients
+	HashedSecret sql.NullString `json:"hashed_secret"`
+	// space separated list of redirect URIs
+	RedirectUris string    `json:"redirect_uris"`
+	Scopes       string    `json:"scopes"`
+	CreatedAt    time.Time `json:"created_at"`
+	// set by an admin to let the client introspect tokens
+	IsResourceServer bool `json:"is_resource_server"`
+}
+
+type PasswordReset struct {
+	ID          int64        `json:"id"`
+	Username    string       `json:"username"`
+	HashedToken string       `json:"hashed_token"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	UsedAt      sql.NullTime `json:"used_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type RecoveryCode struct {
+	ID         int64        `json:"id"`
+	Username   string       `json:"username"`
+	HashedCode string       `json:"hashed_code"`
+	UsedAt     sql.NullTime `json:"used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type RevokedToken struct {
+	ID        uuid.UUID `json:"id"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RevokedAt time.Time `json:"revoked_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	ExecuteAt     time.Time `json:"execute_at"`
+	// pending, completed, failed or canceled
+	Status string `json:"status"`
+	// transfer executed once the scheduled transfer completed
+	TransferID sql.NullInt64 `json:"transfer_id"`
+	// why the transfer could not be executed, if it failed
+	FailureReason sql.NullString `json:"failure_reason"`
+	ExecutedAt    sql.NullTime   `json:"executed_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	// standing order the transfer is a run of
+	StandingOrderID sql.NullInt64 `json:"standing_order_id"`
+}
+
+type Session struct {
+	ID           uuid.UUID `json:"id"`
+	Username     string    `json:"username"`
+	RefreshToken string    `json:"refresh_token"`
+	UserAgent    string    `json:"user_agent"`
+	ClientIp     string    `json:"client_ip"`
+	IsBlocked    bool      `json:"is_blocked"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	// last time the refresh token of the session was used
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+type StandingOrder struct {
+	ID            int64  `json:"id"`
+	Username      string `json:"username"`
+	FromAccountID int64  `json:"from_account_id"`
+	ToAccountID   int64  `json:"to_account_id"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// weekly, monthly or last_business_day
+	Frequency string `json:"frequency"`
+	// number of weeks or months between occurrences
+	RepeatEvery int32 `json:"repeat_every"`
+	// day of monthly standing orders
+	DayOfMonth     sql.NullInt32 `json:"day_of_month"`
+	StartAt        time.Time     `json:"start_at"`
+	EndAt          sql.NullTime  `json:"end_at"`
+	MaxOccurrences sql.NullInt32 `json:"max_occurrences"`
+	// number of runs generated so far
+	Occurrences int32 `json:"occurrences"`
+	// time of the next run, null once the standing order has ended
+	NextExecutionAt sql.NullTime `json:"next_execution_at"`
+	// active, paused, canceled or completed
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TotpSecret struct {
+	Username        string `json:"username"`
+	EncryptedSecret []byte `json:"encrypted_secret"`
+	IsEnabled       bool   `json:"is_enabled"`
+	// Time step of the last accepted code, older codes are rejected to prevent replays
+	LastUsedStep int64        `json:"last_used_step"`
+	CreatedAt    time.Time    `json:"created_at"`
+	EnabledAt    sql.NullTime `json:"enabled_at"`
+}
+
 type Transfer struct {
 	ID            int64 `json:"id"`
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
-	// Must be positive
+	// amount debited from the sender, in its currency
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// amount credited to the recipient, in its currency
+	ToAmount int64 `json:"to_amount"`
+	// rate of the currency conversion, spread included, scaled by 10^8
+	FxRate      sql.NullInt64 `json:"fx_rate"`
+	FxSpreadBps sql.NullInt32 `json:"fx_spread_bps"`
+	FxQuoteID   uuid.NullUUID `json:"fx_quote_id"`
+}
+
+type TransferChallenge struct {
+	ID            uuid.UUID `json:"id"`
+	Username      string    `json:"username"`
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	Currency      string    `json:"currency"`
+	// awaiting_confirmation or confirmed
+	Status string `json:"status"`
+	// transfer executed once the challenge was confirmed
+	TransferID  sql.NullInt64 `json:"transfer_id"`
+	ExpiresAt   time.Time     `json:"expires_at"`
+	ConfirmedAt sql.NullTime  `json:"confirmed_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	// quote converting the amount of a cross-currency transfer
+	FxQuoteID uuid.NullUUID `json:"fx_quote_id"`
 }
 
 type User struct {
@@ -38,4 +268,36 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	Role              string    `json:"role"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+}
+
+type UserRevocation struct {
+	Username string `json:"username"`
+	// Tokens issued at or before this time are rejected
+	RevokedBefore time.Time `json:"revoked_before"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type VerifyEmail struct {
+	ID               int64     `json:"id"`
+	Username         string    `json:"username"`
+	Email            string    `json:"email"`
+	HashedSecretCode string    `json:"hashed_secret_code"`
+	IsUsed           bool      `json:"is_used"`
+	CreatedAt        time.Time `json:"created_at"`
+	ExpiredAt        time.Time `json:"expired_at"`
+}
+
+type WebauthnCredential struct {
+	ID           int64  `json:"id"`
+	Username     string `json:"username"`
+	Name         string `json:"name"`
+	CredentialID []byte `json:"credential_id"`
+	// COSE encoded public key
+	PublicKey []byte `json:"public_key"`
+	// signature counter of the authenticator, 0 if it does not count
+	SignCount  int64        `json:"sign_count"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
 }
@@ -0,0 +1,105 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOauthClient(t *testing.T, owner User) OauthClient {
+	arg := CreateOauthClientParams{
+		ID:           util.RandomString(16),
+		Owner:        owner.Username,
+		Name:         util.RandomOwner(),
+		HashedSecret: sql.NullString{String: util.HashSecret(util.RandomString(32)), Valid: true},
+		RedirectUris: "https://budget.example.com/callback",
+		Scopes:       util.AccountsReadScope,
+	}
+
+	client, err := testQueries.CreateOauthClient(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, client.ID)
+	require.Equal(t, arg.Owner, client.Owner)
+	require.Equal(t, arg.HashedSecret, client.HashedSecret)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+	require.False(t, client.IsResourceServer)
+
+	return client
+}
+
+func TestGetOauthClient(t *testing.T) {
+	client1 := createRandomOauthClient(t, createRandomUser(t))
+
+	client2, err := testQueries.GetOauthClient(context.Background(), client1.ID)
+	require.NoError(t, err)
+	require.Equal(t, client1.Name, client2.Name)
+	require.WithinDuration(t, client1.CreatedAt, client2.CreatedAt, time.Second)
+}
+
+func TestUpdateOauthClientResourceServer(t *testing.T) {
+	client := createRandomOauthClient(t, createRandomUser(t))
+
+	updated, err := testQueries.UpdateOauthClientResourceServer(context.Background(), UpdateOauthClientResourceServerParams{
+		ID:               client.ID,
+		IsResourceServer: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, client.ID, updated.ID)
+	require.True(t, updated.IsResourceServer)
+
+	_, err = testQueries.UpdateOauthClientResourceServer(context.Background(), UpdateOauthClientResourceServerParams{
+		ID:               util.RandomString(16),
+		IsResourceServer: true,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseOauthAuthorizationCode(t *testing.T) {
+	user := createRandomUser(t)
+	client := createRandomOauthClient(t, user)
+
+	arg := CreateOauthAuthorizationCodeParams{
+		HashedCode:    util.HashSecret(util.RandomString(32)),
+		ClientID:      client.ID,
+		Username:      user.Username,
+		RedirectUri:   client.RedirectUris,
+		Scopes:        client.Scopes,
+		CodeChallenge: util.CodeChallengeS256(util.RandomString(64)),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	_, err := testQueries.CreateOauthAuthorizationCode(context.Background(), arg)
+	require.NoError(t, err)
+
+	// Looking the code up does not use it
+	code, err := testQueries.GetOauthAuthorizationCode(context.Background(), arg.HashedCode)
+	require.NoError(t, err)
+	require.Equal(t, arg.ClientID, code.ClientID)
+	require.False(t, code.UsedAt.Valid)
+
+	code, err = testQueries.UseOauthAuthorizationCode(context.Background(), arg.HashedCode)
+	require.NoError(t, err)
+	require.Equal(t, arg.ClientID, code.ClientID)
+	require.Equal(t, arg.CodeChallenge, code.CodeChallenge)
+	require.True(t, code.UsedAt.Valid)
+
+	// Authorization codes can only be used once
+	_, err = testQueries.UseOauthAuthorizationCode(context.Background(), arg.HashedCode)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testQueries.GetOauthAuthorizationCode(context.Background(), arg.HashedCode)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// Expired codes cannot be used
+	arg.HashedCode = util.HashSecret(util.RandomString(32))
+	arg.ExpiresAt = time.Now().Add(-time.Minute)
+	_, err = testQueries.CreateOauthAuthorizationCode(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = testQueries.UseOauthAuthorizationCode(context.Background(), arg.HashedCode)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// revokeUserTokens revokes the tokens of the user with the revocation store of the transaction
+func revokeUserTokens(ctx context.Context, revocationStore token.RevocationStore, username string) error {
+	now := time.Now()
+	return revocationStore.RevokeUserTokens(ctx, username, now, now.Add(time.Minute))
+}
+
+func createRandomPasswordReset(t *testing.T, user User, expiresAt time.Time) PasswordReset {
+	arg := CreatePasswordResetParams{
+		Username:    user.Username,
+		HashedToken: util.HashSecret(util.RandomString(32)),
+		ExpiresAt:   expiresAt,
+	}
+
+	passwordReset, err := testQueries.CreatePasswordReset(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, passwordReset.Username)
+	require.Equal(t, arg.HashedToken, passwordReset.HashedToken)
+	require.WithinDuration(t, arg.ExpiresAt, passwordReset.ExpiresAt, time.Second)
+	require.False(t, passwordReset.UsedAt.Valid)
+
+	return passwordReset
+}
+
+func TestUsePasswordReset(t *testing.T) {
+	user := createRandomUser(t)
+	passwordReset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+
+	usedReset, err := testQueries.UsePasswordReset(context.Background(), passwordReset.HashedToken)
+	require.NoError(t, err)
+	require.Equal(t, passwordReset.ID, usedReset.ID)
+	require.True(t, usedReset.UsedAt.Valid)
+
+	// A reset token can only be used once
+	_, err = testQueries.UsePasswordReset(context.Background(), passwordReset.HashedToken)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestUseExpiredPasswordReset(t *testing.T) {
+	user := createRandomUser(t)
+	passwordReset := createRandomPasswordReset(t, user, time.Now().Add(-time.Minute))
+
+	_, err := testQueries.UsePasswordReset(context.Background(), passwordReset.HashedToken)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestResetPasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	passwordReset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+	otherReset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+	apiKey := createRandomApiKey(t, user)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	result, err := store.ResetPasswordTx(context.Background(), ResetPasswordTxParams{
+		HashedToken:    passwordReset.HashedToken,
+		HashedPassword: hashedPassword,
+		RevokeTokens:   revokeUserTokens,
+	})
+	require.NoError(t, err)
+	require.Equal(t, passwordReset.ID, result.PasswordReset.ID)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.WithinDuration(t, time.Now(), result.User.PasswordChangedAt, time.Second)
+
+	// The other pending resets of the user are cancelled
+	_, err = testQueries.UsePasswordReset(context.Background(), otherReset.HashedToken)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// And so are its API keys
+	_, err = testQueries.GetApiKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// And its tokens are revoked
+	_, err = testQueries.GetUserRevocation(context.Background(), user.Username)
+	require.NoError(t, err)
+}
+
+func TestChangePasswordTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	passwordReset := createRandomPasswordReset(t, user, time.Now().Add(time.Hour))
+	apiKey := createRandomApiKey(t, user)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	result, err := store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		RevokeTokens:   revokeUserTokens,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, result.User.Username)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.WithinDuration(t, time.Now(), result.User.PasswordChangedAt, time.Second)
+
+	// Pending resets of the user are cancelled
+	_, err = testQueries.UsePasswordReset(context.Background(), passwordReset.HashedToken)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// And so are its API keys
+	_, err = testQueries.GetApiKeyByPrefix(context.Background(), apiKey.Prefix)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// And its tokens are revoked
+	_, err = testQueries.GetUserRevocation(context.Background(), user.Username)
+	require.NoError(t, err)
+}
+
+func TestChangePasswordTxRevocationFails(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	errRevocation := errors.New("revocation failed")
+	_, err = store.ChangePasswordTx(context.Background(), ChangePasswordTxParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+		RevokeTokens: func(ctx context.Context, revocationStore token.RevocationStore, username string) error {
+			return errRevocation
+		},
+	})
+	require.ErrorIs(t, err, errRevocation)
+
+	// The password is not changed without the revocation
+	unchangedUser, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, user.HashedPassword, unchangedUser.HashedPassword)
+}
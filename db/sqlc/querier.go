@@ -4,24 +4,116 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/google/uuid"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	BlockSession(ctx context.Context, id uuid.UUID) (Session, error)
+	BlockUserSession(ctx context.Context, arg BlockUserSessionParams) (Session, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	CancelPasswordResets(ctx context.Context, username string) (int64, error)
+	CancelScheduledTransfer(ctx context.Context, arg CancelScheduledTransferParams) (ScheduledTransfer, error)
+	CancelStandingOrder(ctx context.Context, arg CancelStandingOrderParams) (StandingOrder, error)
+	ClaimDueScheduledTransfer(ctx context.Context) (ScheduledTransfer, error)
+	ClaimDueStandingOrder(ctx context.Context) (StandingOrder, error)
+	CompleteScheduledTransfer(ctx context.Context, arg CompleteScheduledTransferParams) (ScheduledTransfer, error)
+	ConfirmTransferChallenge(ctx context.Context, arg ConfirmTransferChallengeParams) (TransferChallenge, error)
+	CountUserAgentSessions(ctx context.Context, arg CountUserAgentSessionsParams) (CountUserAgentSessionsRow, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (ApiKey, error)
+	CreateDPoPProof(ctx context.Context, arg CreateDPoPProofParams) (int64, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFxQuote(ctx context.Context, arg CreateFxQuoteParams) (FxQuote, error)
+	CreateFxRate(ctx context.Context, arg CreateFxRateParams) (FxRate, error)
+	CreateIdempotencyKey(ctx context.Context, arg CreateIdempotencyKeyParams) (IdempotencyKey, error)
+	CreateOauthAuthorizationCode(ctx context.Context, arg CreateOauthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOauthClient(ctx context.Context, arg CreateOauthClientParams) (OauthClient, error)
+	CreateOutboxEmail(ctx context.Context, arg CreateOutboxEmailParams) (EmailOutbox, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateRevokedToken(ctx context.Context, arg CreateRevokedTokenParams) (int64, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	CreateStandingOrder(ctx context.Context, arg CreateStandingOrderParams) (StandingOrder, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
+	CreateTransferChallenge(ctx context.Context, arg CreateTransferChallengeParams) (TransferChallenge, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	CreateWebauthnCredential(ctx context.Context, arg CreateWebauthnCredentialParams) (WebauthnCredential, error)
 	DeleteAccount(ctx context.Context, id int64) error
+	DeleteApiKey(ctx context.Context, arg DeleteApiKeyParams) (int64, error)
+	DeleteExpiredDPoPProofs(ctx context.Context) (int64, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+	DeleteExpiredLoginThrottles(ctx context.Context, windowStart time.Time) (int64, error)
+	DeleteExpiredRevokedTokens(ctx context.Context) (int64, error)
+	DeleteExpiredUserRevocations(ctx context.Context) (int64, error)
+	DeleteTotpSecret(ctx context.Context, username string) error
+	DeleteUserApiKeys(ctx context.Context, username string) (int64, error)
+	DeleteWebauthnCredential(ctx context.Context, arg DeleteWebauthnCredentialParams) (int64, error)
+	EnableTotpSecret(ctx context.Context, username string) (TotpSecret, error)
+	FailScheduledTransfer(ctx context.Context, arg FailScheduledTransferParams) (ScheduledTransfer, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (ApiKey, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetFxQuote(ctx context.Context, id uuid.UUID) (FxQuote, error)
+	GetFxRate(ctx context.Context, arg GetFxRateParams) (FxRate, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetOauthAuthorizationCode(ctx context.Context, hashedCode string) (OauthAuthorizationCode, error)
+	GetOauthClient(ctx context.Context, id string) (OauthClient, error)
+	GetRevokedToken(ctx context.Context, id uuid.UUID) (RevokedToken, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
+	GetStandingOrder(ctx context.Context, id int64) (StandingOrder, error)
+	GetStandingOrderForUpdate(ctx context.Context, id int64) (StandingOrder, error)
+	GetTotpSecret(ctx context.Context, username string) (TotpSecret, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferChallenge(ctx context.Context, id uuid.UUID) (TransferChallenge, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserRevocation(ctx context.Context, username string) (UserRevocation, error)
+	GetWebauthnCredential(ctx context.Context, credentialID []byte) (WebauthnCredential, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	ListApiKeys(ctx context.Context, username string) ([]ApiKey, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListLoginThrottles(ctx context.Context, arg ListLoginThrottlesParams) ([]LoginThrottle, error)
+	ListPendingOutboxEmails(ctx context.Context, arg ListPendingOutboxEmailsParams) ([]EmailOutbox, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	ListStandingOrderRuns(ctx context.Context, arg ListStandingOrderRunsParams) ([]ScheduledTransfer, error)
+	ListStandingOrders(ctx context.Context, arg ListStandingOrdersParams) ([]StandingOrder, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	ListUserSessions(ctx context.Context, username string) ([]Session, error)
+	ListWebauthnCredentials(ctx context.Context, username string) ([]WebauthnCredential, error)
+	LockLoginThrottle(ctx context.Context, arg LockLoginThrottleParams) error
+	MarkOutboxEmailFailed(ctx context.Context, arg MarkOutboxEmailFailedParams) error
+	MarkOutboxEmailSent(ctx context.Context, id int64) error
+	PauseStandingOrder(ctx context.Context, arg PauseStandingOrderParams) (StandingOrder, error)
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error)
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	ResetLoginThrottle(ctx context.Context, arg ResetLoginThrottleParams) error
+	SetTransferChallengeTransfer(ctx context.Context, arg SetTransferChallengeTransferParams) (TransferChallenge, error)
+	TouchSession(ctx context.Context, arg TouchSessionParams) error
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountOverdraftLimit(ctx context.Context, arg UpdateAccountOverdraftLimitParams) (Account, error)
+	UpdateApiKeyLastUsed(ctx context.Context, id int64) error
+	UpdateOauthClientResourceServer(ctx context.Context, arg UpdateOauthClientResourceServerParams) (OauthClient, error)
+	UpdateStandingOrderSchedule(ctx context.Context, arg UpdateStandingOrderScheduleParams) (StandingOrder, error)
+	UpdateTotpLastUsedStep(ctx context.Context, arg UpdateTotpLastUsedStepParams) (int64, error)
+	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (User, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
+	UpdateWebauthnCredentialSignCount(ctx context.Context, arg UpdateWebauthnCredentialSignCountParams) (int64, error)
+	UpsertTotpSecret(ctx context.Context, arg UpsertTotpSecretParams) (TotpSecret, error)
+	UpsertUserRevocation(ctx context.Context, arg UpsertUserRevocationParams) (UserRevocation, error)
+	UseFxQuote(ctx context.Context, arg UseFxQuoteParams) (FxQuote, error)
+	UseOauthAuthorizationCode(ctx context.Context, hashedCode string) (OauthAuthorizationCode, error)
+	UsePasswordReset(ctx context.Context, hashedToken string) (PasswordReset, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseVerifyEmail(ctx context.Context, arg UseVerifyEmailParams) (VerifyEmail, error)
+	VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+)
+
+// SQLReplayCache is a token.ReplayCache of DPoP proof identifiers backed by Postgres
+type SQLReplayCache struct {
+	querier Querier
+}
+
+// NewReplayCache creates a token.ReplayCache on top of the given querier
+func NewReplayCache(querier Querier) token.ReplayCache {
+	return &SQLReplayCache{
+		querier: querier,
+	}
+}
+
+// Use records the identifier until expiresAt. It returns false if the identifier was already used.
+func (cache *SQLReplayCache) Use(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	rows, err := cache.querier.CreateDPoPProof(ctx, CreateDPoPProofParams{
+		Jti:       id,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// Prune forgets the identifiers that have expired
+func (cache *SQLReplayCache) Prune(ctx context.Context) error {
+	_, err := cache.querier.DeleteExpiredDPoPProofs(ctx)
+	return err
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLReplayCache(t *testing.T) {
+	cache := NewReplayCache(testQueries)
+	id := util.RandomString(32)
+
+	fresh, err := cache.Use(context.Background(), id, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, fresh)
+
+	fresh, err = cache.Use(context.Background(), id, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, fresh)
+}
+
+func TestSQLPruneReplayCache(t *testing.T) {
+	cache := NewReplayCache(testQueries)
+	expired := util.RandomString(32)
+	active := util.RandomString(32)
+
+	_, err := cache.Use(context.Background(), expired, time.Now().Add(-time.Second))
+	require.NoError(t, err)
+	_, err = cache.Use(context.Background(), active, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	err = cache.Prune(context.Background())
+	require.NoError(t, err)
+
+	fresh, err := cache.Use(context.Background(), expired, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.True(t, fresh)
+
+	fresh, err = cache.Use(context.Background(), active, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.False(t, fresh)
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/google/uuid"
+)
+
+// SQLRevocationStore is a token.RevocationStore backed by Postgres
+type SQLRevocationStore struct {
+	querier Querier
+}
+
+// NewRevocationStore creates a token.RevocationStore on top of the given querier
+func NewRevocationStore(querier Querier) token.RevocationStore {
+	return &SQLRevocationStore{
+		querier: querier,
+	}
+}
+
+// RevokeToken revokes a single token until it expires
+func (store *SQLRevocationStore) RevokeToken(ctx context.Context, tokenID uuid.UUID, username string, expiresAt time.Time) error {
+	_, err := store.querier.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+		ID:        tokenID,
+		Username:  username,
+		ExpiresAt: expiresAt,
+	})
+	return err
+}
+
+// ConsumeToken revokes a single use token until it expires, it returns false if it was already revoked.
+// The insert is the check, so that it is atomic.
+func (store *SQLRevocationStore) ConsumeToken(ctx context.Context, tokenID uuid.UUID, username string, expiresAt time.Time) (bool, error) {
+	rows, err := store.querier.CreateRevokedToken(ctx, CreateRevokedTokenParams{
+		ID:        tokenID,
+		Username:  username,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// RevokeUserTokens revokes every token of a user issued at or before issuedBefore
+func (store *SQLRevocationStore) RevokeUserTokens(ctx context.Context, username string, issuedBefore time.Time, expiresAt time.Time) error {
+	_, err := store.querier.UpsertUserRevocation(ctx, UpsertUserRevocationParams{
+		Username:      username,
+		RevokedBefore: issuedBefore,
+		ExpiresAt:     expiresAt,
+	})
+	return err
+}
+
+// IsRevoked checks if the token with the given payload has been revoked
+func (store *SQLRevocationStore) IsRevoked(ctx context.Context, payload *token.Payload) (bool, error) {
+	_, err := store.querier.GetRevokedToken(ctx, payload.ID)
+	if err == nil {
+		return true, nil
+	}
+	if err != sql.ErrNoRows {
+		return false, err
+	}
+
+	// Revoking a session revokes the access tokens issued from it
+	if payload.SessionID != uuid.Nil {
+		_, err = store.querier.GetRevokedToken(ctx, payload.SessionID)
+		if err == nil {
+			return true, nil
+		}
+		if err != sql.ErrNoRows {
+			return false, err
+		}
+	}
+
+	revocation, err := store.querier.GetUserRevocation(ctx, payload.Username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return !payload.IssuedAt.After(revocation.RevokedBefore), nil
+}
+
+// Prune deletes the revocations of tokens that have already expired
+func (store *SQLRevocationStore) Prune(ctx context.Context) error {
+	_, err := store.querier.DeleteExpiredRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = store.querier.DeleteExpiredUserRevocations(ctx)
+	return err
+}
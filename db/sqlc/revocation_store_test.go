@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLRevokeToken(t *testing.T) {
+	store := NewRevocationStore(testQueries)
+	user := createRandomUser(t)
+
+	payload1, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+	payload2, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeToken(context.Background(), payload1.ID, payload1.Username, payload1.ExpiredAt)
+	require.NoError(t, err)
+
+	// Revoking the same token twice is not an error
+	err = store.RevokeToken(context.Background(), payload1.ID, payload1.Username, payload1.ExpiredAt)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestSQLRevokeSession(t *testing.T) {
+	store := NewRevocationStore(testQueries)
+	user := createRandomUser(t)
+
+	refreshPayload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+	accessPayload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+	accessPayload.SessionID = refreshPayload.ID
+	otherPayload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+
+	// Sessions have the ID of their refresh token
+	err = store.RevokeToken(context.Background(), refreshPayload.ID, refreshPayload.Username, refreshPayload.ExpiredAt)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), accessPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestSQLConsumeToken(t *testing.T) {
+	store := NewRevocationStore(testQueries)
+	user := createRandomUser(t)
+
+	payload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+
+	consumed, err := store.ConsumeToken(context.Background(), payload.ID, payload.Username, payload.ExpiredAt)
+	require.NoError(t, err)
+	require.True(t, consumed)
+
+	// A token can only be consumed once
+	consumed, err = store.ConsumeToken(context.Background(), payload.ID, payload.Username, payload.ExpiredAt)
+	require.NoError(t, err)
+	require.False(t, consumed)
+
+	revoked, err := store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestSQLRevokeUserTokens(t *testing.T) {
+	store := NewRevocationStore(testQueries)
+	user := createRandomUser(t)
+
+	oldPayload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeUserTokens(context.Background(), user.Username, time.Now(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	newPayload, err := token.NewPayload(user.Username, user.Role, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestSQLRevokeUserTokensKeepsLongest(t *testing.T) {
+	store := NewRevocationStore(testQueries)
+	user := createRandomUser(t)
+
+	issuedBefore := time.Now()
+	expiresAt := issuedBefore.Add(time.Hour)
+	err := store.RevokeUserTokens(context.Background(), user.Username, issuedBefore, expiresAt)
+	require.NoError(t, err)
+
+	// A shorter revocation of earlier tokens does not cut the previous one down
+	err = store.RevokeUserTokens(context.Background(), user.Username, issuedBefore.Add(-time.Second), issuedBefore.Add(time.Minute))
+	require.NoError(t, err)
+
+	revocation, err := testQueries.GetUserRevocation(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, issuedBefore, revocation.RevokedBefore, time.Millisecond)
+	require.WithinDuration(t, expiresAt, revocation.ExpiresAt, time.Millisecond)
+}
+
+func TestSQLPruneRevocations(t *testing.T) {
+	store := NewRevocationStore(testQueries)
+	user := createRandomUser(t)
+
+	payload, err := token.NewPayload(user.Username, user.Role, -time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeToken(context.Background(), payload.ID, payload.Username, payload.ExpiredAt)
+	require.NoError(t, err)
+
+	err = store.Prune(context.Background())
+	require.NoError(t, err)
+
+	_, err = testQueries.GetRevokedToken(context.Background(), payload.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
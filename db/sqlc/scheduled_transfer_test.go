@@ -0,0 +1,89 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomScheduledTransfer(t *testing.T, fromAccount, toAccount Account, executeAt time.Time) ScheduledTransfer {
+	arg := CreateScheduledTransferParams{
+		Username:      fromAccount.Owner,
+		FromAccountID: fromAccount.ID,
+		ToAccountID:   toAccount.ID,
+		Amount:        util.RandomMoney(),
+		Currency:      fromAccount.Currency,
+		ExecuteAt:     executeAt,
+	}
+
+	scheduledTransfer, err := testQueries.CreateScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, scheduledTransfer.ID)
+	require.Equal(t, arg.Username, scheduledTransfer.Username)
+	require.Equal(t, arg.FromAccountID, scheduledTransfer.FromAccountID)
+	require.Equal(t, arg.ToAccountID, scheduledTransfer.ToAccountID)
+	require.Equal(t, arg.Amount, scheduledTransfer.Amount)
+	require.Equal(t, arg.Currency, scheduledTransfer.Currency)
+	require.WithinDuration(t, arg.ExecuteAt, scheduledTransfer.ExecuteAt, time.Second)
+	require.Equal(t, "pending", scheduledTransfer.Status)
+	require.False(t, scheduledTransfer.TransferID.Valid)
+	require.False(t, scheduledTransfer.FailureReason.Valid)
+
+	return scheduledTransfer
+}
+
+func TestCreateScheduledTransfer(t *testing.T) {
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+}
+
+func TestCancelScheduledTransfer(t *testing.T) {
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	scheduledTransfer := createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+
+	// only the user who scheduled the transfer can cancel it
+	_, err := testQueries.CancelScheduledTransfer(context.Background(), CancelScheduledTransferParams{
+		ID:       scheduledTransfer.ID,
+		Username: account2.Owner,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	arg := CancelScheduledTransferParams{
+		ID:       scheduledTransfer.ID,
+		Username: account1.Owner,
+	}
+	canceled, err := testQueries.CancelScheduledTransfer(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, "canceled", canceled.Status)
+
+	_, err = testQueries.CancelScheduledTransfer(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestListScheduledTransfers(t *testing.T) {
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	for i := 0; i < 6; i++ {
+		createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Duration(i+1)*time.Hour))
+	}
+
+	scheduledTransfers, err := testQueries.ListScheduledTransfers(context.Background(), ListScheduledTransfersParams{
+		Username: account1.Owner,
+		Limit:    5,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, scheduledTransfers, 5)
+
+	for i, scheduledTransfer := range scheduledTransfers {
+		require.Equal(t, account1.Owner, scheduledTransfer.Username)
+		if i > 0 {
+			require.False(t, scheduledTransfer.ExecuteAt.After(scheduledTransfers[i-1].ExecuteAt))
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomSession(t *testing.T) Session {
+	user := createRandomUser(t)
+
+	arg := CreateSessionParams{
+		ID:           uuid.New(),
+		Username:     user.Username,
+		RefreshToken: util.RandomString(32),
+		UserAgent:    util.RandomString(12),
+		ClientIp:     "127.0.0.1",
+		IsBlocked:    false,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+
+	session, err := testQueries.CreateSession(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, session)
+
+	require.Equal(t, arg.ID, session.ID)
+	require.Equal(t, arg.Username, session.Username)
+	require.Equal(t, arg.RefreshToken, session.RefreshToken)
+	require.Equal(t, arg.UserAgent, session.UserAgent)
+	require.Equal(t, arg.ClientIp, session.ClientIp)
+	require.False(t, session.IsBlocked)
+	require.WithinDuration(t, arg.ExpiresAt, session.ExpiresAt, time.Second)
+	require.NotZero(t, session.CreatedAt)
+
+	return session
+}
+
+func TestCreateSession(t *testing.T) {
+	createRandomSession(t)
+}
+
+func TestGetSession(t *testing.T) {
+	session1 := createRandomSession(t)
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, session2)
+
+	require.Equal(t, session1.ID, session2.ID)
+	require.Equal(t, session1.Username, session2.Username)
+	require.Equal(t, session1.RefreshToken, session2.RefreshToken)
+	require.WithinDuration(t, session1.ExpiresAt, session2.ExpiresAt, time.Second)
+	require.WithinDuration(t, session1.CreatedAt, session2.CreatedAt, time.Second)
+}
+
+func TestBlockSession(t *testing.T) {
+	session1 := createRandomSession(t)
+	session2, err := testQueries.BlockSession(context.Background(), session1.ID)
+
+	require.NoError(t, err)
+	require.Equal(t, session1.ID, session2.ID)
+	require.True(t, session2.IsBlocked)
+}
+
+func TestBlockUserSessions(t *testing.T) {
+	session1 := createRandomSession(t)
+
+	rows, err := testQueries.BlockUserSessions(context.Background(), session1.Username)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.True(t, session2.IsBlocked)
+}
+
+func TestListUserSessions(t *testing.T) {
+	session := createRandomSession(t)
+
+	sessions, err := testQueries.ListUserSessions(context.Background(), session.Username)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, session.ID, sessions[0].ID)
+
+	_, err = testQueries.BlockSession(context.Background(), session.ID)
+	require.NoError(t, err)
+
+	sessions, err = testQueries.ListUserSessions(context.Background(), session.Username)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
+
+func TestBlockUserSession(t *testing.T) {
+	session1 := createRandomSession(t)
+	other := createRandomUser(t)
+
+	_, err := testQueries.BlockUserSession(context.Background(), BlockUserSessionParams{
+		ID:       session1.ID,
+		Username: other.Username,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	session2, err := testQueries.BlockUserSession(context.Background(), BlockUserSessionParams{
+		ID:       session1.ID,
+		Username: session1.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, session2.IsBlocked)
+}
+
+func TestTouchSession(t *testing.T) {
+	session1 := createRandomSession(t)
+
+	err := testQueries.TouchSession(context.Background(), TouchSessionParams{
+		ID:       session1.ID,
+		ClientIp: "10.0.0.1",
+	})
+	require.NoError(t, err)
+
+	session2, err := testQueries.GetSession(context.Background(), session1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.1", session2.ClientIp)
+	require.False(t, session2.LastSeenAt.Before(session1.LastSeenAt))
+}
+
+func TestCreateSessionTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	createSession := func(userAgent string) CreateSessionTxResult {
+		result, err := store.CreateSessionTx(context.Background(), CreateSessionTxParams{
+			CreateSessionParams: CreateSessionParams{
+				ID:           uuid.New(),
+				Username:     user.Username,
+				RefreshToken: util.RandomString(32),
+				UserAgent:    userAgent,
+				ClientIp:     "127.0.0.1",
+				ExpiresAt:    time.Now().Add(time.Hour),
+			},
+			NewDeviceEmail: func(session Session) CreateOutboxEmailParams {
+				return CreateOutboxEmailParams{
+					ToAddress: user.Email,
+					Subject:   "new device",
+					Body:      session.UserAgent,
+				}
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, userAgent, result.Session.UserAgent)
+		return result
+	}
+
+	// the first login of a user is not a new device
+	result := createSession("agent-1")
+	require.Nil(t, result.Email)
+
+	result = createSession("agent-1")
+	require.Nil(t, result.Email)
+
+	result = createSession("agent-2")
+	require.NotNil(t, result.Email)
+	require.Equal(t, user.Email, result.Email.ToAddress)
+	require.Equal(t, "agent-2", result.Email.Body)
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+// randomStandingOrderParams returns the params of a weekly standing order between the accounts, starting at the given time
+func randomStandingOrderParams(fromAccount, toAccount Account, startAt time.Time) CreateStandingOrderParams {
+	return CreateStandingOrderParams{
+		Username:        fromAccount.Owner,
+		FromAccountID:   fromAccount.ID,
+		ToAccountID:     toAccount.ID,
+		Amount:          util.RandomMoney(),
+		Currency:        fromAccount.Currency,
+		Frequency:       util.WeeklyFrequency,
+		RepeatEvery:     1,
+		StartAt:         startAt,
+		NextExecutionAt: sql.NullTime{Time: startAt, Valid: true},
+	}
+}
+
+func createRandomStandingOrder(t *testing.T, arg CreateStandingOrderParams) StandingOrder {
+	standingOrder, err := testQueries.CreateStandingOrder(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, standingOrder.ID)
+	require.Equal(t, arg.Username, standingOrder.Username)
+	require.Equal(t, arg.FromAccountID, standingOrder.FromAccountID)
+	require.Equal(t, arg.ToAccountID, standingOrder.ToAccountID)
+	require.Equal(t, arg.Amount, standingOrder.Amount)
+	require.Equal(t, arg.Frequency, standingOrder.Frequency)
+	require.Equal(t, arg.RepeatEvery, standingOrder.RepeatEvery)
+	require.Equal(t, arg.DayOfMonth, standingOrder.DayOfMonth)
+	require.Equal(t, arg.MaxOccurrences, standingOrder.MaxOccurrences)
+	require.WithinDuration(t, arg.StartAt, standingOrder.StartAt, time.Second)
+	require.WithinDuration(t, arg.NextExecutionAt.Time, standingOrder.NextExecutionAt.Time, time.Second)
+	require.Zero(t, standingOrder.Occurrences)
+	require.Equal(t, "active", standingOrder.Status)
+
+	return standingOrder
+}
+
+func TestCreateStandingOrder(t *testing.T) {
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+
+	arg := randomStandingOrderParams(account1, account2, time.Now().Add(time.Hour))
+	arg.Frequency = util.MonthlyFrequency
+	arg.DayOfMonth = sql.NullInt32{Int32: 15, Valid: true}
+	arg.MaxOccurrences = sql.NullInt32{Int32: 12, Valid: true}
+	createRandomStandingOrder(t, arg)
+}
+
+func TestPauseStandingOrder(t *testing.T) {
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	standingOrder := createRandomStandingOrder(t, randomStandingOrderParams(account1, account2, time.Now().Add(time.Hour)))
+
+	arg := PauseStandingOrderParams{
+		ID:       standingOrder.ID,
+		Username: account1.Owner,
+	}
+	paused, err := testQueries.PauseStandingOrder(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, "paused", paused.Status)
+	require.Equal(t, standingOrder.NextExecutionAt.Time, paused.NextExecutionAt.Time)
+
+	_, err = testQueries.PauseStandingOrder(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCancelStandingOrder(t *testing.T) {
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	standingOrder := createRandomStandingOrder(t, randomStandingOrderParams(account1, account2, time.Now().Add(time.Hour)))
+
+	// only the user who set up the standing order can cancel it
+	_, err := testQueries.CancelStandingOrder(context.Background(), CancelStandingOrderParams{
+		ID:       standingOrder.ID,
+		Username: account2.Owner,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	arg := CancelStandingOrderParams{
+		ID:       standingOrder.ID,
+		Username: account1.Owner,
+	}
+	canceled, err := testQueries.CancelStandingOrder(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, "canceled", canceled.Status)
+	require.False(t, canceled.NextExecutionAt.Valid)
+
+	_, err = testQueries.CancelStandingOrder(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestListStandingOrders(t *testing.T) {
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	for i := 0; i < 6; i++ {
+		createRandomStandingOrder(t, randomStandingOrderParams(account1, account2, time.Now().Add(time.Hour)))
+	}
+
+	standingOrders, err := testQueries.ListStandingOrders(context.Background(), ListStandingOrdersParams{
+		Username: account1.Owner,
+		Limit:    5,
+		Offset:   0,
+	})
+	require.NoError(t, err)
+	require.Len(t, standingOrders, 5)
+
+	for _, standingOrder := range standingOrders {
+		require.Equal(t, account1.Owner, standingOrder.Username)
+	}
+}
@@ -3,12 +3,34 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/JMustang/OldBank/token"
+	"github.com/JMustang/OldBank/util"
+	"github.com/google/uuid"
 )
 
 type Store interface {
 	Querier
 	TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error)
+	CrossCurrencyTransferTx(ctx context.Context, arg CrossCurrencyTransferTxParams) (CrossCurrencyTransferTxResult, error)
+	EnableTotpTx(ctx context.Context, arg EnableTotpTxParams) (EnableTotpTxResult, error)
+	CreatePasswordResetTx(ctx context.Context, arg CreatePasswordResetTxParams) (CreatePasswordResetTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error)
+	CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error)
+	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error)
+	UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error)
+	CreateSessionTx(ctx context.Context, arg CreateSessionTxParams) (CreateSessionTxResult, error)
+	CreateWebauthnCredentialTx(ctx context.Context, arg CreateWebauthnCredentialTxParams) (CreateWebauthnCredentialTxResult, error)
+	CreateTransferChallengeTx(ctx context.Context, arg CreateTransferChallengeTxParams) (CreateTransferChallengeTxResult, error)
+	ConfirmTransferChallengeTx(ctx context.Context, arg ConfirmTransferChallengeTxParams) (ConfirmTransferChallengeTxResult, error)
+	ExecuteScheduledTransferTx(ctx context.Context) (ExecuteScheduledTransferTxResult, error)
+	GenerateStandingOrderRunTx(ctx context.Context) (GenerateStandingOrderRunTxResult, error)
+	ResumeStandingOrderTx(ctx context.Context, arg ResumeStandingOrderTxParams) (ResumeStandingOrderTxResult, error)
 }
 
 type SQLStore struct {
@@ -45,6 +67,17 @@ type TransferTxParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	// IdempotencyKey, if set, records the result of the transfer under the key,
+	// so that the retries of the request can replay it instead of moving the money again
+	IdempotencyKey *IdempotencyKeyParams `json:"-"`
+}
+
+// IdempotencyKeyParams identify the request of a transfer retried by the client
+type IdempotencyKeyParams struct {
+	Username    string
+	Key         string
+	RequestHash string
+	ExpiresAt   time.Time
 }
 
 type TransferTxResult struct {
@@ -55,49 +88,196 @@ type TransferTxResult struct {
 	ToEntry     Entry    `json:"to_entry"`
 }
 
+// TransferTx moves money between two accounts. If the transfer has an idempotency key,
+// it returns ErrIdempotencyKeyInUse and rolls back if the key already recorded a transfer.
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
-
-		result.Transfer, err = q.CreateTransfer(ctx, CreateTransferParams{
-			FromAccountID: arg.FromAccountID,
-			ToAccountID:   arg.ToAccountID,
-			Amount:        arg.Amount,
-		})
+		result, err = transfer(ctx, q, arg)
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.FromAccountID,
-			Amount:    -arg.Amount,
-		})
-		if err != nil {
-			return err
+		if arg.IdempotencyKey != nil {
+			return recordIdempotencyKey(ctx, q, *arg.IdempotencyKey, result, uuid.NullUUID{})
 		}
+		return nil
+	})
 
-		result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
-			AccountID: arg.ToAccountID,
-			Amount:    arg.Amount,
-		})
+	return result, err
+}
+
+type CrossCurrencyTransferTxParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	ToAccountID   int64     `json:"to_account_id"`
+	Amount        int64     `json:"amount"`
+	QuoteID       uuid.UUID `json:"quote_id"`
+	// Username is the user the quote was given to
+	Username       string                `json:"username"`
+	IdempotencyKey *IdempotencyKeyParams `json:"-"`
+}
+
+type CrossCurrencyTransferTxResult struct {
+	TransferTxResult
+	Quote FxQuote `json:"quote"`
+}
+
+// CrossCurrencyTransferTx moves money between accounts of different currencies at the rate locked by a quote,
+// which cannot be used again. It returns ErrRecordNotFound if the quote is unknown, expired or already used.
+func (store *SQLStore) CrossCurrencyTransferTx(ctx context.Context, arg CrossCurrencyTransferTxParams) (CrossCurrencyTransferTxResult, error) {
+	var result CrossCurrencyTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.TransferTxResult, result.Quote, err = crossCurrencyTransfer(ctx, q, arg)
 		if err != nil {
 			return err
 		}
 
-		if arg.FromAccountID < arg.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.Amount)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.Amount, arg.FromAccountID, -arg.Amount)
+		if arg.IdempotencyKey != nil {
+			return recordIdempotencyKey(ctx, q, *arg.IdempotencyKey, result.TransferTxResult, uuid.NullUUID{})
 		}
-
 		return nil
 	})
 
 	return result, err
 }
 
+// recordIdempotencyKey records the result of the transfer, or its challenge if it awaits step-up,
+// under its idempotency key. Concurrent transfers with the same key wait for each other, and all but the first fail.
+func recordIdempotencyKey(ctx context.Context, q *Queries, arg IdempotencyKeyParams, result any, transferChallengeID uuid.NullUUID) error {
+	response, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateIdempotencyKey(ctx, CreateIdempotencyKeyParams{
+		Username:            arg.Username,
+		Key:                 arg.Key,
+		RequestHash:         arg.RequestHash,
+		Response:            response,
+		ExpiresAt:           arg.ExpiresAt,
+		TransferChallengeID: transferChallengeID,
+	})
+	if err == sql.ErrNoRows {
+		// the key of an unexpired transfer is not replaced
+		return ErrIdempotencyKeyInUse
+	}
+	return err
+}
+
+// transfer moves money between two accounts of the same currency, within the database transaction of q.
+// It returns an InsufficientFundsError if the sender cannot go that far below zero.
+func transfer(ctx context.Context, q *Queries, arg TransferTxParams) (TransferTxResult, error) {
+	return moveMoney(ctx, q, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      arg.Amount,
+	})
+}
+
+// crossCurrencyTransfer moves money between two accounts of different currencies, converting the amount
+// at the rate of the quote. It returns ErrRecordNotFound if the quote is unknown, expired or already used,
+// and ErrZeroConvertedAmount if the converted amount rounds down to zero.
+func crossCurrencyTransfer(ctx context.Context, q *Queries, arg CrossCurrencyTransferTxParams) (TransferTxResult, FxQuote, error) {
+	quote, err := q.UseFxQuote(ctx, UseFxQuoteParams{
+		ID:       arg.QuoteID,
+		Username: arg.Username,
+	})
+	if err != nil {
+		return TransferTxResult{}, quote, err
+	}
+
+	toAmount, err := util.ConvertAmount(arg.Amount, quote.Rate)
+	if err != nil {
+		return TransferTxResult{}, quote, err
+	}
+	if toAmount <= 0 {
+		// the sender would be debited for nothing
+		return TransferTxResult{}, quote, ErrZeroConvertedAmount
+	}
+
+	result, err := moveMoney(ctx, q, CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount,
+		ToAmount:      toAmount,
+		FxRate:        sql.NullInt64{Int64: quote.Rate, Valid: true},
+		FxSpreadBps:   sql.NullInt32{Int32: quote.SpreadBps, Valid: true},
+		FxQuoteID:     uuid.NullUUID{UUID: quote.ID, Valid: true},
+	})
+	return result, quote, err
+}
+
+// moveMoney records the transfer, debiting its amount from the sender and crediting its to amount to the recipient.
+func moveMoney(ctx context.Context, q *Queries, arg CreateTransferParams) (TransferTxResult, error) {
+	var result TransferTxResult
+
+	fromAccount, err := lockAccounts(ctx, q, arg.FromAccountID, arg.ToAccountID)
+	if err != nil {
+		return result, err
+	}
+	if fromAccount.Balance-arg.Amount < -fromAccount.OverdraftLimit {
+		return result, &InsufficientFundsError{
+			AccountID:      fromAccount.ID,
+			Balance:        fromAccount.Balance,
+			OverdraftLimit: fromAccount.OverdraftLimit,
+			Amount:         arg.Amount,
+		}
+	}
+
+	result.Transfer, err = q.CreateTransfer(ctx, arg)
+	if err != nil {
+		return result, err
+	}
+
+	result.FromEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.FromAccountID,
+		Amount:    -arg.Amount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, CreateEntryParams{
+		AccountID: arg.ToAccountID,
+		Amount:    arg.ToAmount,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	if arg.FromAccountID < arg.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(ctx, q, arg.FromAccountID, -arg.Amount, arg.ToAccountID, arg.ToAmount)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, arg.ToAccountID, arg.ToAmount, arg.FromAccountID, -arg.Amount)
+	}
+
+	return result, err
+}
+
+// lockAccounts locks both accounts of a transfer until the end of the transaction and returns the sender.
+// They are locked in the same order as addMoney updates them, so that concurrent transfers cannot deadlock.
+func lockAccounts(ctx context.Context, q *Queries, fromAccountID int64, toAccountID int64) (Account, error) {
+	if fromAccountID < toAccountID {
+		fromAccount, err := q.GetAccountForUpdate(ctx, fromAccountID)
+		if err != nil {
+			return fromAccount, err
+		}
+		_, err = q.GetAccountForUpdate(ctx, toAccountID)
+		return fromAccount, err
+	}
+
+	_, err := q.GetAccountForUpdate(ctx, toAccountID)
+	if err != nil {
+		return Account{}, err
+	}
+	return q.GetAccountForUpdate(ctx, fromAccountID)
+}
+
 func addMoney(
 	ctx context.Context,
 	q *Queries,
@@ -120,3 +300,664 @@ func addMoney(
 	})
 	return
 }
+
+type EnableTotpTxParams struct {
+	Username            string   `json:"username"`
+	HashedRecoveryCodes []string `json:"hashed_recovery_codes"`
+}
+
+type EnableTotpTxResult struct {
+	TotpSecret    TotpSecret     `json:"totp_secret"`
+	RecoveryCodes []RecoveryCode `json:"recovery_codes"`
+}
+
+// EnableTotpTx enables the TOTP secret of a user and stores its recovery codes within a single database transaction
+func (store *SQLStore) EnableTotpTx(ctx context.Context, arg EnableTotpTxParams) (EnableTotpTxResult, error) {
+	var result EnableTotpTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.TotpSecret, err = q.EnableTotpSecret(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.RecoveryCodes = make([]RecoveryCode, 0, len(arg.HashedRecoveryCodes))
+		for _, hashedCode := range arg.HashedRecoveryCodes {
+			recoveryCode, err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+				Username:   arg.Username,
+				HashedCode: hashedCode,
+			})
+			if err != nil {
+				return err
+			}
+			result.RecoveryCodes = append(result.RecoveryCodes, recoveryCode)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+type CreatePasswordResetTxParams struct {
+	CreatePasswordResetParams
+	Email CreateOutboxEmailParams `json:"email"`
+}
+
+type CreatePasswordResetTxResult struct {
+	PasswordReset PasswordReset `json:"password_reset"`
+	Email         EmailOutbox   `json:"email"`
+}
+
+// CreatePasswordResetTx stores a password reset along with the email delivering its token,
+// so that the email is sent if and only if the reset exists
+func (store *SQLStore) CreatePasswordResetTx(ctx context.Context, arg CreatePasswordResetTxParams) (CreatePasswordResetTxResult, error) {
+	var result CreatePasswordResetTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.PasswordReset, err = q.CreatePasswordReset(ctx, arg.CreatePasswordResetParams)
+		if err != nil {
+			return err
+		}
+
+		result.Email, err = q.CreateOutboxEmail(ctx, arg.Email)
+		return err
+	})
+
+	return result, err
+}
+
+type ResetPasswordTxParams struct {
+	HashedToken    string `json:"hashed_token"`
+	HashedPassword string `json:"hashed_password"`
+	// RevokeTokens revokes the tokens of the user issued before the password change, with the
+	// revocation store of the transaction, so that the new password is never committed without it
+	RevokeTokens func(ctx context.Context, revocationStore token.RevocationStore, username string) error `json:"-"`
+}
+
+type ResetPasswordTxResult struct {
+	PasswordReset PasswordReset `json:"password_reset"`
+	User          User          `json:"user"`
+}
+
+// ResetPasswordTx consumes a password reset token and changes the password of its user.
+// Other pending resets, all sessions and all API keys of the user are cancelled, and its tokens are revoked.
+// It returns ErrRecordNotFound if the token is unknown, expired or already used.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (ResetPasswordTxResult, error) {
+	var result ResetPasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.PasswordReset, err = q.UsePasswordReset(ctx, arg.HashedToken)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:       result.PasswordReset.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CancelPasswordResets(ctx, result.User.Username)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.BlockUserSessions(ctx, result.User.Username)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.DeleteUserApiKeys(ctx, result.User.Username)
+		if err != nil {
+			return err
+		}
+
+		return arg.RevokeTokens(ctx, NewRevocationStore(q), result.User.Username)
+	})
+
+	return result, err
+}
+
+type CreateUserTxParams struct {
+	CreateUserParams
+	HashedSecretCode string    `json:"hashed_secret_code"`
+	ExpiredAt        time.Time `json:"expired_at"`
+	// VerificationEmail builds the email delivering the secret code of the verify email
+	VerificationEmail func(verifyEmail VerifyEmail) CreateOutboxEmailParams `json:"-"`
+}
+
+type CreateUserTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+	Email       EmailOutbox `json:"email"`
+}
+
+// CreateUserTx creates a user along with the verify email of its address and the email delivering its secret code
+func (store *SQLStore) CreateUserTx(ctx context.Context, arg CreateUserTxParams) (CreateUserTxResult, error) {
+	var result CreateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		if err != nil {
+			return err
+		}
+
+		result.VerifyEmail, err = q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:         result.User.Username,
+			Email:            result.User.Email,
+			HashedSecretCode: arg.HashedSecretCode,
+			ExpiredAt:        arg.ExpiredAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Email, err = q.CreateOutboxEmail(ctx, arg.VerificationEmail(result.VerifyEmail))
+		return err
+	})
+
+	return result, err
+}
+
+type VerifyEmailTxParams struct {
+	EmailID          int64  `json:"email_id"`
+	HashedSecretCode string `json:"hashed_secret_code"`
+}
+
+type VerifyEmailTxResult struct {
+	User        User        `json:"user"`
+	VerifyEmail VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmailTx consumes the secret code of a verify email and marks the address of its user as verified.
+// It returns ErrRecordNotFound if the code is wrong, expired or already used,
+// or if the user has changed its email address since.
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxParams) (VerifyEmailTxResult, error) {
+	var result VerifyEmailTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.VerifyEmail, err = q.UseVerifyEmail(ctx, UseVerifyEmailParams{
+			ID:               arg.EmailID,
+			HashedSecretCode: arg.HashedSecretCode,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.VerifyUserEmail(ctx, VerifyUserEmailParams{
+			Username: result.VerifyEmail.Username,
+			Email:    result.VerifyEmail.Email,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+type UpdateUserTxParams struct {
+	UpdateUserParams
+	HashedSecretCode string    `json:"hashed_secret_code"`
+	ExpiredAt        time.Time `json:"expired_at"`
+	// VerificationEmail builds the email delivering the secret code of the verify email of a new address
+	VerificationEmail func(user User, verifyEmail VerifyEmail) CreateOutboxEmailParams `json:"-"`
+	// EmailChangedEmail builds the email telling the previous address of the user that it was replaced
+	EmailChangedEmail func(oldUser User, user User) CreateOutboxEmailParams `json:"-"`
+}
+
+type UpdateUserTxResult struct {
+	User User `json:"user"`
+	// VerifyEmail and Email are only set if a new email address must be verified
+	VerifyEmail *VerifyEmail `json:"verify_email,omitempty"`
+	Email       *EmailOutbox `json:"email,omitempty"`
+	// EmailChangedEmail is only set if the email address was replaced
+	EmailChangedEmail *EmailOutbox `json:"email_changed_email,omitempty"`
+}
+
+// UpdateUserTx updates the profile of a user. If the email address is changed, it is marked
+// as unverified and a verify email is created along with the email delivering its secret code,
+// and the previous address is told about the change.
+func (store *SQLStore) UpdateUserTx(ctx context.Context, arg UpdateUserTxParams) (UpdateUserTxResult, error) {
+	var result UpdateUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		oldUser, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, arg.UpdateUserParams)
+		if err != nil {
+			return err
+		}
+
+		if !arg.Email.Valid {
+			return nil
+		}
+
+		if result.User.Email != oldUser.Email {
+			emailChangedEmail, err := q.CreateOutboxEmail(ctx, arg.EmailChangedEmail(oldUser, result.User))
+			if err != nil {
+				return err
+			}
+			result.EmailChangedEmail = &emailChangedEmail
+		}
+
+		if result.User.IsEmailVerified {
+			return nil
+		}
+
+		verifyEmail, err := q.CreateVerifyEmail(ctx, CreateVerifyEmailParams{
+			Username:         result.User.Username,
+			Email:            result.User.Email,
+			HashedSecretCode: arg.HashedSecretCode,
+			ExpiredAt:        arg.ExpiredAt,
+		})
+		if err != nil {
+			return err
+		}
+		result.VerifyEmail = &verifyEmail
+
+		email, err := q.CreateOutboxEmail(ctx, arg.VerificationEmail(result.User, verifyEmail))
+		if err != nil {
+			return err
+		}
+		result.Email = &email
+		return nil
+	})
+
+	return result, err
+}
+
+type ChangePasswordTxParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"hashed_password"`
+	// RevokeTokens revokes the tokens of the user issued before the password change, with the
+	// revocation store of the transaction, so that the new password is never committed without it
+	RevokeTokens func(ctx context.Context, revocationStore token.RevocationStore, username string) error `json:"-"`
+}
+
+type ChangePasswordTxResult struct {
+	User User `json:"user"`
+}
+
+// ChangePasswordTx changes the password of a user and bumps its PasswordChangedAt.
+// Pending password resets, all sessions and all API keys of the user are cancelled, and its tokens are revoked.
+func (store *SQLStore) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxParams) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.User, err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			Username:       arg.Username,
+			HashedPassword: arg.HashedPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = q.CancelPasswordResets(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.BlockUserSessions(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.DeleteUserApiKeys(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		return arg.RevokeTokens(ctx, NewRevocationStore(q), arg.Username)
+	})
+
+	return result, err
+}
+
+type CreateSessionTxParams struct {
+	CreateSessionParams
+	// NewDeviceEmail builds the email warning the user of a login from a user agent it never logged in with
+	NewDeviceEmail func(session Session) CreateOutboxEmailParams `json:"-"`
+}
+
+type CreateSessionTxResult struct {
+	Session Session `json:"session"`
+	// Email is only set if the login came from a new device
+	Email *EmailOutbox `json:"email,omitempty"`
+}
+
+// CreateSessionTx creates the session of a login. If the user has logged in before,
+// but never with this user agent, the email built by NewDeviceEmail is queued.
+func (store *SQLStore) CreateSessionTx(ctx context.Context, arg CreateSessionTxParams) (CreateSessionTxResult, error) {
+	var result CreateSessionTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		history, err := q.CountUserAgentSessions(ctx, CountUserAgentSessionsParams{
+			Username:  arg.Username,
+			UserAgent: arg.UserAgent,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.Session, err = q.CreateSession(ctx, arg.CreateSessionParams)
+		if err != nil {
+			return err
+		}
+
+		if history.SessionCount == 0 || history.UserAgentSessionCount > 0 {
+			return nil
+		}
+
+		email, err := q.CreateOutboxEmail(ctx, arg.NewDeviceEmail(result.Session))
+		if err != nil {
+			return err
+		}
+		result.Email = &email
+		return nil
+	})
+
+	return result, err
+}
+
+type CreateWebauthnCredentialTxParams struct {
+	CreateWebauthnCredentialParams
+	// NewPasskeyEmail builds the email telling the user that a passkey was added to the account
+	NewPasskeyEmail func(user User, credential WebauthnCredential) CreateOutboxEmailParams `json:"-"`
+}
+
+type CreateWebauthnCredentialTxResult struct {
+	Credential WebauthnCredential `json:"credential"`
+	Email      EmailOutbox        `json:"email"`
+}
+
+// CreateWebauthnCredentialTx stores a new passkey of a user along with the email built by NewPasskeyEmail,
+// so that the user always hears of a passkey that can log into the account
+func (store *SQLStore) CreateWebauthnCredentialTx(ctx context.Context, arg CreateWebauthnCredentialTxParams) (CreateWebauthnCredentialTxResult, error) {
+	var result CreateWebauthnCredentialTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+
+		result.Credential, err = q.CreateWebauthnCredential(ctx, arg.CreateWebauthnCredentialParams)
+		if err != nil {
+			return err
+		}
+
+		user, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result.Email, err = q.CreateOutboxEmail(ctx, arg.NewPasskeyEmail(user, result.Credential))
+		return err
+	})
+
+	return result, err
+}
+
+type CreateTransferChallengeTxParams struct {
+	CreateTransferChallengeParams
+	IdempotencyKey *IdempotencyKeyParams `json:"-"`
+}
+
+type CreateTransferChallengeTxResult struct {
+	TransferChallenge TransferChallenge `json:"transfer_challenge"`
+}
+
+// CreateTransferChallengeTx parks a transfer awaiting step-up. If the transfer has an idempotency key,
+// the challenge is recorded under it, and it returns ErrIdempotencyKeyInUse and rolls back if the key was already used.
+func (store *SQLStore) CreateTransferChallengeTx(ctx context.Context, arg CreateTransferChallengeTxParams) (CreateTransferChallengeTxResult, error) {
+	var result CreateTransferChallengeTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.TransferChallenge, err = q.CreateTransferChallenge(ctx, arg.CreateTransferChallengeParams)
+		if err != nil {
+			return err
+		}
+
+		if arg.IdempotencyKey != nil {
+			challengeID := uuid.NullUUID{UUID: result.TransferChallenge.ID, Valid: true}
+			return recordIdempotencyKey(ctx, q, *arg.IdempotencyKey, result.TransferChallenge, challengeID)
+		}
+		return nil
+	})
+
+	return result, err
+}
+
+type ConfirmTransferChallengeTxParams struct {
+	ID       uuid.UUID `json:"id"`
+	Username string    `json:"username"`
+}
+
+type ConfirmTransferChallengeTxResult struct {
+	TransferTxResult
+	TransferChallenge TransferChallenge `json:"transfer_challenge"`
+}
+
+// ConfirmTransferChallengeTx confirms a transfer challenge of the user and executes its transfer.
+// It returns ErrRecordNotFound if the challenge is unknown, expired or already confirmed,
+// or if the quote of a cross-currency transfer has expired.
+func (store *SQLStore) ConfirmTransferChallengeTx(ctx context.Context, arg ConfirmTransferChallengeTxParams) (ConfirmTransferChallengeTxResult, error) {
+	var result ConfirmTransferChallengeTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		challenge, err := q.ConfirmTransferChallenge(ctx, ConfirmTransferChallengeParams{
+			ID:       arg.ID,
+			Username: arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		if challenge.FxQuoteID.Valid {
+			result.TransferTxResult, _, err = crossCurrencyTransfer(ctx, q, CrossCurrencyTransferTxParams{
+				FromAccountID: challenge.FromAccountID,
+				ToAccountID:   challenge.ToAccountID,
+				Amount:        challenge.Amount,
+				QuoteID:       challenge.FxQuoteID.UUID,
+				Username:      challenge.Username,
+			})
+		} else {
+			result.TransferTxResult, err = transfer(ctx, q, TransferTxParams{
+				FromAccountID: challenge.FromAccountID,
+				ToAccountID:   challenge.ToAccountID,
+				Amount:        challenge.Amount,
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		result.TransferChallenge, err = q.SetTransferChallengeTransfer(ctx, SetTransferChallengeTransferParams{
+			ID:         challenge.ID,
+			TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+		})
+		return err
+	})
+
+	return result, err
+}
+
+type ExecuteScheduledTransferTxResult struct {
+	TransferTxResult
+	ScheduledTransfer ScheduledTransfer `json:"scheduled_transfer"`
+}
+
+// ExecuteScheduledTransferTx claims the next due scheduled transfer and executes it. Rows claimed by
+// concurrent executors are skipped, so that several instances can execute scheduled transfers at once.
+// A transfer the sender cannot fund is recorded as failed. It returns ErrRecordNotFound if no transfer is due.
+// On any other error, the transaction is rolled back and the result still holds the claimed scheduled transfer.
+func (store *SQLStore) ExecuteScheduledTransferTx(ctx context.Context) (ExecuteScheduledTransferTxResult, error) {
+	var result ExecuteScheduledTransferTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		scheduledTransfer, err := q.ClaimDueScheduledTransfer(ctx)
+		if err != nil {
+			return err
+		}
+		result.ScheduledTransfer = scheduledTransfer
+
+		result.TransferTxResult, err = transfer(ctx, q, TransferTxParams{
+			FromAccountID: scheduledTransfer.FromAccountID,
+			ToAccountID:   scheduledTransfer.ToAccountID,
+			Amount:        scheduledTransfer.Amount,
+		})
+		var insufficientFunds *InsufficientFundsError
+		if errors.As(err, &insufficientFunds) {
+			scheduledTransfer, err = q.FailScheduledTransfer(ctx, FailScheduledTransferParams{
+				ID:            scheduledTransfer.ID,
+				FailureReason: sql.NullString{String: insufficientFunds.Error(), Valid: true},
+			})
+		} else if err == nil {
+			scheduledTransfer, err = q.CompleteScheduledTransfer(ctx, CompleteScheduledTransferParams{
+				ID:         scheduledTransfer.ID,
+				TransferID: sql.NullInt64{Int64: result.Transfer.ID, Valid: true},
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		result.ScheduledTransfer = scheduledTransfer
+		return nil
+	})
+
+	return result, err
+}
+
+const (
+	standingOrderStatusActive    = "active"
+	standingOrderStatusPaused    = "paused"
+	standingOrderStatusCompleted = "completed"
+)
+
+// nextStandingOrderExecution returns the first occurrence of the standing order after the given time,
+// or null if the standing order has reached its end date or its maximum number of occurrences.
+// Calendar rules are evaluated in UTC.
+func nextStandingOrderExecution(order StandingOrder, after time.Time) sql.NullTime {
+	if order.MaxOccurrences.Valid && order.Occurrences >= order.MaxOccurrences.Int32 {
+		return sql.NullTime{}
+	}
+
+	recurrence := util.Recurrence{
+		Frequency:  order.Frequency,
+		Interval:   int(order.RepeatEvery),
+		DayOfMonth: int(order.DayOfMonth.Int32),
+	}
+	next := recurrence.Next(order.StartAt.UTC(), after.UTC())
+	if order.EndAt.Valid && next.After(order.EndAt.Time) {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: next, Valid: true}
+}
+
+type GenerateStandingOrderRunTxResult struct {
+	StandingOrder StandingOrder     `json:"standing_order"`
+	Run           ScheduledTransfer `json:"run"`
+}
+
+// GenerateStandingOrderRunTx claims the next due standing order and schedules its run as a transfer due immediately,
+// which keeps the history of the runs of the standing order. The standing order is then advanced to its next occurrence,
+// or completed if it has none. Rows claimed by concurrent generators are skipped.
+// It returns ErrRecordNotFound if no standing order is due.
+func (store *SQLStore) GenerateStandingOrderRunTx(ctx context.Context) (GenerateStandingOrderRunTxResult, error) {
+	var result GenerateStandingOrderRunTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		order, err := q.ClaimDueStandingOrder(ctx)
+		if err != nil {
+			return err
+		}
+
+		result.Run, err = q.CreateScheduledTransfer(ctx, CreateScheduledTransferParams{
+			Username:        order.Username,
+			FromAccountID:   order.FromAccountID,
+			ToAccountID:     order.ToAccountID,
+			Amount:          order.Amount,
+			Currency:        order.Currency,
+			ExecuteAt:       order.NextExecutionAt.Time,
+			StandingOrderID: sql.NullInt64{Int64: order.ID, Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		order.Occurrences++
+		next := nextStandingOrderExecution(order, order.NextExecutionAt.Time)
+		status := standingOrderStatusActive
+		if !next.Valid {
+			status = standingOrderStatusCompleted
+		}
+
+		result.StandingOrder, err = q.UpdateStandingOrderSchedule(ctx, UpdateStandingOrderScheduleParams{
+			ID:              order.ID,
+			Status:          status,
+			Occurrences:     order.Occurrences,
+			NextExecutionAt: next,
+		})
+		return err
+	})
+
+	return result, err
+}
+
+type ResumeStandingOrderTxParams struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type ResumeStandingOrderTxResult struct {
+	StandingOrder StandingOrder `json:"standing_order"`
+}
+
+// ResumeStandingOrderTx resumes a paused standing order of the user from its next occurrence,
+// skipping the ones missed while it was paused. The standing order is completed if it has no occurrence left.
+// It returns ErrRecordNotFound if the standing order is unknown or not paused.
+func (store *SQLStore) ResumeStandingOrderTx(ctx context.Context, arg ResumeStandingOrderTxParams) (ResumeStandingOrderTxResult, error) {
+	var result ResumeStandingOrderTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		order, err := q.GetStandingOrderForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+		if order.Username != arg.Username || order.Status != standingOrderStatusPaused {
+			return ErrRecordNotFound
+		}
+
+		next := nextStandingOrderExecution(order, time.Now())
+		status := standingOrderStatusActive
+		if !next.Valid {
+			status = standingOrderStatusCompleted
+		}
+
+		result.StandingOrder, err = q.UpdateStandingOrderSchedule(ctx, UpdateStandingOrderScheduleParams{
+			ID:              order.ID,
+			Status:          status,
+			Occurrences:     order.Occurrences,
+			NextExecutionAt: next,
+		})
+		return err
+	})
+
+	return result, err
+}
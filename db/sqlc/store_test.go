@@ -2,22 +2,37 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
 	"fmt"
 	"testing"
+	"time"
 
+	"github.com/JMustang/OldBank/util"
 	"github.com/stretchr/testify/require"
 )
 
+// fundAccount sets the balance of the account, so that its transfers do not fail for insufficient funds
+func fundAccount(t *testing.T, account Account, balance int64) Account {
+	account, err := testQueries.UpdateAccount(context.Background(), UpdateAccountParams{
+		ID:      account.ID,
+		Balance: balance,
+	})
+	require.NoError(t, err)
+	return account
+}
+
 func TestTransferTx(t *testing.T) {
 	store := NewStore(testDB)
 
 	account1 := createRandomAccount(t)
 	account2 := createRandomAccount(t)
-	fmt.Println(">> before:", account1.account.Balance, account2.account.Balance)
 
 	// Run n concurrent transfer transections
 	n := 5
 	amount := int64(10)
+	account1.account = fundAccount(t, account1.account, account1.account.Balance+int64(n)*amount)
+	fmt.Println(">> before:", account1.account.Balance, account2.account.Balance)
 
 	errs := make(chan error, n)
 	results := make(chan TransferTxResult, n)
@@ -117,11 +132,13 @@ func TestTransferTxDeadlock(t *testing.T) {
 
 	account1 := createRandomAccount(t)
 	account2 := createRandomAccount(t)
-	fmt.Println(">> before:", account1.account.Balance, account2.account.Balance)
 
 	// Run n concurrent transfer transections
 	n := 10
 	amount := int64(10)
+	account1.account = fundAccount(t, account1.account, account1.account.Balance+int64(n)*amount)
+	account2.account = fundAccount(t, account2.account, account2.account.Balance+int64(n)*amount)
+	fmt.Println(">> before:", account1.account.Balance, account2.account.Balance)
 	errs := make(chan error, n)
 
 	for i := 0; i < n; i++ {
@@ -162,3 +179,570 @@ func TestTransferTxDeadlock(t *testing.T) {
 	require.Equal(t, account1.account.Balance, updatedAccount1.Balance)
 	require.Equal(t, account2.account.Balance, updatedAccount2.Balance)
 }
+
+func TestTransferTxIdempotencyKey(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+
+	// Run n concurrent retries of the same transfer
+	n := 5
+	amount := int64(10)
+	account1.account = fundAccount(t, account1.account, account1.account.Balance+amount)
+	key := IdempotencyKeyParams{
+		Username:    account1.account.Owner,
+		Key:         util.RandomString(32),
+		RequestHash: util.RandomString(64),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	errs := make(chan error, n)
+	results := make(chan TransferTxResult, n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID:  account1.account.ID,
+				ToAccountID:    account2.account.ID,
+				Amount:         amount,
+				IdempotencyKey: &key,
+			})
+
+			errs <- err
+			results <- result
+		}()
+	}
+
+	// Only one of the retries moves the money
+	var transfer Transfer
+	for i := 0; i < n; i++ {
+		err := <-errs
+		result := <-results
+		if err != nil {
+			require.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+			continue
+		}
+		require.Zero(t, transfer.ID)
+		transfer = result.Transfer
+	}
+	require.NotZero(t, transfer.ID)
+
+	record, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key.RequestHash, record.RequestHash)
+
+	var recorded TransferTxResult
+	err = json.Unmarshal(record.Response, &recorded)
+	require.NoError(t, err)
+	require.Equal(t, transfer.ID, recorded.Transfer.ID)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.account.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.account.Balance-amount, updatedAccount1.Balance)
+}
+
+func TestTransferTxExpiredIdempotencyKey(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t)
+	account2 := createRandomAccount(t)
+	account1.account = fundAccount(t, account1.account, account1.account.Balance+20)
+	key := IdempotencyKeyParams{
+		Username:    account1.account.Owner,
+		Key:         util.RandomString(32),
+		RequestHash: util.RandomString(64),
+		ExpiresAt:   time.Now().Add(-time.Second),
+	}
+
+	arg := TransferTxParams{
+		FromAccountID:  account1.account.ID,
+		ToAccountID:    account2.account.ID,
+		Amount:         10,
+		IdempotencyKey: &key,
+	}
+	_, err := store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	// the key can be reused once expired
+	key.ExpiresAt = time.Now().Add(time.Hour)
+	_, err = store.TransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	_, err = store.TransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+}
+
+func TestTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, createRandomAccount(t).account, 100)
+	account2 := createRandomAccount(t).account
+
+	_, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        101,
+	})
+	var insufficientFunds *InsufficientFundsError
+	require.ErrorAs(t, err, &insufficientFunds)
+	require.Equal(t, account1.ID, insufficientFunds.AccountID)
+	require.Equal(t, int64(100), insufficientFunds.Balance)
+	require.Equal(t, int64(101), insufficientFunds.Amount)
+
+	// the whole balance can be transferred
+	result, err := store.TransferTx(context.Background(), TransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+	})
+	require.NoError(t, err)
+	require.Zero(t, result.FromAccount.Balance)
+}
+
+func TestTransferTxOverdraftLimit(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, createRandomAccount(t).account, 0)
+	account2 := createRandomAccount(t).account
+
+	account1, err := testQueries.UpdateAccountOverdraftLimit(context.Background(), UpdateAccountOverdraftLimitParams{
+		ID:             account1.ID,
+		OverdraftLimit: 50,
+	})
+	require.NoError(t, err)
+
+	// Run n concurrent transfers, of which only the ones within the overdraft limit succeed
+	n := 10
+	amount := int64(10)
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err := store.TransferTx(context.Background(), TransferTxParams{
+				FromAccountID: account1.ID,
+				ToAccountID:   account2.ID,
+				Amount:        amount,
+			})
+			errs <- err
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		if err == nil {
+			succeeded++
+			continue
+		}
+		var insufficientFunds *InsufficientFundsError
+		require.ErrorAs(t, err, &insufficientFunds)
+	}
+	require.Equal(t, 5, succeeded)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-50), updatedAccount1.Balance)
+}
+
+func TestCrossCurrencyTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  1000,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	account2, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+
+	fxRate := createRandomFxRate(t, util.USD, util.EUR, time.Now())
+	quote := createRandomFxQuote(t, user.Username, fxRate, time.Now().Add(time.Minute))
+
+	amount := int64(100)
+	toAmount, err := util.ConvertAmount(amount, quote.Rate)
+	require.NoError(t, err)
+
+	arg := CrossCurrencyTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+		QuoteID:       quote.ID,
+		Username:      user.Username,
+	}
+
+	result, err := store.CrossCurrencyTransferTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	transfer := result.Transfer
+	require.Equal(t, amount, transfer.Amount)
+	require.Equal(t, toAmount, transfer.ToAmount)
+	require.Equal(t, quote.Rate, transfer.FxRate.Int64)
+	require.Equal(t, quote.SpreadBps, transfer.FxSpreadBps.Int32)
+	require.Equal(t, quote.ID, transfer.FxQuoteID.UUID)
+
+	require.Equal(t, -amount, result.FromEntry.Amount)
+	require.Equal(t, toAmount, result.ToEntry.Amount)
+	require.Equal(t, account1.Balance-amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+toAmount, result.ToAccount.Balance)
+	require.True(t, result.Quote.UsedAt.Valid)
+
+	// the quote locks its rate for a single transfer
+	_, err = store.CrossCurrencyTransferTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-amount, updatedAccount1.Balance)
+}
+
+func TestCrossCurrencyTransferTxExpiredQuote(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  1000,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	account2, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+
+	fxRate := createRandomFxRate(t, util.USD, util.EUR, time.Now())
+	quote := createRandomFxQuote(t, user.Username, fxRate, time.Now().Add(-time.Second))
+
+	_, err = store.CrossCurrencyTransferTx(context.Background(), CrossCurrencyTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        100,
+		QuoteID:       quote.ID,
+		Username:      user.Username,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestCrossCurrencyTransferTxZeroConvertedAmount(t *testing.T) {
+	store := NewStore(testDB)
+
+	user := createRandomUser(t)
+	account1, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    user.Username,
+		Balance:  1000,
+		Currency: util.USD,
+	})
+	require.NoError(t, err)
+	account2, err := testQueries.CreateAccount(context.Background(), CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  0,
+		Currency: util.EUR,
+	})
+	require.NoError(t, err)
+
+	// a single cent converts to nothing at a rate below 1
+	fxRate, err := testQueries.CreateFxRate(context.Background(), CreateFxRateParams{
+		BaseCurrency:  util.USD,
+		QuoteCurrency: util.EUR,
+		Rate:          90_000_000,
+		EffectiveAt:   time.Now(),
+	})
+	require.NoError(t, err)
+	quote := createRandomFxQuote(t, user.Username, fxRate, time.Now().Add(time.Minute))
+
+	_, err = store.CrossCurrencyTransferTx(context.Background(), CrossCurrencyTransferTxParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        1,
+		QuoteID:       quote.ID,
+		Username:      user.Username,
+	})
+	require.ErrorIs(t, err, ErrZeroConvertedAmount)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	// the transaction is rolled back, so the quote can still be used
+	updatedQuote, err := testQueries.GetFxQuote(context.Background(), quote.ID)
+	require.NoError(t, err)
+	require.False(t, updatedQuote.UsedAt.Valid)
+}
+
+// executeDueScheduledTransfers executes scheduled transfers until none is due
+func executeDueScheduledTransfers(t *testing.T, store Store) {
+	for {
+		_, err := store.ExecuteScheduledTransferTx(context.Background())
+		if err == ErrRecordNotFound {
+			return
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestExecuteScheduledTransferTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+
+	due := createRandomScheduledTransfer(t, account1, account2, time.Now().Add(-time.Second))
+	account1 = fundAccount(t, account1, due.Amount)
+	later := createRandomScheduledTransfer(t, account1, account2, time.Now().Add(time.Hour))
+
+	executeDueScheduledTransfers(t, store)
+
+	executed, err := testQueries.GetScheduledTransfer(context.Background(), due.ID)
+	require.NoError(t, err)
+	require.Equal(t, "completed", executed.Status)
+	require.True(t, executed.TransferID.Valid)
+	require.True(t, executed.ExecutedAt.Valid)
+
+	transfer, err := testQueries.GetTransfer(context.Background(), executed.TransferID.Int64)
+	require.NoError(t, err)
+	require.Equal(t, due.FromAccountID, transfer.FromAccountID)
+	require.Equal(t, due.ToAccountID, transfer.ToAccountID)
+	require.Equal(t, due.Amount, transfer.Amount)
+
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount1.Balance)
+
+	// transfers are not executed before they are due
+	pending, err := testQueries.GetScheduledTransfer(context.Background(), later.ID)
+	require.NoError(t, err)
+	require.Equal(t, "pending", pending.Status)
+}
+
+func TestExecuteScheduledTransferTxInsufficientFunds(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := fundAccount(t, createRandomAccount(t).account, 0)
+	account2 := createRandomAccount(t).account
+
+	due := createRandomScheduledTransfer(t, account1, account2, time.Now().Add(-time.Second))
+
+	executeDueScheduledTransfers(t, store)
+
+	executed, err := testQueries.GetScheduledTransfer(context.Background(), due.ID)
+	require.NoError(t, err)
+	require.Equal(t, "failed", executed.Status)
+	require.False(t, executed.TransferID.Valid)
+	require.True(t, executed.FailureReason.Valid)
+	require.Contains(t, executed.FailureReason.String, "insufficient funds")
+
+	updatedAccount2, err := testQueries.GetAccount(context.Background(), account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestExecuteScheduledTransferTxConcurrent(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+
+	n := 10
+	scheduledTransfers := make([]ScheduledTransfer, n)
+	total := int64(0)
+	for i := range scheduledTransfers {
+		scheduledTransfers[i] = createRandomScheduledTransfer(t, account1, account2, time.Now().Add(-time.Second))
+		total += scheduledTransfers[i].Amount
+	}
+	account1 = fundAccount(t, account1, total)
+
+	// concurrent executors, as on several instances, skip the transfers claimed by each other
+	executors := 4
+	errs := make(chan error, executors)
+	for i := 0; i < executors; i++ {
+		go func() {
+			for {
+				_, err := store.ExecuteScheduledTransferTx(context.Background())
+				if err != nil {
+					if err == ErrRecordNotFound {
+						err = nil
+					}
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < executors; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	for _, scheduledTransfer := range scheduledTransfers {
+		executed, err := testQueries.GetScheduledTransfer(context.Background(), scheduledTransfer.ID)
+		require.NoError(t, err)
+		require.Equal(t, "completed", executed.Status)
+	}
+
+	// each transfer was executed exactly once
+	updatedAccount1, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Zero(t, updatedAccount1.Balance)
+}
+
+// generateDueStandingOrderRuns generates the runs of the standing orders until none is due
+func generateDueStandingOrderRuns(t *testing.T, store Store) {
+	for {
+		_, err := store.GenerateStandingOrderRunTx(context.Background())
+		if err == ErrRecordNotFound {
+			return
+		}
+		require.NoError(t, err)
+	}
+}
+
+func standingOrderRuns(t *testing.T, standingOrder StandingOrder) []ScheduledTransfer {
+	runs, err := testQueries.ListStandingOrderRuns(context.Background(), ListStandingOrderRunsParams{
+		StandingOrderID: sql.NullInt64{Int64: standingOrder.ID, Valid: true},
+		Limit:           10,
+		Offset:          0,
+	})
+	require.NoError(t, err)
+	return runs
+}
+
+func TestGenerateStandingOrderRunTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+
+	// weekly occurrences missed for three weeks are caught up
+	startAt := time.Now().Add(-21*24*time.Hour - time.Minute).UTC()
+	standingOrder := createRandomStandingOrder(t, randomStandingOrderParams(account1, account2, startAt))
+
+	generateDueStandingOrderRuns(t, store)
+
+	runs := standingOrderRuns(t, standingOrder)
+	require.Len(t, runs, 4)
+	for i, run := range runs {
+		require.Equal(t, standingOrder.Amount, run.Amount)
+		require.Equal(t, "pending", run.Status)
+		require.WithinDuration(t, startAt.AddDate(0, 0, 7*(len(runs)-1-i)), run.ExecuteAt, time.Second)
+	}
+
+	updated, err := testQueries.GetStandingOrder(context.Background(), standingOrder.ID)
+	require.NoError(t, err)
+	require.Equal(t, "active", updated.Status)
+	require.Equal(t, int32(4), updated.Occurrences)
+	require.WithinDuration(t, startAt.AddDate(0, 0, 28), updated.NextExecutionAt.Time, time.Second)
+
+	// the runs are executed like any scheduled transfer
+	fundAccount(t, account1, 4*standingOrder.Amount)
+	executeDueScheduledTransfers(t, store)
+
+	for _, run := range standingOrderRuns(t, standingOrder) {
+		require.Equal(t, "completed", run.Status)
+		require.True(t, run.TransferID.Valid)
+	}
+}
+
+func TestGenerateStandingOrderRunTxMaxOccurrences(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+
+	arg := randomStandingOrderParams(account1, account2, time.Now().Add(-21*24*time.Hour-time.Minute))
+	arg.MaxOccurrences = sql.NullInt32{Int32: 2, Valid: true}
+	standingOrder := createRandomStandingOrder(t, arg)
+
+	generateDueStandingOrderRuns(t, store)
+
+	require.Len(t, standingOrderRuns(t, standingOrder), 2)
+
+	updated, err := testQueries.GetStandingOrder(context.Background(), standingOrder.ID)
+	require.NoError(t, err)
+	require.Equal(t, "completed", updated.Status)
+	require.Equal(t, int32(2), updated.Occurrences)
+	require.False(t, updated.NextExecutionAt.Valid)
+}
+
+func TestGenerateStandingOrderRunTxEndDate(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+
+	arg := randomStandingOrderParams(account1, account2, time.Now().Add(-21*24*time.Hour-time.Minute))
+	arg.EndAt = sql.NullTime{Time: arg.StartAt.AddDate(0, 0, 8), Valid: true}
+	standingOrder := createRandomStandingOrder(t, arg)
+
+	generateDueStandingOrderRuns(t, store)
+
+	require.Len(t, standingOrderRuns(t, standingOrder), 2)
+
+	updated, err := testQueries.GetStandingOrder(context.Background(), standingOrder.ID)
+	require.NoError(t, err)
+	require.Equal(t, "completed", updated.Status)
+	require.False(t, updated.NextExecutionAt.Valid)
+}
+
+func TestResumeStandingOrderTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+
+	startAt := time.Now().Add(-20 * 24 * time.Hour).UTC()
+	standingOrder := createRandomStandingOrder(t, randomStandingOrderParams(account1, account2, startAt))
+
+	arg := ResumeStandingOrderTxParams{
+		ID:       standingOrder.ID,
+		Username: account1.Owner,
+	}
+
+	// only paused standing orders can be resumed
+	_, err := store.ResumeStandingOrderTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	_, err = testQueries.PauseStandingOrder(context.Background(), PauseStandingOrderParams{
+		ID:       standingOrder.ID,
+		Username: account1.Owner,
+	})
+	require.NoError(t, err)
+
+	_, err = store.ResumeStandingOrderTx(context.Background(), ResumeStandingOrderTxParams{
+		ID:       standingOrder.ID,
+		Username: account2.Owner,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	result, err := store.ResumeStandingOrderTx(context.Background(), arg)
+	require.NoError(t, err)
+
+	// the occurrences missed while paused are skipped
+	resumed := result.StandingOrder
+	require.Equal(t, "active", resumed.Status)
+	require.Zero(t, resumed.Occurrences)
+	require.WithinDuration(t, startAt.AddDate(0, 0, 21), resumed.NextExecutionAt.Time, time.Second)
+
+	generateDueStandingOrderRuns(t, store)
+	require.Empty(t, standingOrderRuns(t, standingOrder))
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomTotpSecret(t *testing.T) TotpSecret {
+	user := createRandomUser(t)
+
+	arg := UpsertTotpSecretParams{
+		Username:        user.Username,
+		EncryptedSecret: []byte(util.RandomString(32)),
+	}
+
+	totpSecret, err := testQueries.UpsertTotpSecret(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, totpSecret)
+
+	require.Equal(t, arg.Username, totpSecret.Username)
+	require.Equal(t, arg.EncryptedSecret, totpSecret.EncryptedSecret)
+	require.False(t, totpSecret.IsEnabled)
+	require.Zero(t, totpSecret.LastUsedStep)
+	require.False(t, totpSecret.EnabledAt.Valid)
+	require.NotZero(t, totpSecret.CreatedAt)
+
+	return totpSecret
+}
+
+func TestUpsertTotpSecret(t *testing.T) {
+	totpSecret1 := createRandomTotpSecret(t)
+
+	arg := UpsertTotpSecretParams{
+		Username:        totpSecret1.Username,
+		EncryptedSecret: []byte(util.RandomString(32)),
+	}
+
+	totpSecret2, err := testQueries.UpsertTotpSecret(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.EncryptedSecret, totpSecret2.EncryptedSecret)
+
+	totpSecret3, err := testQueries.GetTotpSecret(context.Background(), totpSecret1.Username)
+	require.NoError(t, err)
+	require.Equal(t, totpSecret2.EncryptedSecret, totpSecret3.EncryptedSecret)
+}
+
+func TestUpdateTotpLastUsedStep(t *testing.T) {
+	totpSecret := createRandomTotpSecret(t)
+
+	arg := UpdateTotpLastUsedStepParams{
+		Step:     util.RandomInt(1, 1000),
+		Username: totpSecret.Username,
+	}
+
+	rows, err := testQueries.UpdateTotpLastUsedStep(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	// The same step cannot be used twice
+	rows, err = testQueries.UpdateTotpLastUsedStep(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+}
+
+func TestEnableTotpTx(t *testing.T) {
+	store := NewStore(testDB)
+	totpSecret := createRandomTotpSecret(t)
+
+	recoveryCode := util.RandomString(10)
+	arg := EnableTotpTxParams{
+		Username:            totpSecret.Username,
+		HashedRecoveryCodes: []string{util.HashSecret(recoveryCode), util.HashSecret(util.RandomString(10))},
+	}
+
+	result, err := store.EnableTotpTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.TotpSecret.IsEnabled)
+	require.True(t, result.TotpSecret.EnabledAt.Valid)
+	require.Len(t, result.RecoveryCodes, 2)
+
+	useArg := UseRecoveryCodeParams{
+		Username:   totpSecret.Username,
+		HashedCode: util.HashSecret(recoveryCode),
+	}
+
+	rows, err := testQueries.UseRecoveryCode(context.Background(), useArg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	// Recovery codes are single use
+	rows, err = testQueries.UseRecoveryCode(context.Background(), useArg)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	// Disabling TOTP deletes the recovery codes with the secret
+	err = testQueries.DeleteTotpSecret(context.Background(), totpSecret.Username)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetTotpSecret(context.Background(), totpSecret.Username)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
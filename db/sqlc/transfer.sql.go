@@ -5,26 +5,45 @@ package db
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
 )
 
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
     from_account_id,
     to_account_id,
-    amount
+    amount,
+    to_amount,
+    fx_rate,
+    fx_spread_bps,
+    fx_quote_id
 ) VALUES (
-    $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+    $1, $2, $3, $4, $5, $6, $7
+) RETURNING id, from_account_id, to_account_id, amount, created_at, to_amount, fx_rate, fx_spread_bps, fx_quote_id
 `
 
 type CreateTransferParams struct {
-	FromAccountID int64 `json:"from_account_id"`
-	ToAccountID   int64 `json:"to_account_id"`
-	Amount        int64 `json:"amount"`
+	FromAccountID int64         `json:"from_account_id"`
+	ToAccountID   int64         `json:"to_account_id"`
+	Amount        int64         `json:"amount"`
+	ToAmount      int64         `json:"to_amount"`
+	FxRate        sql.NullInt64 `json:"fx_rate"`
+	FxSpreadBps   sql.NullInt32 `json:"fx_spread_bps"`
+	FxQuoteID     uuid.NullUUID `json:"fx_quote_id"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRowContext(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRowContext(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ToAmount,
+		arg.FxRate,
+		arg.FxSpreadBps,
+		arg.FxQuoteID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -32,12 +51,16 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.FxRate,
+		&i.FxSpreadBps,
+		&i.FxQuoteID,
 	)
 	return i, err
 }
 
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, fx_rate, fx_spread_bps, fx_quote_id FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -50,12 +73,16 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ToAmount,
+		&i.FxRate,
+		&i.FxSpreadBps,
+		&i.FxQuoteID,
 	)
 	return i, err
 }
 
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, to_amount, fx_rate, fx_spread_bps, fx_quote_id FROM transfers
 WHERE
     from_account_id = $1 OR
     to_account_id = $2
@@ -91,6 +118,10 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ToAmount,
+			&i.FxRate,
+			&i.FxSpreadBps,
+			&i.FxQuoteID,
 		); err != nil {
 			return nil, err
 		}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomTransferChallenge(t *testing.T, from Account, to Account, expiresAt time.Time) TransferChallenge {
+	arg := CreateTransferChallengeParams{
+		ID:            uuid.New(),
+		Username:      from.Owner,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        util.RandomMoney(),
+		Currency:      from.Currency,
+		ExpiresAt:     expiresAt,
+	}
+
+	challenge, err := testQueries.CreateTransferChallenge(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, challenge.ID)
+	require.Equal(t, arg.Username, challenge.Username)
+	require.Equal(t, arg.FromAccountID, challenge.FromAccountID)
+	require.Equal(t, arg.ToAccountID, challenge.ToAccountID)
+	require.Equal(t, arg.Amount, challenge.Amount)
+	require.Equal(t, arg.Currency, challenge.Currency)
+	require.Equal(t, "awaiting_confirmation", challenge.Status)
+	require.False(t, challenge.TransferID.Valid)
+	require.False(t, challenge.ConfirmedAt.Valid)
+
+	return challenge
+}
+
+func TestCreateTransferChallengeTxIdempotencyKey(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	key := IdempotencyKeyParams{
+		Username:    account1.Owner,
+		Key:         util.RandomString(32),
+		RequestHash: util.RandomString(64),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	arg := CreateTransferChallengeTxParams{
+		CreateTransferChallengeParams: CreateTransferChallengeParams{
+			ID:            uuid.New(),
+			Username:      account1.Owner,
+			FromAccountID: account1.ID,
+			ToAccountID:   account2.ID,
+			Amount:        util.RandomMoney(),
+			Currency:      account1.Currency,
+			ExpiresAt:     time.Now().Add(time.Minute),
+		},
+		IdempotencyKey: &key,
+	}
+	result, err := store.CreateTransferChallengeTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, result.TransferChallenge.ID)
+
+	record, err := store.GetIdempotencyKey(context.Background(), GetIdempotencyKeyParams{
+		Username: key.Username,
+		Key:      key.Key,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key.RequestHash, record.RequestHash)
+	require.Equal(t, arg.ID, record.TransferChallengeID.UUID)
+
+	var recorded TransferChallenge
+	err = json.Unmarshal(record.Response, &recorded)
+	require.NoError(t, err)
+	require.Equal(t, arg.ID, recorded.ID)
+
+	// A retry does not create another challenge
+	arg.ID = uuid.New()
+	_, err = store.CreateTransferChallengeTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrIdempotencyKeyInUse)
+
+	_, err = store.GetTransferChallenge(context.Background(), arg.ID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestConfirmTransferChallengeTx(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	challenge := createRandomTransferChallenge(t, account1, account2, time.Now().Add(time.Minute))
+	account1 = fundAccount(t, account1, account1.Balance+challenge.Amount)
+
+	// Challenges of other users cannot be confirmed
+	_, err := store.ConfirmTransferChallengeTx(context.Background(), ConfirmTransferChallengeTxParams{
+		ID:       challenge.ID,
+		Username: account2.Owner,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	result, err := store.ConfirmTransferChallengeTx(context.Background(), ConfirmTransferChallengeTxParams{
+		ID:       challenge.ID,
+		Username: account1.Owner,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "confirmed", result.TransferChallenge.Status)
+	require.True(t, result.TransferChallenge.ConfirmedAt.Valid)
+	require.Equal(t, result.Transfer.ID, result.TransferChallenge.TransferID.Int64)
+	require.Equal(t, challenge.Amount, result.Transfer.Amount)
+	require.Equal(t, account1.Balance-challenge.Amount, result.FromAccount.Balance)
+	require.Equal(t, account2.Balance+challenge.Amount, result.ToAccount.Balance)
+
+	// A challenge only executes its transfer once
+	_, err = store.ConfirmTransferChallengeTx(context.Background(), ConfirmTransferChallengeTxParams{
+		ID:       challenge.ID,
+		Username: account1.Owner,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestConfirmExpiredTransferChallenge(t *testing.T) {
+	store := NewStore(testDB)
+
+	account1 := createRandomAccount(t).account
+	account2 := createRandomAccount(t).account
+	challenge := createRandomTransferChallenge(t, account1, account2, time.Now().Add(-time.Second))
+
+	_, err := store.ConfirmTransferChallengeTx(context.Background(), ConfirmTransferChallengeTxParams{
+		ID:       challenge.ID,
+		Username: account1.Owner,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+
+	account, err := testQueries.GetAccount(context.Background(), account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, account.Balance)
+}
@@ -22,6 +22,7 @@ func createRandomTransfer(t *testing.T, fromAccount, toAccount Account) TestTran
 		ToAccountID:   toAccount.ID,
 		Amount:        util.RandomPositiveMoney(), // Transferências devem ser sempre positivas
 	}
+	arg.ToAmount = arg.Amount
 
 	transfer, err := testQueries.CreateTransfer(context.Background(), arg)
 	require.NoError(t, err)
@@ -58,6 +59,7 @@ func TestCreateTransfer(t *testing.T) {
 			FromAccountID: fromAccount.ID,
 			ToAccountID:   toAccount.ID,
 			Amount:        0,
+			ToAmount:      0,
 		}
 
 		transfer, err := testQueries.CreateTransfer(context.Background(), arg)
@@ -73,6 +75,7 @@ func TestCreateTransfer(t *testing.T) {
 			ToAccountID:   fromAccount.ID,
 			Amount:        util.RandomPositiveMoney(),
 		}
+		arg.ToAmount = arg.Amount
 
 		transfer, err := testQueries.CreateTransfer(context.Background(), arg)
 		require.NoError(t, err)
@@ -199,10 +202,12 @@ func TestTransferTxs(t *testing.T) {
 		// Criar transferências em goroutines paralelas
 		for i := 0; i < n; i++ {
 			go func() {
+				amount := util.RandomPositiveMoney()
 				transfer, err := testQueries.CreateTransfer(context.Background(), CreateTransferParams{
 					FromAccountID: account1.ID,
 					ToAccountID:   account2.ID,
-					Amount:        util.RandomPositiveMoney(),
+					Amount:        amount,
+					ToAmount:      amount,
 				})
 
 				errs <- err
@@ -228,6 +233,7 @@ func compareTransfers(t *testing.T, params CreateTransferParams, transfer Transf
 	require.Equal(t, params.FromAccountID, transfer.FromAccountID)
 	require.Equal(t, params.ToAccountID, transfer.ToAccountID)
 	require.Equal(t, params.Amount, transfer.Amount)
+	require.Equal(t, params.ToAmount, transfer.ToAmount)
 	require.NotZero(t, transfer.ID)
 	require.NotZero(t, transfer.CreatedAt)
 }
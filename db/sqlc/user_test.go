@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 	"time"
 
@@ -27,6 +28,7 @@ func createRandomUser(t *testing.T) User {
 	require.Equal(t, arg.HashedPassword, user.HashedPassword)
 	require.Equal(t, arg.FullName, user.FullName)
 	require.Equal(t, arg.Email, user.Email)
+	require.Equal(t, util.DepositorRole, user.Role)
 
 	require.True(t, user.PasswordChangedAt.IsZero())
 	require.NotZero(t, user.CreatedAt)
@@ -53,3 +55,132 @@ func TestGetUser(t *testing.T) {
 	require.WithinDuration(t, user1.PasswordChangedAt, user2.PasswordChangedAt, time.Second)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
 }
+
+func TestUpdateUserRole(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	user2, err := testQueries.UpdateUserRole(context.Background(), UpdateUserRoleParams{
+		Username: user1.Username,
+		Role:     util.BankerRole,
+	})
+	require.NoError(t, err)
+	require.Equal(t, user1.Username, user2.Username)
+	require.Equal(t, util.BankerRole, user2.Role)
+}
+
+func TestRehashUserPassword(t *testing.T) {
+	user := createRandomUser(t)
+
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	// The hash is only replaced if it has not changed in the meantime
+	rows, err := testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		NewHashedPassword: hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: "outdated",
+	})
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	rows, err = testQueries.RehashUserPassword(context.Background(), RehashUserPasswordParams{
+		NewHashedPassword: hashedPassword,
+		Username:          user.Username,
+		OldHashedPassword: user.HashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	rehashedUser, err := testQueries.GetUser(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, rehashedUser.HashedPassword)
+	require.Equal(t, user.PasswordChangedAt, rehashedUser.PasswordChangedAt)
+}
+
+func TestUpdateUserFullName(t *testing.T) {
+	user := createRandomUser(t)
+	user, err := testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	require.NoError(t, err)
+
+	newFullName := util.RandomOwner()
+	updatedUser, err := testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: user.Username,
+		FullName: sql.NullString{String: newFullName, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newFullName, updatedUser.FullName)
+	require.Equal(t, user.Email, updatedUser.Email)
+	require.True(t, updatedUser.IsEmailVerified)
+
+	// Setting the same email address keeps it verified
+	updatedUser, err = testQueries.UpdateUser(context.Background(), UpdateUserParams{
+		Username: user.Username,
+		Email:    sql.NullString{String: user.Email, Valid: true},
+	})
+	require.NoError(t, err)
+	require.True(t, updatedUser.IsEmailVerified)
+}
+
+func TestUpdateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	_, err := testQueries.VerifyUserEmail(context.Background(), VerifyUserEmailParams{
+		Username: user.Username,
+		Email:    user.Email,
+	})
+	require.NoError(t, err)
+
+	newEmail := util.RandomEmail()
+	result, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user.Username,
+			Email:    sql.NullString{String: newEmail, Valid: true},
+		},
+		HashedSecretCode: util.HashSecret(util.RandomString(32)),
+		ExpiredAt:        time.Now().Add(time.Minute),
+		VerificationEmail: func(user User, verifyEmail VerifyEmail) CreateOutboxEmailParams {
+			return CreateOutboxEmailParams{
+				ToAddress: verifyEmail.Email,
+				Subject:   "Verify your new email",
+				Body:      user.FullName,
+			}
+		},
+		EmailChangedEmail: func(oldUser User, user User) CreateOutboxEmailParams {
+			return CreateOutboxEmailParams{
+				ToAddress: oldUser.Email,
+				Subject:   "Your email was changed",
+				Body:      user.Email,
+			}
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, newEmail, result.User.Email)
+	require.Equal(t, user.FullName, result.User.FullName)
+	require.False(t, result.User.IsEmailVerified)
+	require.NotNil(t, result.VerifyEmail)
+	require.Equal(t, newEmail, result.VerifyEmail.Email)
+	require.NotNil(t, result.Email)
+	require.Equal(t, newEmail, result.Email.ToAddress)
+	require.Equal(t, user.FullName, result.Email.Body)
+	require.NotNil(t, result.EmailChangedEmail)
+	require.Equal(t, user.Email, result.EmailChangedEmail.ToAddress)
+	require.Equal(t, newEmail, result.EmailChangedEmail.Body)
+}
+
+func TestUpdateUserTxDuplicateEmail(t *testing.T) {
+	store := NewStore(testDB)
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+
+	_, err := store.UpdateUserTx(context.Background(), UpdateUserTxParams{
+		UpdateUserParams: UpdateUserParams{
+			Username: user1.Username,
+			Email:    sql.NullString{String: user2.Email, Valid: true},
+		},
+	})
+	require.Error(t, err)
+	require.Equal(t, UniqueViolation, ErrorCode(err))
+}
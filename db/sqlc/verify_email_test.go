@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomVerifyEmail(t *testing.T, user User, expiredAt time.Time) VerifyEmail {
+	arg := CreateVerifyEmailParams{
+		Username:         user.Username,
+		Email:            user.Email,
+		HashedSecretCode: util.HashSecret(util.RandomString(32)),
+		ExpiredAt:        expiredAt,
+	}
+
+	verifyEmail, err := testQueries.CreateVerifyEmail(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, verifyEmail.Username)
+	require.Equal(t, arg.Email, verifyEmail.Email)
+	require.Equal(t, arg.HashedSecretCode, verifyEmail.HashedSecretCode)
+	require.False(t, verifyEmail.IsUsed)
+	require.WithinDuration(t, arg.ExpiredAt, verifyEmail.ExpiredAt, time.Second)
+
+	return verifyEmail
+}
+
+func TestVerifyEmailTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	require.False(t, user.IsEmailVerified)
+
+	verifyEmail := createRandomVerifyEmail(t, user, time.Now().Add(time.Minute))
+	arg := VerifyEmailTxParams{
+		EmailID:          verifyEmail.ID,
+		HashedSecretCode: verifyEmail.HashedSecretCode,
+	}
+
+	result, err := store.VerifyEmailTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, result.VerifyEmail.IsUsed)
+	require.True(t, result.User.IsEmailVerified)
+
+	// A secret code can only be used once
+	_, err = store.VerifyEmailTx(context.Background(), arg)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestVerifyEmailTxWrongCode(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	verifyEmail := createRandomVerifyEmail(t, user, time.Now().Add(time.Minute))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:          verifyEmail.ID,
+		HashedSecretCode: util.HashSecret(util.RandomString(32)),
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestVerifyEmailTxExpired(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	verifyEmail := createRandomVerifyEmail(t, user, time.Now().Add(-time.Minute))
+
+	_, err := store.VerifyEmailTx(context.Background(), VerifyEmailTxParams{
+		EmailID:          verifyEmail.ID,
+		HashedSecretCode: verifyEmail.HashedSecretCode,
+	})
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCreateUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	hashedPassword, err := util.HashPassword(util.RandomString(6))
+	require.NoError(t, err)
+
+	arg := CreateUserTxParams{
+		CreateUserParams: CreateUserParams{
+			Username:       util.RandomOwner(),
+			HashedPassword: hashedPassword,
+			FullName:       util.RandomOwner(),
+			Email:          util.RandomEmail(),
+		},
+		HashedSecretCode: util.HashSecret(util.RandomString(32)),
+		ExpiredAt:        time.Now().Add(time.Minute),
+		VerificationEmail: func(verifyEmail VerifyEmail) CreateOutboxEmailParams {
+			return CreateOutboxEmailParams{
+				ToAddress: verifyEmail.Email,
+				Subject:   "Verify your email",
+				Body:      util.RandomString(50),
+			}
+		},
+	}
+
+	result, err := store.CreateUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Username, result.User.Username)
+	require.False(t, result.User.IsEmailVerified)
+	require.Equal(t, result.User.Username, result.VerifyEmail.Username)
+	require.Equal(t, arg.Email, result.VerifyEmail.Email)
+	require.Equal(t, arg.HashedSecretCode, result.VerifyEmail.HashedSecretCode)
+	require.Equal(t, arg.Email, result.Email.ToAddress)
+	require.False(t, result.Email.SentAt.Valid)
+}
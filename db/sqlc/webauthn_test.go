@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomWebauthnCredential(t *testing.T, user User) WebauthnCredential {
+	arg := CreateWebauthnCredentialParams{
+		Username:     user.Username,
+		Name:         util.RandomOwner(),
+		CredentialID: []byte(util.RandomString(16)),
+		PublicKey:    []byte(util.RandomString(77)),
+		SignCount:    util.RandomInt(0, 100),
+	}
+
+	credential, err := testQueries.CreateWebauthnCredential(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotZero(t, credential.ID)
+	require.Equal(t, arg.Username, credential.Username)
+	require.Equal(t, arg.Name, credential.Name)
+	require.Equal(t, arg.CredentialID, credential.CredentialID)
+	require.Equal(t, arg.PublicKey, credential.PublicKey)
+	require.Equal(t, arg.SignCount, credential.SignCount)
+	require.False(t, credential.LastUsedAt.Valid)
+
+	return credential
+}
+
+func TestGetWebauthnCredential(t *testing.T) {
+	credential1 := createRandomWebauthnCredential(t, createRandomUser(t))
+
+	credential2, err := testQueries.GetWebauthnCredential(context.Background(), credential1.CredentialID)
+	require.NoError(t, err)
+	require.Equal(t, credential1.ID, credential2.ID)
+	require.Equal(t, credential1.PublicKey, credential2.PublicKey)
+}
+
+func TestUpdateWebauthnCredentialSignCount(t *testing.T) {
+	credential := createRandomWebauthnCredential(t, createRandomUser(t))
+
+	arg := UpdateWebauthnCredentialSignCountParams{
+		NewSignCount: credential.SignCount + 1,
+		ID:           credential.ID,
+		OldSignCount: credential.SignCount,
+	}
+	rows, err := testQueries.UpdateWebauthnCredentialSignCount(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	// A concurrent login read the old counter, it cannot update it again
+	rows, err = testQueries.UpdateWebauthnCredentialSignCount(context.Background(), arg)
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	usedCredential, err := testQueries.GetWebauthnCredential(context.Background(), credential.CredentialID)
+	require.NoError(t, err)
+	require.Equal(t, arg.NewSignCount, usedCredential.SignCount)
+	require.True(t, usedCredential.LastUsedAt.Valid)
+	require.WithinDuration(t, time.Now(), usedCredential.LastUsedAt.Time, time.Second)
+}
+
+func TestListAndDeleteWebauthnCredentials(t *testing.T) {
+	user := createRandomUser(t)
+	otherUser := createRandomUser(t)
+	credential1 := createRandomWebauthnCredential(t, user)
+	credential2 := createRandomWebauthnCredential(t, user)
+
+	credentials, err := testQueries.ListWebauthnCredentials(context.Background(), user.Username)
+	require.NoError(t, err)
+	require.Len(t, credentials, 2)
+	require.Equal(t, credential1.ID, credentials[0].ID)
+	require.Equal(t, credential2.ID, credentials[1].ID)
+
+	// Credentials of another user cannot be deleted
+	rows, err := testQueries.DeleteWebauthnCredential(context.Background(), DeleteWebauthnCredentialParams{ID: credential1.ID, Username: otherUser.Username})
+	require.NoError(t, err)
+	require.Zero(t, rows)
+
+	rows, err = testQueries.DeleteWebauthnCredential(context.Background(), DeleteWebauthnCredentialParams{ID: credential1.ID, Username: user.Username})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rows)
+
+	_, err = testQueries.GetWebauthnCredential(context.Background(), credential1.CredentialID)
+	require.ErrorIs(t, err, ErrRecordNotFound)
+}
+
+func TestCreateWebauthnCredentialTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+
+	arg := CreateWebauthnCredentialParams{
+		Username:     user.Username,
+		Name:         util.RandomOwner(),
+		CredentialID: []byte(util.RandomString(16)),
+		PublicKey:    []byte(util.RandomString(77)),
+	}
+	result, err := store.CreateWebauthnCredentialTx(context.Background(), CreateWebauthnCredentialTxParams{
+		CreateWebauthnCredentialParams: arg,
+		NewPasskeyEmail: func(user User, credential WebauthnCredential) CreateOutboxEmailParams {
+			return CreateOutboxEmailParams{
+				ToAddress: user.Email,
+				Subject:   "new passkey",
+				Body:      credential.Name,
+			}
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, arg.CredentialID, result.Credential.CredentialID)
+	require.Equal(t, user.Email, result.Email.ToAddress)
+	require.Equal(t, arg.Name, result.Email.Body)
+}
@@ -0,0 +1,80 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	db "github.com/JMustang/OldBank/db/sqlc"
+)
+
+const (
+	dispatchBatchSize   = 50
+	maxDispatchAttempts = 5
+)
+
+// OutboxDispatcher delivers the emails written into the email_outbox table
+type OutboxDispatcher struct {
+	store  db.Querier
+	sender EmailSender
+}
+
+// NewOutboxDispatcher creates a new OutboxDispatcher
+func NewOutboxDispatcher(store db.Querier, sender EmailSender) *OutboxDispatcher {
+	return &OutboxDispatcher{
+		store:  store,
+		sender: sender,
+	}
+}
+
+// Run dispatches pending emails every interval until the context is done
+func (dispatcher *OutboxDispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := dispatcher.DispatchPending(ctx)
+			if err != nil {
+				log.Println("cannot dispatch emails:", err)
+			}
+		}
+	}
+}
+
+// DispatchPending sends the pending emails of the outbox.
+// Emails failing to send are retried on the next dispatch, up to a maximum number of attempts.
+func (dispatcher *OutboxDispatcher) DispatchPending(ctx context.Context) error {
+	emails, err := dispatcher.store.ListPendingOutboxEmails(ctx, db.ListPendingOutboxEmailsParams{
+		MaxAttempts: maxDispatchAttempts,
+		LimitCount:  dispatchBatchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, email := range emails {
+		err = dispatcher.sender.SendEmail(ctx, Email{
+			To:      email.ToAddress,
+			Subject: email.Subject,
+			Body:    email.Body,
+		})
+		if err != nil {
+			err = dispatcher.store.MarkOutboxEmailFailed(ctx, db.MarkOutboxEmailFailedParams{
+				ID:        email.ID,
+				LastError: sql.NullString{String: err.Error(), Valid: true},
+			})
+		} else {
+			err = dispatcher.store.MarkOutboxEmailSent(ctx, email.ID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
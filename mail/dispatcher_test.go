@@ -0,0 +1,135 @@
+package mail
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	mockdb "github.com/JMustang/OldBank/db/mock"
+	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/util"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSender struct {
+	sent []Email
+	err  error
+}
+
+func (sender *fakeSender) SendEmail(ctx context.Context, email Email) error {
+	if sender.err != nil {
+		return sender.err
+	}
+	sender.sent = append(sender.sent, email)
+	return nil
+}
+
+func randomOutboxEmail() db.EmailOutbox {
+	return db.EmailOutbox{
+		ID:        util.RandomInt(1, 1000),
+		ToAddress: util.RandomEmail(),
+		Subject:   util.RandomString(10),
+		Body:      util.RandomString(50),
+	}
+}
+
+func TestDispatchPending(t *testing.T) {
+	emails := []db.EmailOutbox{randomOutboxEmail(), randomOutboxEmail()}
+
+	testCases := []struct {
+		name       string
+		sender     *fakeSender
+		buildStubs func(store *mockdb.MockStore)
+		checkSent  func(t *testing.T, sender *fakeSender, err error)
+	}{
+		{
+			name:   "OK",
+			sender: &fakeSender{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPendingOutboxEmails(gomock.Any(), gomock.Eq(db.ListPendingOutboxEmailsParams{
+						MaxAttempts: maxDispatchAttempts,
+						LimitCount:  dispatchBatchSize,
+					})).
+					Times(1).
+					Return(emails, nil)
+				for _, email := range emails {
+					store.EXPECT().
+						MarkOutboxEmailSent(gomock.Any(), gomock.Eq(email.ID)).
+						Times(1).
+						Return(nil)
+				}
+				store.EXPECT().
+					MarkOutboxEmailFailed(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkSent: func(t *testing.T, sender *fakeSender, err error) {
+				require.NoError(t, err)
+				require.Len(t, sender.sent, len(emails))
+				for i, email := range emails {
+					require.Equal(t, email.ToAddress, sender.sent[i].To)
+					require.Equal(t, email.Subject, sender.sent[i].Subject)
+					require.Equal(t, email.Body, sender.sent[i].Body)
+				}
+			},
+		},
+		{
+			name:   "SendFailure",
+			sender: &fakeSender{err: errors.New("connection refused")},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPendingOutboxEmails(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(emails, nil)
+				for _, email := range emails {
+					store.EXPECT().
+						MarkOutboxEmailFailed(gomock.Any(), gomock.Eq(db.MarkOutboxEmailFailedParams{
+							ID:        email.ID,
+							LastError: sql.NullString{String: "connection refused", Valid: true},
+						})).
+						Times(1).
+						Return(nil)
+				}
+				store.EXPECT().
+					MarkOutboxEmailSent(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkSent: func(t *testing.T, sender *fakeSender, err error) {
+				require.NoError(t, err)
+				require.Empty(t, sender.sent)
+			},
+		},
+		{
+			name:   "ListError",
+			sender: &fakeSender{},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().
+					ListPendingOutboxEmails(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil, sql.ErrConnDone)
+			},
+			checkSent: func(t *testing.T, sender *fakeSender, err error) {
+				require.ErrorIs(t, err, sql.ErrConnDone)
+				require.Empty(t, sender.sent)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			dispatcher := NewOutboxDispatcher(store, tc.sender)
+			err := dispatcher.DispatchPending(context.Background())
+			tc.checkSent(t, tc.sender, err)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// FileSender writes emails as .eml files into a directory instead of sending them.
+// It is meant for development and tests.
+type FileSender struct {
+	from    string
+	dir     string
+	counter atomic.Int64
+}
+
+// NewFileSender creates a new FileSender writing into dir
+func NewFileSender(from string, dir string) EmailSender {
+	return &FileSender{
+		from: from,
+		dir:  dir,
+	}
+}
+
+// SendEmail writes the email into a new file
+func (sender *FileSender) SendEmail(ctx context.Context, email Email) error {
+	err := os.MkdirAll(sender.dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%d-%d.eml", time.Now().UnixNano(), sender.counter.Add(1))
+	err = os.WriteFile(filepath.Join(sender.dir, name), formatMessage(sender.from, email), 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
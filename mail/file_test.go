@@ -0,0 +1,35 @@
+package mail
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSender(t *testing.T) {
+	dir := t.TempDir()
+	sender := NewFileSender("no-reply@oldbank.local", dir)
+
+	email := Email{
+		To:      util.RandomEmail(),
+		Subject: "Test email",
+		Body:    "Hello from OldBank",
+	}
+	require.NoError(t, sender.SendEmail(context.Background(), email))
+	require.NoError(t, sender.SendEmail(context.Background(), email))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+
+	message, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.Contains(t, string(message), "From: no-reply@oldbank.local\r\n")
+	require.Contains(t, string(message), "To: "+email.To+"\r\n")
+	require.Contains(t, string(message), "Subject: "+email.Subject+"\r\n")
+	require.Contains(t, string(message), email.Body)
+}
@@ -0,0 +1,32 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JMustang/OldBank/util"
+)
+
+// Email is an outgoing plain text email
+type Email struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// EmailSender is an interface for delivering emails
+type EmailSender interface {
+	// SendEmail delivers the email or returns an error
+	SendEmail(ctx context.Context, email Email) error
+}
+
+// NewSender creates the email sender configured by MAIL_SENDER
+func NewSender(config util.Config) (EmailSender, error) {
+	switch config.MailSender {
+	case "", "file":
+		return NewFileSender(config.MailFrom, config.MailFileDir), nil
+	case "smtp":
+		return NewSMTPSender(config.MailFrom, config.SMTPAddress, config.SMTPUsername, config.SMTPPassword), nil
+	}
+	return nil, fmt.Errorf("unsupported mail sender: %s", config.MailSender)
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPSender sends emails through an SMTP server
+type SMTPSender struct {
+	from     string
+	address  string
+	username string
+	password string
+}
+
+// NewSMTPSender creates a new SMTPSender. The server address is in the host:port format.
+func NewSMTPSender(from string, address string, username string, password string) EmailSender {
+	return &SMTPSender{
+		from:     from,
+		address:  address,
+		username: username,
+		password: password,
+	}
+}
+
+// SendEmail sends the email through the SMTP server
+func (sender *SMTPSender) SendEmail(ctx context.Context, email Email) error {
+	var auth smtp.Auth
+	if sender.username != "" {
+		host, _, err := net.SplitHostPort(sender.address)
+		if err != nil {
+			return fmt.Errorf("invalid smtp address: %w", err)
+		}
+		auth = smtp.PlainAuth("", sender.username, sender.password, host)
+	}
+
+	err := smtp.SendMail(sender.address, auth, sender.from, []string{email.To}, formatMessage(sender.from, email))
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// formatMessage formats the email as an RFC 5322 message
+func formatMessage(from string, email Email) []byte {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "From: %s\r\n", from)
+	fmt.Fprintf(&sb, "To: %s\r\n", email.To)
+	fmt.Fprintf(&sb, "Subject: %s\r\n", email.Subject)
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(email.Body, "\n", "\r\n"))
+	return []byte(sb.String())
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/JMustang/OldBank/util"
 
 	"github.com/JMustang/OldBank/api"
 	db "github.com/JMustang/OldBank/db/sqlc"
+	"github.com/JMustang/OldBank/mail"
 	_ "github.com/lib/pq"
 )
 
@@ -22,13 +28,58 @@ func main() {
 	}
 
 	store := db.NewStore(conn)
+
+	sender, err := mail.NewSender(config)
+	if err != nil {
+		log.Fatal("cannot create mail sender:", err)
+	}
+	dispatcher := mail.NewOutboxDispatcher(store, sender)
+	go dispatcher.Run(context.Background(), mailDispatchInterval(config))
+
 	server, err := api.NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create server:", err)
 	}
 
+	go reloadTokenKeys(server, config.TLSCertFile != "")
+
 	err = server.Start(config.ServerAddress)
 	if err != nil {
 		log.Fatal("cannot start server:", err)
 	}
 }
+
+// reloadTokenKeys reloads the token keys from the config whenever the process receives SIGHUP,
+// along with the TLS certificate if the server serves TLS. Each reload is attempted even if the other
+// one fails, so that a bad key config does not hold up the rotation of the certificate.
+func reloadTokenKeys(server *api.Server, servesTLS bool) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	for range signals {
+		config, err := util.LoadConfig(".")
+		if err != nil {
+			log.Println("cannot load config:", err)
+		} else if err = server.ReloadTokenKeys(config); err != nil {
+			log.Println("cannot reload token keys:", err)
+		} else {
+			log.Println("token keys reloaded")
+		}
+
+		// The certificate is read again from the files the server was started with
+		if servesTLS {
+			if err := server.ReloadTLSCertificate(); err != nil {
+				log.Println("cannot reload TLS certificate:", err)
+			} else {
+				log.Println("TLS certificate reloaded")
+			}
+		}
+	}
+}
+
+func mailDispatchInterval(config util.Config) time.Duration {
+	if config.MailDispatchInterval <= 0 {
+		return 10 * time.Second
+	}
+	return config.MailDispatchInterval
+}
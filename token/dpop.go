@@ -0,0 +1,359 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DPoPProofType is the typ header of DPoP proofs
+const DPoPProofType = "dpop+jwt"
+
+// ErrInvalidDPoPProof is returned when a DPoP proof is malformed, badly signed or does not match the request
+var ErrInvalidDPoPProof = errors.New("DPoP proof is invalid")
+
+// minRSAKeyBits is the smallest RSA key accepted to sign DPoP proofs
+const minRSAKeyBits = 2048
+
+// DPoPProof is a DPoP proof (RFC 9449) whose signature has been checked.
+// The proof is a JWT signed by the client, carrying its public key in the header.
+type DPoPProof struct {
+	ID              string
+	Method          string
+	URL             string
+	IssuedAt        time.Time
+	AccessTokenHash string
+	// Thumbprint is the JWK thumbprint of the key that signed the proof
+	Thumbprint string
+}
+
+type dpopHeader struct {
+	Type      string   `json:"typ"`
+	Algorithm string   `json:"alg"`
+	JWK       *dpopJWK `json:"jwk"`
+}
+
+type dpopJWK struct {
+	JSONWebKey
+	// D is only set if the client mistakenly sent its private key
+	D string `json:"d,omitempty"`
+}
+
+type dpopClaims struct {
+	ID              string `json:"jti"`
+	Method          string `json:"htm"`
+	URL             string `json:"htu"`
+	IssuedAt        int64  `json:"iat"`
+	AccessTokenHash string `json:"ath,omitempty"`
+}
+
+// ParseDPoPProof checks the format and the signature of a DPoP proof.
+// The claims must then be checked against the request with Verify.
+func ParseDPoPProof(proof string) (*DPoPProof, error) {
+	parts := strings.Split(proof, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: not a JWT", ErrInvalidDPoPProof)
+	}
+
+	var header dpopHeader
+	err := decodeJWTPart(parts[0], &header)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != DPoPProofType {
+		return nil, fmt.Errorf("%w: typ must be %s", ErrInvalidDPoPProof, DPoPProofType)
+	}
+	if header.JWK == nil || header.JWK.D != "" {
+		return nil, fmt.Errorf("%w: jwk must be a public key", ErrInvalidDPoPProof)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: cannot decode signature", ErrInvalidDPoPProof)
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+	err = verifyJWS(header.Algorithm, header.JWK.JSONWebKey, signingInput, signature)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims dpopClaims
+	err = decodeJWTPart(parts[1], &claims)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" || claims.Method == "" || claims.URL == "" || claims.IssuedAt == 0 {
+		return nil, fmt.Errorf("%w: jti, htm, htu and iat are required", ErrInvalidDPoPProof)
+	}
+
+	thumbprint, err := JWKThumbprint(header.JWK.JSONWebKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DPoPProof{
+		ID:              claims.ID,
+		Method:          claims.Method,
+		URL:             claims.URL,
+		IssuedAt:        time.Unix(claims.IssuedAt, 0),
+		AccessTokenHash: claims.AccessTokenHash,
+		Thumbprint:      thumbprint,
+	}, nil
+}
+
+// Verify checks that the proof was created for the request, at most maxAge ago.
+// accessToken is empty when the proof is sent to obtain new tokens.
+// The jti of the proof must also be checked against replays by the caller.
+func (proof *DPoPProof) Verify(method string, requestURL string, accessToken string, maxAge time.Duration, clockSkew time.Duration) error {
+	if proof.Method != method {
+		return fmt.Errorf("%w: htm does not match the request", ErrInvalidDPoPProof)
+	}
+	if !sameDPoPURL(proof.URL, requestURL) {
+		return fmt.Errorf("%w: htu does not match the request", ErrInvalidDPoPProof)
+	}
+
+	now := time.Now()
+	if proof.IssuedAt.After(now.Add(clockSkew)) || proof.IssuedAt.Before(now.Add(-maxAge-clockSkew)) {
+		return fmt.Errorf("%w: iat is too old or in the future", ErrInvalidDPoPProof)
+	}
+
+	if accessToken != "" && proof.AccessTokenHash != AccessTokenHash(accessToken) {
+		return fmt.Errorf("%w: ath does not match the access token", ErrInvalidDPoPProof)
+	}
+	return nil
+}
+
+// AccessTokenHash returns the ath claim binding a DPoP proof to an access token
+func AccessTokenHash(accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+// JWKThumbprint computes the RFC 7638 thumbprint of a public key
+func JWKThumbprint(jwk JSONWebKey) (string, error) {
+	// The required members of each key type, in lexicographic order
+	var members interface{}
+	switch jwk.KeyType {
+	case "EC":
+		members = struct {
+			Curve   string `json:"crv"`
+			KeyType string `json:"kty"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		}{jwk.Curve, jwk.KeyType, jwk.X, jwk.Y}
+	case "OKP":
+		members = struct {
+			Curve   string `json:"crv"`
+			KeyType string `json:"kty"`
+			X       string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	case "RSA":
+		members = struct {
+			E       string `json:"e"`
+			KeyType string `json:"kty"`
+			N       string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	default:
+		return "", fmt.Errorf("%w: unsupported key type %s", ErrInvalidDPoPProof, jwk.KeyType)
+	}
+
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(hash[:]), nil
+}
+
+// NewDPoPProof creates a DPoP proof for a request, signed with an ECDSA P-256, Ed25519 or RSA key.
+// accessToken is empty when the proof is sent to obtain new tokens.
+func NewDPoPProof(key crypto.Signer, method string, requestURL string, accessToken string) (string, error) {
+	claims := dpopClaims{
+		ID:       uuid.NewString(),
+		Method:   method,
+		URL:      requestURL,
+		IssuedAt: time.Now().Unix(),
+	}
+	if accessToken != "" {
+		claims.AccessTokenHash = AccessTokenHash(accessToken)
+	}
+	return signDPoPProof(key, claims)
+}
+
+func signDPoPProof(key crypto.Signer, claims dpopClaims) (string, error) {
+	jwk, algorithm, err := publicJSONWebKey(key.Public())
+	if err != nil {
+		return "", err
+	}
+
+	header, err := json.Marshal(dpopHeader{
+		Type:      DPoPProofType,
+		Algorithm: algorithm,
+		JWK:       &dpopJWK{JSONWebKey: jwk},
+	})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return "", err
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signingInput))
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// publicJSONWebKey encodes the public key of a DPoP client, with the algorithm of its signatures
+func publicJSONWebKey(publicKey crypto.PublicKey) (JSONWebKey, string, error) {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return JSONWebKey{}, "", errors.New("only P-256 ECDSA keys are supported")
+		}
+		return JSONWebKey{
+			KeyType: "EC",
+			Curve:   "P-256",
+			X:       base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			Y:       base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+		}, "ES256", nil
+	case ed25519.PublicKey:
+		return JSONWebKey{
+			KeyType: "OKP",
+			Curve:   "Ed25519",
+			X:       base64.RawURLEncoding.EncodeToString(key),
+		}, "EdDSA", nil
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			KeyType: "RSA",
+			N:       base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:       base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, "RS256", nil
+	}
+	return JSONWebKey{}, "", fmt.Errorf("unsupported key type %T", publicKey)
+}
+
+// verifyJWS checks the signature of a JWS with the public key of the header.
+// Only asymmetric algorithms are accepted, so that "none" or HMAC cannot be used to forge proofs.
+func verifyJWS(algorithm string, jwk JSONWebKey, signingInput []byte, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+
+	switch {
+	case algorithm == "ES256" && jwk.KeyType == "EC" && jwk.Curve == "P-256":
+		x, err := decodeKeyInt(jwk.X)
+		if err != nil {
+			return err
+		}
+		y, err := decodeKeyInt(jwk.Y)
+		if err != nil {
+			return err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return fmt.Errorf("%w: jwk is not on curve P-256", ErrInvalidDPoPProof)
+		}
+		if len(signature) != 64 {
+			return fmt.Errorf("%w: bad signature", ErrInvalidDPoPProof)
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidDPoPProof)
+		}
+	case algorithm == "EdDSA" && jwk.KeyType == "OKP" && jwk.Curve == "Ed25519":
+		publicKey, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(publicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("%w: bad Ed25519 jwk", ErrInvalidDPoPProof)
+		}
+		if !ed25519.Verify(publicKey, signingInput, signature) {
+			return fmt.Errorf("%w: bad signature", ErrInvalidDPoPProof)
+		}
+	case algorithm == "RS256" && jwk.KeyType == "RSA":
+		n, err := decodeKeyInt(jwk.N)
+		if err != nil {
+			return err
+		}
+		e, err := decodeKeyInt(jwk.E)
+		if err != nil {
+			return err
+		}
+		if n.BitLen() < minRSAKeyBits || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return fmt.Errorf("%w: RSA jwk is too weak or malformed", ErrInvalidDPoPProof)
+		}
+		publicKey := &rsa.PublicKey{N: n, E: int(e.Int64())}
+		if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) != nil {
+			return fmt.Errorf("%w: bad signature", ErrInvalidDPoPProof)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported alg %s for the jwk", ErrInvalidDPoPProof, algorithm)
+	}
+	return nil
+}
+
+func decodeKeyInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("%w: bad jwk", ErrInvalidDPoPProof)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func decodeJWTPart(part string, value interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: cannot decode JWT", ErrInvalidDPoPProof)
+	}
+	if json.Unmarshal(data, value) != nil {
+		return fmt.Errorf("%w: cannot decode JWT", ErrInvalidDPoPProof)
+	}
+	return nil
+}
+
+// sameDPoPURL compares the htu claim with the request URL, ignoring the query and fragment
+func sameDPoPURL(claimed string, requestURL string) bool {
+	u1, err := url.Parse(claimed)
+	if err != nil {
+		return false
+	}
+	u2, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u1.Scheme, u2.Scheme) &&
+		strings.EqualFold(u1.Host, u2.Host) &&
+		u1.EscapedPath() == u2.EscapedPath()
+}
@@ -0,0 +1,277 @@
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testDPoPURL = "https://bank.example/accounts"
+
+func generateDPoPKeys(t *testing.T) map[string]crypto.Signer {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return map[string]crypto.Signer{
+		"ES256": ecdsaKey,
+		"EdDSA": ed25519Key,
+		"RS256": rsaKey,
+	}
+}
+
+func TestDPoPProof(t *testing.T) {
+	for algorithm, key := range generateDPoPKeys(t) {
+		key := key
+
+		t.Run(algorithm, func(t *testing.T) {
+			jwk, _, err := publicJSONWebKey(key.Public())
+			require.NoError(t, err)
+			thumbprint, err := JWKThumbprint(jwk)
+			require.NoError(t, err)
+
+			proofJWT, err := NewDPoPProof(key, "GET", testDPoPURL, "access-token")
+			require.NoError(t, err)
+
+			proof, err := ParseDPoPProof(proofJWT)
+			require.NoError(t, err)
+			require.NotEmpty(t, proof.ID)
+			require.Equal(t, "GET", proof.Method)
+			require.Equal(t, testDPoPURL, proof.URL)
+			require.WithinDuration(t, time.Now(), proof.IssuedAt, time.Second)
+			require.Equal(t, thumbprint, proof.Thumbprint)
+
+			err = proof.Verify("GET", testDPoPURL+"?page_id=1", "access-token", time.Minute, 0)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestVerifyDPoPProof(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	newProof := func(t *testing.T, issuedAt time.Time, accessToken string) *DPoPProof {
+		claims := dpopClaims{
+			ID:       uuid.NewString(),
+			Method:   "POST",
+			URL:      testDPoPURL,
+			IssuedAt: issuedAt.Unix(),
+		}
+		if accessToken != "" {
+			claims.AccessTokenHash = AccessTokenHash(accessToken)
+		}
+		proofJWT, err := signDPoPProof(key, claims)
+		require.NoError(t, err)
+
+		proof, err := ParseDPoPProof(proofJWT)
+		require.NoError(t, err)
+		return proof
+	}
+
+	testCases := []struct {
+		name        string
+		proof       func(t *testing.T) *DPoPProof
+		method      string
+		url         string
+		accessToken string
+		valid       bool
+	}{
+		{
+			name: "OK",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now(), "access-token")
+			},
+			method:      "POST",
+			url:         testDPoPURL,
+			accessToken: "access-token",
+			valid:       true,
+		},
+		{
+			name: "WithoutAccessToken",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now(), "")
+			},
+			method: "POST",
+			url:    testDPoPURL,
+			valid:  true,
+		},
+		{
+			name: "WrongMethod",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now(), "")
+			},
+			method: "GET",
+			url:    testDPoPURL,
+		},
+		{
+			name: "WrongURL",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now(), "")
+			},
+			method: "POST",
+			url:    "https://bank.example/transfers",
+		},
+		{
+			name: "WrongScheme",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now(), "")
+			},
+			method: "POST",
+			url:    "http://bank.example/accounts",
+		},
+		{
+			name: "MissingAccessTokenHash",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now(), "")
+			},
+			method:      "POST",
+			url:         testDPoPURL,
+			accessToken: "access-token",
+		},
+		{
+			name: "OtherAccessToken",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now(), "other-token")
+			},
+			method:      "POST",
+			url:         testDPoPURL,
+			accessToken: "access-token",
+		},
+		{
+			name: "TooOld",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now().Add(-2*time.Minute), "")
+			},
+			method: "POST",
+			url:    testDPoPURL,
+		},
+		{
+			name: "InTheFuture",
+			proof: func(t *testing.T) *DPoPProof {
+				return newProof(t, time.Now().Add(time.Minute), "")
+			},
+			method: "POST",
+			url:    testDPoPURL,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.proof(t).Verify(tc.method, tc.url, tc.accessToken, time.Minute, time.Second)
+			if tc.valid {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrInvalidDPoPProof)
+			}
+		})
+	}
+}
+
+func TestInvalidDPoPProof(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	proofJWT, err := NewDPoPProof(key, "GET", testDPoPURL, "")
+	require.NoError(t, err)
+	parts := strings.Split(proofJWT, ".")
+
+	encode := func(value interface{}) string {
+		data, err := json.Marshal(value)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	jwk, _, err := publicJSONWebKey(key.Public())
+	require.NoError(t, err)
+	otherJWK, _, err := publicJSONWebKey(otherKey.Public())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		proof string
+	}{
+		{
+			name:  "NotJWT",
+			proof: "not-a-jwt",
+		},
+		{
+			name:  "WrongType",
+			proof: encode(dpopHeader{Type: "JWT", Algorithm: "EdDSA", JWK: &dpopJWK{JSONWebKey: jwk}}) + "." + parts[1] + "." + parts[2],
+		},
+		{
+			name:  "NoneAlgorithm",
+			proof: encode(dpopHeader{Type: DPoPProofType, Algorithm: "none", JWK: &dpopJWK{JSONWebKey: jwk}}) + "." + parts[1] + ".",
+		},
+		{
+			name:  "MissingKey",
+			proof: encode(dpopHeader{Type: DPoPProofType, Algorithm: "EdDSA"}) + "." + parts[1] + "." + parts[2],
+		},
+		{
+			name:  "PrivateKey",
+			proof: encode(dpopHeader{Type: DPoPProofType, Algorithm: "EdDSA", JWK: &dpopJWK{JSONWebKey: jwk, D: "secret"}}) + "." + parts[1] + "." + parts[2],
+		},
+		{
+			name:  "OtherKey",
+			proof: encode(dpopHeader{Type: DPoPProofType, Algorithm: "EdDSA", JWK: &dpopJWK{JSONWebKey: otherJWK}}) + "." + parts[1] + "." + parts[2],
+		},
+		{
+			name:  "TamperedClaims",
+			proof: parts[0] + "." + encode(dpopClaims{ID: "id", Method: "POST", URL: testDPoPURL, IssuedAt: time.Now().Unix()}) + "." + parts[2],
+		},
+		{
+			name:  "MissingClaims",
+			proof: mustSignDPoPProof(t, key, dpopClaims{ID: "id", Method: "GET"}),
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			proof, err := ParseDPoPProof(tc.proof)
+			require.ErrorIs(t, err, ErrInvalidDPoPProof)
+			require.Nil(t, proof)
+		})
+	}
+}
+
+func mustSignDPoPProof(t *testing.T, key crypto.Signer, claims dpopClaims) string {
+	proof, err := signDPoPProof(key, claims)
+	require.NoError(t, err)
+	return proof
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// Example of RFC 7638, section 3.1
+	jwk := JSONWebKey{
+		KeyType: "RSA",
+		N:       "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		E:       "AQAB",
+		KeyID:   "2011-04-29",
+	}
+
+	thumbprint, err := JWKThumbprint(jwk)
+	require.NoError(t, err)
+	require.Equal(t, "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs", thumbprint)
+
+	_, err = JWKThumbprint(JSONWebKey{KeyType: "oct"})
+	require.ErrorIs(t, err, ErrInvalidDPoPProof)
+}
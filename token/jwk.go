@@ -0,0 +1,38 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+)
+
+// JSONWebKey is a public key in the JSON Web Key format (RFC 7517)
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+}
+
+// PublicKeyProvider is implemented by makers whose tokens can be verified with public keys only
+type PublicKeyProvider interface {
+	// PublicKeys returns the keys accepted to verify tokens
+	PublicKeys() []JSONWebKey
+}
+
+// NewEd25519JSONWebKey encodes an Ed25519 public key as a JSONWebKey.
+// The key ID matches the one carried by the tokens signed with this key.
+func NewEd25519JSONWebKey(keyID string, publicKey ed25519.PublicKey) JSONWebKey {
+	return JSONWebKey{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		X:         base64.RawURLEncoding.EncodeToString(publicKey),
+		KeyID:     keyID,
+		Use:       "sig",
+		Algorithm: "EdDSA",
+	}
+}
@@ -1,8 +1,8 @@
 package token
 
 import (
-	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -12,27 +12,76 @@ const minSecretKeySize = 32
 
 // JWTMaker is a JSON Web Token maker
 type JWTMaker struct {
-	secretKey string
+	mutex       sync.RWMutex
+	options     Options
+	activeKeyID string
+	secretKeys  map[string][]byte
 }
 
 // NewJWTMaker creates a new JWTMaker
 func NewJWTMaker(secretKey string) (Maker, error) {
-	if len(secretKey) < minSecretKeySize {
-		return nil, fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+	return NewJWTKeyringMaker(NewKeyring(DefaultKeyID, secretKey), Options{})
+}
+
+// NewJWTKeyringMaker creates a new JWTMaker with a keyring of secret keys
+func NewJWTKeyringMaker(keyring Keyring, options Options) (Maker, error) {
+	maker := &JWTMaker{
+		options: options,
+	}
+
+	err := maker.SetKeyring(keyring)
+	if err != nil {
+		return nil, err
 	}
-	return &JWTMaker{secretKey}, nil
+	return maker, nil
 }
 
-// CreateToken creates a new token for a specific username and duration
-func (maker *JWTMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	payload, err := NewPayload(username, duration)
+// SetKeyring replaces the secret keys of the maker
+func (maker *JWTMaker) SetKeyring(keyring Keyring) error {
+	err := keyring.validate()
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	secretKeys := make(map[string][]byte, len(keyring.Keys))
+	for keyID, secretKey := range keyring.Keys {
+		if len(secretKey) < minSecretKeySize {
+			return fmt.Errorf("invalid key size: must be at least %d characters", minSecretKeySize)
+		}
+		secretKeys[keyID] = []byte(secretKey)
 	}
 
+	maker.mutex.Lock()
+	defer maker.mutex.Unlock()
+
+	maker.activeKeyID = keyring.ActiveKeyID
+	maker.secretKeys = secretKeys
+	return nil
+}
+
+// CreateToken creates a new token for a specific username, role and duration
+func (maker *JWTMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.CreateTokenFromPayload(payload)
+	return token, payload, err
+}
+
+// CreateTokenFromPayload creates a new token carrying the given payload
+func (maker *JWTMaker) CreateTokenFromPayload(payload *Payload) (string, error) {
+	maker.options.stamp(payload)
+
+	maker.mutex.RLock()
+	keyID := maker.activeKeyID
+	secretKey := maker.secretKeys[keyID]
+	maker.mutex.RUnlock()
+
 	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, payload)
-	token, err := jwtToken.SignedString([]byte(maker.secretKey))
-	return token, err
+	jwtToken.Header["kid"] = keyID
+	return jwtToken.SignedString(secretKey)
 }
 
 // VerifyToken checks if the token is valid or not
@@ -42,15 +91,26 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		if !ok {
 			return nil, ErrInvalidToken
 		}
-		return []byte(maker.secretKey), nil
+
+		// Tokens issued before key IDs were introduced have no kid and use the active key
+		keyID, _ := token.Header["kid"].(string)
+
+		maker.mutex.RLock()
+		defer maker.mutex.RUnlock()
+
+		if keyID == "" {
+			keyID = maker.activeKeyID
+		}
+		secretKey, ok := maker.secretKeys[keyID]
+		if !ok {
+			return nil, ErrInvalidToken
+		}
+		return secretKey, nil
 	}
-	// Parse the token
-	jwtToken, err := jwt.ParseWithClaims(token, &Payload{}, keyFunc)
+	// Parse the token, its claims are checked below with the clock skew of the maker
+	parser := &jwt.Parser{SkipClaimsValidation: true}
+	jwtToken, err := parser.ParseWithClaims(token, &Payload{}, keyFunc)
 	if err != nil {
-		verr, ok := err.(*jwt.ValidationError)
-		if ok && errors.Is(verr.Inner, ErrExpiredToken) {
-			return nil, ErrExpiredToken
-		}
 		return nil, ErrInvalidToken
 	}
 
@@ -59,5 +119,10 @@ func (maker *JWTMaker) VerifyToken(token string) (*Payload, error) {
 		return nil, ErrInvalidToken
 	}
 
+	err = maker.options.validate(payload)
+	if err != nil {
+		return nil, err
+	}
+
 	return payload, nil
 }
@@ -0,0 +1,87 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/o1egl/paseto"
+)
+
+// DefaultKeyID is the ID of the key of makers created with a single key
+const DefaultKeyID = "default"
+
+/*
+Keyring is a set of keys identified by their key ID.
+The active key signs new tokens, while every key of the ring is accepted to verify them,
+so that a key can be rotated without invalidating the tokens it already signed.
+*/
+type Keyring struct {
+	ActiveKeyID string
+	Keys        map[string]string
+}
+
+// KeyRotator is implemented by makers whose keys can be replaced at runtime
+type KeyRotator interface {
+	// SetKeyring replaces the keys used to create and verify tokens
+	SetKeyring(keyring Keyring) error
+}
+
+// NewKeyring creates a keyring holding a single active key
+func NewKeyring(keyID string, key string) Keyring {
+	return Keyring{
+		ActiveKeyID: keyID,
+		Keys:        map[string]string{keyID: key},
+	}
+}
+
+// ParseKeyring parses a comma separated list of keys in the "id:key" format
+func ParseKeyring(activeKeyID string, keys string) (Keyring, error) {
+	keyring := Keyring{
+		ActiveKeyID: activeKeyID,
+		Keys:        make(map[string]string),
+	}
+
+	for _, entry := range strings.Split(keys, ",") {
+		keyID, key, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			return Keyring{}, fmt.Errorf("invalid key %q: must be in the id:key format", entry)
+		}
+		if _, ok := keyring.Keys[keyID]; ok {
+			return Keyring{}, fmt.Errorf("duplicated key id: %s", keyID)
+		}
+		keyring.Keys[keyID] = key
+	}
+
+	err := keyring.validate()
+	if err != nil {
+		return Keyring{}, err
+	}
+	return keyring, nil
+}
+
+func (keyring Keyring) validate() error {
+	for keyID := range keyring.Keys {
+		if keyID == "" {
+			return fmt.Errorf("key id must not be empty")
+		}
+	}
+	if _, ok := keyring.Keys[keyring.ActiveKeyID]; !ok {
+		return fmt.Errorf("active key %q is not in the keyring", keyring.ActiveKeyID)
+	}
+	return nil
+}
+
+// keyFooter is the PASETO footer carrying the ID of the key of a token
+type keyFooter struct {
+	KeyID string `json:"kid"`
+}
+
+// footerKeyID returns the key ID in the footer of a PASETO token, or an empty string if it has none
+func footerKeyID(token string) (string, error) {
+	footer := keyFooter{}
+	err := paseto.ParseFooter(token, &footer)
+	if err != nil {
+		return "", ErrInvalidToken
+	}
+	return footer.KeyID, nil
+}
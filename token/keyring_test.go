@@ -0,0 +1,134 @@
+package token
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKeyring(t *testing.T) {
+	key1 := util.RandomString(32)
+	key2 := util.RandomString(32)
+
+	keyring, err := ParseKeyring("k2", fmt.Sprintf("k1:%s, k2:%s", key1, key2))
+	require.NoError(t, err)
+	require.Equal(t, "k2", keyring.ActiveKeyID)
+	require.Equal(t, map[string]string{"k1": key1, "k2": key2}, keyring.Keys)
+
+	_, err = ParseKeyring("k3", fmt.Sprintf("k1:%s,k2:%s", key1, key2))
+	require.Error(t, err)
+
+	_, err = ParseKeyring("k1", key1)
+	require.Error(t, err)
+
+	_, err = ParseKeyring("k1", fmt.Sprintf("k1:%s,k1:%s", key1, key2))
+	require.Error(t, err)
+
+	_, err = ParseKeyring("", fmt.Sprintf(":%s", key1))
+	require.Error(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		randKey  func() string
+		newMaker func(keyring Keyring, options Options) (Maker, error)
+	}{
+		{
+			name:     "Paseto",
+			randKey:  func() string { return util.RandomString(32) },
+			newMaker: NewPasetoKeyringMaker,
+		},
+		{
+			name:     "PasetoPublic",
+			randKey:  randomPrivateKey,
+			newMaker: NewPasetoPublicKeyringMaker,
+		},
+		{
+			name:     "JWT",
+			randKey:  func() string { return util.RandomString(32) },
+			newMaker: NewJWTKeyringMaker,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			oldKey := tc.randKey()
+			newKey := tc.randKey()
+
+			maker, err := tc.newMaker(NewKeyring("old", oldKey), Options{})
+			require.NoError(t, err)
+
+			oldToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			// Rotate to the new key while still accepting the old one
+			rotator, ok := maker.(KeyRotator)
+			require.True(t, ok)
+			err = rotator.SetKeyring(Keyring{
+				ActiveKeyID: "new",
+				Keys:        map[string]string{"old": oldKey, "new": newKey},
+			})
+			require.NoError(t, err)
+
+			newToken, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(oldToken)
+			require.NoError(t, err)
+			_, err = maker.VerifyToken(newToken)
+			require.NoError(t, err)
+
+			// Retire the old key
+			err = rotator.SetKeyring(NewKeyring("new", newKey))
+			require.NoError(t, err)
+
+			_, err = maker.VerifyToken(oldToken)
+			require.EqualError(t, err, ErrInvalidToken.Error())
+			_, err = maker.VerifyToken(newToken)
+			require.NoError(t, err)
+
+			// An invalid keyring leaves the current keys in place
+			err = rotator.SetKeyring(NewKeyring("bad", "short"))
+			require.Error(t, err)
+			_, err = maker.VerifyToken(newToken)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestPasetoTokenWithoutKeyID(t *testing.T) {
+	symmetricKey := util.RandomString(32)
+
+	maker, err := NewPasetoMaker(symmetricKey)
+	require.NoError(t, err)
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// Tokens issued before key IDs were introduced have no footer
+	token, err := paseto.NewV2().Encrypt([]byte(symmetricKey), payload, nil)
+	require.NoError(t, err)
+
+	verified, err := maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, verified.ID)
+}
+
+func TestInvalidKeyringKey(t *testing.T) {
+	_, err := NewPasetoKeyringMaker(NewKeyring("k1", util.RandomString(16)), Options{})
+	require.Error(t, err)
+
+	_, err = NewPasetoPublicKeyringMaker(NewKeyring("k1", hex.EncodeToString([]byte(util.RandomString(16)))), Options{})
+	require.Error(t, err)
+
+	_, err = NewJWTKeyringMaker(NewKeyring("k1", util.RandomString(16)), Options{})
+	require.Error(t, err)
+}
@@ -0,0 +1,46 @@
+package token
+
+import (
+	"time"
+)
+
+// Options are the registered claims a maker sets in new tokens and requires from the tokens it verifies.
+// Several environments or services sharing keys must use different issuers or audiences,
+// so that a token issued for one of them is rejected by the others.
+type Options struct {
+	// Issuer is the iss claim of the tokens, it is not checked if empty
+	Issuer string
+	// Audience is the aud claim of the tokens, it is not checked if empty
+	Audience string
+	// ClockSkew is the tolerance when checking the exp and nbf claims,
+	// for tokens created on a server whose clock is not exactly in sync
+	ClockSkew time.Duration
+}
+
+// stamp sets the issuer and audience of the payload, unless it already has them
+func (options Options) stamp(payload *Payload) {
+	if payload.Issuer == "" {
+		payload.Issuer = options.Issuer
+	}
+	if payload.Audience == "" {
+		payload.Audience = options.Audience
+	}
+}
+
+// validate checks the registered claims of a payload
+func (options Options) validate(payload *Payload) error {
+	now := time.Now()
+	if now.After(payload.ExpiredAt.Add(options.ClockSkew)) {
+		return ErrExpiredToken
+	}
+	if now.Add(options.ClockSkew).Before(payload.NotBefore) {
+		return ErrTokenNotYetValid
+	}
+	if options.Issuer != "" && payload.Issuer != options.Issuer {
+		return ErrInvalidToken
+	}
+	if options.Audience != "" && payload.Audience != options.Audience {
+		return ErrInvalidToken
+	}
+	return nil
+}
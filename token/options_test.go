@@ -0,0 +1,153 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptions(t *testing.T) {
+	makers := []struct {
+		name     string
+		randKey  func() string
+		newMaker func(keyring Keyring, options Options) (Maker, error)
+	}{
+		{
+			name:     "Paseto",
+			randKey:  func() string { return util.RandomString(32) },
+			newMaker: NewPasetoKeyringMaker,
+		},
+		{
+			name:     "PasetoPublic",
+			randKey:  randomPrivateKey,
+			newMaker: NewPasetoPublicKeyringMaker,
+		},
+		{
+			name:     "JWT",
+			randKey:  func() string { return util.RandomString(32) },
+			newMaker: NewJWTKeyringMaker,
+		},
+	}
+
+	options := Options{
+		Issuer:    "oldbank",
+		Audience:  "oldbank-api",
+		ClockSkew: 30 * time.Second,
+	}
+
+	testCases := []struct {
+		name          string
+		issuerOptions Options
+		buildPayload  func(payload *Payload)
+		checkVerify   func(t *testing.T, payload *Payload, err error)
+	}{
+		{
+			name:          "OK",
+			issuerOptions: options,
+			buildPayload:  func(payload *Payload) {},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.NoError(t, err)
+				require.Equal(t, options.Issuer, payload.Issuer)
+				require.Equal(t, options.Audience, payload.Audience)
+				require.WithinDuration(t, payload.IssuedAt, payload.NotBefore, time.Second)
+			},
+		},
+		{
+			name:          "WrongIssuer",
+			issuerOptions: Options{Issuer: "staging", Audience: options.Audience},
+			buildPayload:  func(payload *Payload) {},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.EqualError(t, err, ErrInvalidToken.Error())
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:          "WrongAudience",
+			issuerOptions: Options{Issuer: options.Issuer, Audience: "oldbank-reports"},
+			buildPayload:  func(payload *Payload) {},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.EqualError(t, err, ErrInvalidToken.Error())
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:          "NoClaims",
+			issuerOptions: Options{},
+			buildPayload:  func(payload *Payload) {},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.EqualError(t, err, ErrInvalidToken.Error())
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:          "NotYetValid",
+			issuerOptions: options,
+			buildPayload: func(payload *Payload) {
+				payload.NotBefore = time.Now().Add(time.Minute)
+			},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.EqualError(t, err, ErrTokenNotYetValid.Error())
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:          "NotBeforeWithinClockSkew",
+			issuerOptions: options,
+			buildPayload: func(payload *Payload) {
+				payload.NotBefore = time.Now().Add(10 * time.Second)
+			},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:          "Expired",
+			issuerOptions: options,
+			buildPayload: func(payload *Payload) {
+				payload.ExpiredAt = time.Now().Add(-time.Minute)
+			},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.EqualError(t, err, ErrExpiredToken.Error())
+				require.Nil(t, payload)
+			},
+		},
+		{
+			name:          "ExpiredWithinClockSkew",
+			issuerOptions: options,
+			buildPayload: func(payload *Payload) {
+				payload.ExpiredAt = time.Now().Add(-10 * time.Second)
+			},
+			checkVerify: func(t *testing.T, payload *Payload, err error) {
+				require.NoError(t, err)
+			},
+		},
+	}
+
+	for i := range makers {
+		m := makers[i]
+		for j := range testCases {
+			tc := testCases[j]
+
+			t.Run(m.name+"/"+tc.name, func(t *testing.T) {
+				keyring := NewKeyring(DefaultKeyID, m.randKey())
+
+				issuer, err := m.newMaker(keyring, tc.issuerOptions)
+				require.NoError(t, err)
+				verifier, err := m.newMaker(keyring, options)
+				require.NoError(t, err)
+
+				payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+				require.NoError(t, err)
+				tc.buildPayload(payload)
+
+				token, err := issuer.CreateTokenFromPayload(payload)
+				require.NoError(t, err)
+
+				verified, err := verifier.VerifyToken(token)
+				tc.checkVerify(t, verified, err)
+			})
+		}
+	}
+}
@@ -2,6 +2,7 @@ package token
 
 import (
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/aead/chacha20poly1305"
@@ -10,46 +11,117 @@ import (
 
 // PasetoMaker is a PASETO token maker
 type PasetoMaker struct {
-	paseto       *paseto.V2
-	symmetricKey []byte
+	paseto        *paseto.V2
+	mutex         sync.RWMutex
+	options       Options
+	activeKeyID   string
+	symmetricKeys map[string][]byte
 }
 
 // NewPasetoMaker constructs a new PasetoMaker with the required symmetric key
 func NewPasetoMaker(symmetricKey string) (Maker, error) {
-	if len(symmetricKey) != chacha20poly1305.KeySize {
-		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
-	}
+	return NewPasetoKeyringMaker(NewKeyring(DefaultKeyID, symmetricKey), Options{})
+}
 
+// NewPasetoKeyringMaker constructs a new PasetoMaker with a keyring of symmetric keys
+func NewPasetoKeyringMaker(keyring Keyring, options Options) (Maker, error) {
 	maker := &PasetoMaker{
-		paseto:       paseto.NewV2(),
-		symmetricKey: []byte(symmetricKey),
+		paseto:  paseto.NewV2(),
+		options: options,
+	}
+
+	err := maker.SetKeyring(keyring)
+	if err != nil {
+		return nil, err
 	}
 	return maker, nil
 }
 
-// CreateToken creates a new Paseto token with the subject and duration in the payload
-func (maker *PasetoMaker) CreateToken(username string, duration time.Duration) (string, error) {
-	Payload, err := NewPayload(username, duration)
+// SetKeyring replaces the symmetric keys of the maker
+func (maker *PasetoMaker) SetKeyring(keyring Keyring) error {
+	err := keyring.validate()
+	if err != nil {
+		return err
+	}
+
+	symmetricKeys := make(map[string][]byte, len(keyring.Keys))
+	for keyID, symmetricKey := range keyring.Keys {
+		if len(symmetricKey) != chacha20poly1305.KeySize {
+			return fmt.Errorf("invalid key size: must be exactly %d characters", chacha20poly1305.KeySize)
+		}
+		symmetricKeys[keyID] = []byte(symmetricKey)
+	}
+
+	maker.mutex.Lock()
+	defer maker.mutex.Unlock()
+
+	maker.activeKeyID = keyring.ActiveKeyID
+	maker.symmetricKeys = symmetricKeys
+	return nil
+}
+
+// CreateToken creates a new Paseto token with the subject, role and duration in the payload
+func (maker *PasetoMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
 	if err != nil {
-		return "", err
+		return "", payload, err
 	}
 
-	return maker.paseto.Encrypt(maker.symmetricKey, Payload, nil)
+	token, err := maker.CreateTokenFromPayload(payload)
+	return token, payload, err
+}
+
+// CreateTokenFromPayload creates a new token carrying the given payload
+func (maker *PasetoMaker) CreateTokenFromPayload(payload *Payload) (string, error) {
+	maker.options.stamp(payload)
+
+	maker.mutex.RLock()
+	keyID := maker.activeKeyID
+	symmetricKey := maker.symmetricKeys[keyID]
+	maker.mutex.RUnlock()
+
+	return maker.paseto.Encrypt(symmetricKey, payload, keyFooter{KeyID: keyID})
 }
 
 // VerifyToken checks if the token is valid or not
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
+	symmetricKey, err := maker.verificationKey(token)
+	if err != nil {
+		return nil, err
+	}
+
 	payload := &Payload{}
 
-	err := maker.paseto.Decrypt(token, maker.symmetricKey, payload, nil)
+	err = maker.paseto.Decrypt(token, symmetricKey, payload, nil)
 	if err != nil {
 		return nil, ErrInvalidToken
 	}
 
-	err = payload.Valid()
+	err = maker.options.validate(payload)
 	if err != nil {
 		return nil, err
 	}
 
 	return payload, nil
 }
+
+// verificationKey returns the key identified by the token footer.
+// Tokens issued before key IDs were introduced have no footer and use the active key.
+func (maker *PasetoMaker) verificationKey(token string) ([]byte, error) {
+	keyID, err := footerKeyID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	maker.mutex.RLock()
+	defer maker.mutex.RUnlock()
+
+	if keyID == "" {
+		keyID = maker.activeKeyID
+	}
+	symmetricKey, ok := maker.symmetricKeys[keyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return symmetricKey, nil
+}
@@ -0,0 +1,144 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/o1egl/paseto"
+)
+
+// PasetoPublicMaker is a PASETO token maker signing v2.public tokens with Ed25519 keys
+type PasetoPublicMaker struct {
+	paseto      *paseto.V2
+	mutex       sync.RWMutex
+	options     Options
+	activeKeyID string
+	privateKeys map[string]ed25519.PrivateKey
+}
+
+// NewPasetoPublicMaker constructs a new PasetoPublicMaker from a hex encoded Ed25519 seed
+func NewPasetoPublicMaker(privateKey string) (Maker, error) {
+	return NewPasetoPublicKeyringMaker(NewKeyring(DefaultKeyID, privateKey), Options{})
+}
+
+// NewPasetoPublicKeyringMaker constructs a new PasetoPublicMaker with a keyring of hex encoded Ed25519 seeds
+func NewPasetoPublicKeyringMaker(keyring Keyring, options Options) (Maker, error) {
+	maker := &PasetoPublicMaker{
+		paseto:  paseto.NewV2(),
+		options: options,
+	}
+
+	err := maker.SetKeyring(keyring)
+	if err != nil {
+		return nil, err
+	}
+	return maker, nil
+}
+
+// SetKeyring replaces the private keys of the maker
+func (maker *PasetoPublicMaker) SetKeyring(keyring Keyring) error {
+	err := keyring.validate()
+	if err != nil {
+		return err
+	}
+
+	privateKeys := make(map[string]ed25519.PrivateKey, len(keyring.Keys))
+	for keyID, privateKey := range keyring.Keys {
+		seed, err := hex.DecodeString(privateKey)
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return fmt.Errorf("invalid private key: must be a hex encoded %d bytes Ed25519 seed", ed25519.SeedSize)
+		}
+		privateKeys[keyID] = ed25519.NewKeyFromSeed(seed)
+	}
+
+	maker.mutex.Lock()
+	defer maker.mutex.Unlock()
+
+	maker.activeKeyID = keyring.ActiveKeyID
+	maker.privateKeys = privateKeys
+	return nil
+}
+
+// CreateToken creates a new signed Paseto token with the subject, role and duration in the payload
+func (maker *PasetoPublicMaker) CreateToken(username string, role string, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, duration)
+	if err != nil {
+		return "", payload, err
+	}
+
+	token, err := maker.CreateTokenFromPayload(payload)
+	return token, payload, err
+}
+
+// CreateTokenFromPayload creates a new token carrying the given payload
+func (maker *PasetoPublicMaker) CreateTokenFromPayload(payload *Payload) (string, error) {
+	maker.options.stamp(payload)
+
+	maker.mutex.RLock()
+	keyID := maker.activeKeyID
+	privateKey := maker.privateKeys[keyID]
+	maker.mutex.RUnlock()
+
+	return maker.paseto.Sign(privateKey, payload, keyFooter{KeyID: keyID})
+}
+
+// VerifyToken checks if the token is valid or not
+func (maker *PasetoPublicMaker) VerifyToken(token string) (*Payload, error) {
+	publicKey, err := maker.verificationKey(token)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := &Payload{}
+
+	err = maker.paseto.Verify(token, publicKey, payload, nil)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	err = maker.options.validate(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return payload, nil
+}
+
+// verificationKey returns the public key identified by the token footer
+func (maker *PasetoPublicMaker) verificationKey(token string) (ed25519.PublicKey, error) {
+	keyID, err := footerKeyID(token)
+	if err != nil {
+		return nil, err
+	}
+
+	maker.mutex.RLock()
+	defer maker.mutex.RUnlock()
+
+	if keyID == "" {
+		keyID = maker.activeKeyID
+	}
+	privateKey, ok := maker.privateKeys[keyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return privateKey.Public().(ed25519.PublicKey), nil
+}
+
+// PublicKeys returns the public keys verifying the tokens of this maker, sorted by key ID
+func (maker *PasetoPublicMaker) PublicKeys() []JSONWebKey {
+	maker.mutex.RLock()
+	defer maker.mutex.RUnlock()
+
+	keys := make([]JSONWebKey, 0, len(maker.privateKeys))
+	for keyID, privateKey := range maker.privateKeys {
+		keys = append(keys, NewEd25519JSONWebKey(keyID, privateKey.Public().(ed25519.PublicKey)))
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].KeyID < keys[j].KeyID
+	})
+	return keys
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/o1egl/paseto"
+	"github.com/stretchr/testify/require"
+)
+
+func randomPrivateKey() string {
+	return hex.EncodeToString([]byte(util.RandomString(ed25519.SeedSize)))
+}
+
+func TestPasetoPublicMaker(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomPrivateKey())
+	require.NoError(t, err)
+
+	username := util.RandomOwner()
+	role := util.DepositorRole
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, role, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+	require.Contains(t, token, "v2.public.")
+
+	payload, err = maker.VerifyToken(token)
+	require.NoError(t, err)
+	require.NotEmpty(t, payload)
+
+	require.NotZero(t, payload.ID)
+	require.Equal(t, username, payload.Username)
+	require.Equal(t, role, payload.Role)
+	require.WithinDuration(t, issuedAt, payload.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, payload.ExpiredAt, time.Second)
+}
+
+func TestExpiredPasetoPublicToken(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomPrivateKey())
+	require.NoError(t, err)
+
+	token, payload, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	require.NotEmpty(t, payload)
+
+	payload, err = maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrExpiredToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestPasetoPublicTokenOfAnotherKey(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomPrivateKey())
+	require.NoError(t, err)
+	otherMaker, err := NewPasetoPublicMaker(randomPrivateKey())
+	require.NoError(t, err)
+
+	token, _, err := otherMaker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token)
+	require.Error(t, err)
+	require.EqualError(t, err, ErrInvalidToken.Error())
+	require.Nil(t, payload)
+}
+
+func TestInvalidPasetoPublicKey(t *testing.T) {
+	_, err := NewPasetoPublicMaker("not-hex")
+	require.Error(t, err)
+
+	_, err = NewPasetoPublicMaker(hex.EncodeToString([]byte(util.RandomString(16))))
+	require.Error(t, err)
+}
+
+func TestPasetoPublicMakerPublicKeys(t *testing.T) {
+	maker, err := NewPasetoPublicMaker(randomPrivateKey())
+	require.NoError(t, err)
+
+	provider, ok := maker.(PublicKeyProvider)
+	require.True(t, ok)
+
+	keys := provider.PublicKeys()
+	require.Len(t, keys, 1)
+	require.Equal(t, "OKP", keys[0].KeyType)
+	require.Equal(t, "Ed25519", keys[0].Curve)
+	require.Equal(t, "EdDSA", keys[0].Algorithm)
+	require.Equal(t, DefaultKeyID, keys[0].KeyID)
+
+	// Tokens of the maker must be verifiable with the published key alone
+	x, err := base64.RawURLEncoding.DecodeString(keys[0].X)
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	payload := &Payload{}
+	err = paseto.NewV2().Verify(token, ed25519.PublicKey(x), payload, nil)
+	require.NoError(t, err)
+	require.NotZero(t, payload.ID)
+}
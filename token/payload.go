@@ -9,8 +9,21 @@ import (
 
 // Different types of error returned by the VerifyToken function
 var (
-	ErrInvalidToken = errors.New("token is invalid")
-	ErrExpiredToken = errors.New("token has expired")
+	ErrInvalidToken     = errors.New("token is invalid")
+	ErrExpiredToken     = errors.New("token has expired")
+	ErrRevokedToken     = errors.New("token has been revoked")
+	ErrTokenNotYetValid = errors.New("token is not valid yet")
+)
+
+// Purposes of the tokens that are not access tokens
+const (
+	// PurposeRefresh marks refresh tokens, which can only be exchanged for access tokens
+	PurposeRefresh = "refresh"
+	// PurposeMFAPending marks tokens proving the password of a user whose second factor is still to be checked
+	PurposeMFAPending = "mfa_pending"
+	// PurposeWebAuthnRegistration and PurposeWebAuthnLogin mark tokens carrying the challenge of a WebAuthn ceremony
+	PurposeWebAuthnRegistration = "webauthn_registration"
+	PurposeWebAuthnLogin        = "webauthn_login"
 )
 
 // Payload contains the payload data of the token
@@ -20,27 +33,78 @@ type Payload struct {
 	Role      string    `json:"role"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// NotBefore is the time before which the token must not be accepted
+	NotBefore time.Time `json:"nbf"`
+	// Issuer and Audience are set by the maker creating the token
+	Issuer   string `json:"iss,omitempty"`
+	Audience string `json:"aud,omitempty"`
+	// Purpose restricts what the token can be used for, it is empty for access tokens
+	Purpose string `json:"purpose,omitempty"`
+	// Scopes restricts what the token can access, it is empty for tokens with the full access of their user
+	Scopes []string `json:"scopes,omitempty"`
+	// ClientID is the OAuth client the token was issued to, it is empty for tokens issued to the user
+	ClientID string `json:"client_id,omitempty"`
+	// SessionID is the login session an access token was issued from, it is the zero UUID for other tokens
+	SessionID uuid.UUID `json:"session_id"`
+	// Confirmation binds the token to the DPoP key of its client, it is nil for bearer tokens
+	Confirmation *Confirmation `json:"cnf,omitempty"`
+}
+
+// Confirmation identifies the key a token is bound to (RFC 7800)
+type Confirmation struct {
+	// JWKThumbprint is the RFC 7638 thumbprint of the DPoP public key
+	JWKThumbprint string `json:"jkt"`
 }
 
-// NewPayload creates a new token payload with a specific username and duration
-func NewPayload(username string, duration time.Duration) (*Payload, error) {
+// NewPayload creates a new token payload with a specific username, role and duration
+func NewPayload(username string, role string, duration time.Duration) (*Payload, error) {
 	tokenID, err := uuid.NewRandom()
 	if err != nil {
 		return nil, err
 	}
 
+	now := time.Now()
 	payload := &Payload{
 		ID:        tokenID,
 		Username:  username,
-		IssuedAt:  time.Now(),
-		ExpiredAt: time.Now().Add(duration),
+		Role:      role,
+		IssuedAt:  now,
+		ExpiredAt: now.Add(duration),
+		NotBefore: now,
 	}
 	return payload, nil
 }
 
+// Valid checks if the token payload is valid or not
 func (payload *Payload) Valid() error {
 	if time.Now().After(payload.ExpiredAt) {
 		return ErrExpiredToken
 	}
 	return nil
 }
+
+// IsDPoPBound returns true if the token can only be used with a DPoP proof
+func (payload *Payload) IsDPoPBound() bool {
+	return payload.Confirmation != nil && payload.Confirmation.JWKThumbprint != ""
+}
+
+// HasScopes returns true if the token grants all the scopes
+func (payload *Payload) HasScopes(scopes ...string) bool {
+	if len(payload.Scopes) == 0 {
+		return true
+	}
+
+	for _, scope := range scopes {
+		found := false
+		for _, granted := range payload.Scopes {
+			if granted == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReplayCache remembers identifiers that can only be used once, such as the jti of DPoP proofs
+type ReplayCache interface {
+	// Use records the identifier until expiresAt. It returns false if the identifier was already used.
+	Use(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+
+	// Prune forgets the identifiers that have expired
+	Prune(ctx context.Context) error
+}
+
+// MemoryReplayCache is a ReplayCache that keeps identifiers in memory
+type MemoryReplayCache struct {
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+// NewMemoryReplayCache creates a new empty MemoryReplayCache
+func NewMemoryReplayCache() *MemoryReplayCache {
+	return &MemoryReplayCache{
+		ids: make(map[string]time.Time),
+	}
+}
+
+// Use records the identifier until expiresAt. It returns false if the identifier was already used.
+func (cache *MemoryReplayCache) Use(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if _, ok := cache.ids[id]; ok {
+		return false, nil
+	}
+	cache.ids[id] = expiresAt
+	return true, nil
+}
+
+// Prune forgets the identifiers that have expired
+func (cache *MemoryReplayCache) Prune(ctx context.Context) error {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+	for id, expiresAt := range cache.ids {
+		if now.After(expiresAt) {
+			delete(cache.ids, id)
+		}
+	}
+	return nil
+}
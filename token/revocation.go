@@ -0,0 +1,130 @@
+package token
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RevocationStore keeps track of tokens that were revoked before they expired
+type RevocationStore interface {
+	// RevokeToken revokes a single token until it expires
+	RevokeToken(ctx context.Context, tokenID uuid.UUID, username string, expiresAt time.Time) error
+
+	// ConsumeToken revokes a single use token until it expires. It returns false if the token
+	// was already revoked, so that only one of concurrent uses of the token succeeds.
+	ConsumeToken(ctx context.Context, tokenID uuid.UUID, username string, expiresAt time.Time) (bool, error)
+
+	// RevokeUserTokens revokes every token of a user issued at or before issuedBefore.
+	// The revocation is kept until expiresAt, after which all those tokens are expired anyway.
+	// A previous revocation of the user is only ever extended, never shortened.
+	RevokeUserTokens(ctx context.Context, username string, issuedBefore time.Time, expiresAt time.Time) error
+
+	// IsRevoked checks if the token with the given payload has been revoked,
+	// either on its own, through the session it was issued from or through its user
+	IsRevoked(ctx context.Context, payload *Payload) (bool, error)
+
+	// Prune deletes the revocations of tokens that have already expired
+	Prune(ctx context.Context) error
+}
+
+type userRevocation struct {
+	issuedBefore time.Time
+	expiresAt    time.Time
+}
+
+// MemoryRevocationStore is a RevocationStore that keeps revocations in memory
+type MemoryRevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[uuid.UUID]time.Time
+	users  map[string]userRevocation
+}
+
+// NewMemoryRevocationStore creates a new empty MemoryRevocationStore
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{
+		tokens: make(map[uuid.UUID]time.Time),
+		users:  make(map[string]userRevocation),
+	}
+}
+
+// RevokeToken revokes a single token until it expires
+func (store *MemoryRevocationStore) RevokeToken(ctx context.Context, tokenID uuid.UUID, username string, expiresAt time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.tokens[tokenID] = expiresAt
+	return nil
+}
+
+// ConsumeToken revokes a single use token until it expires, it returns false if it was already revoked
+func (store *MemoryRevocationStore) ConsumeToken(ctx context.Context, tokenID uuid.UUID, username string, expiresAt time.Time) (bool, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if _, ok := store.tokens[tokenID]; ok {
+		return false, nil
+	}
+	store.tokens[tokenID] = expiresAt
+	return true, nil
+}
+
+// RevokeUserTokens revokes every token of a user issued at or before issuedBefore
+func (store *MemoryRevocationStore) RevokeUserTokens(ctx context.Context, username string, issuedBefore time.Time, expiresAt time.Time) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	revocation := store.users[username]
+	if issuedBefore.After(revocation.issuedBefore) {
+		revocation.issuedBefore = issuedBefore
+	}
+	if expiresAt.After(revocation.expiresAt) {
+		revocation.expiresAt = expiresAt
+	}
+	store.users[username] = revocation
+	return nil
+}
+
+// IsRevoked checks if the token with the given payload has been revoked
+func (store *MemoryRevocationStore) IsRevoked(ctx context.Context, payload *Payload) (bool, error) {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if _, ok := store.tokens[payload.ID]; ok {
+		return true, nil
+	}
+
+	if payload.SessionID != uuid.Nil {
+		if _, ok := store.tokens[payload.SessionID]; ok {
+			return true, nil
+		}
+	}
+
+	revocation, ok := store.users[payload.Username]
+	if ok && !payload.IssuedAt.After(revocation.issuedBefore) {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// Prune deletes the revocations of tokens that have already expired
+func (store *MemoryRevocationStore) Prune(ctx context.Context) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := time.Now()
+	for tokenID, expiresAt := range store.tokens {
+		if now.After(expiresAt) {
+			delete(store.tokens, tokenID)
+		}
+	}
+	for username, revocation := range store.users {
+		if now.After(revocation.expiresAt) {
+			delete(store.users, username)
+		}
+	}
+	return nil
+}
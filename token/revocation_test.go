@@ -0,0 +1,139 @@
+package token
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/JMustang/OldBank/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRevokeToken(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	payload1, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	payload2, err := NewPayload(payload1.Username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeToken(context.Background(), payload1.ID, payload1.Username, payload1.ExpiredAt)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), payload1)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), payload2)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryRevokeSession(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	refreshPayload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	accessPayload, err := NewPayload(refreshPayload.Username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	accessPayload.SessionID = refreshPayload.ID
+	otherPayload, err := NewPayload(refreshPayload.Username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	// Sessions have the ID of their refresh token
+	err = store.RevokeToken(context.Background(), refreshPayload.ID, refreshPayload.Username, refreshPayload.ExpiredAt)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), accessPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryConsumeToken(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	payload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	consumed, err := store.ConsumeToken(context.Background(), payload.ID, payload.Username, payload.ExpiredAt)
+	require.NoError(t, err)
+	require.True(t, consumed)
+
+	// A token can only be consumed once
+	consumed, err = store.ConsumeToken(context.Background(), payload.ID, payload.Username, payload.ExpiredAt)
+	require.NoError(t, err)
+	require.False(t, consumed)
+
+	revoked, err := store.IsRevoked(context.Background(), payload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+}
+
+func TestMemoryRevokeUserTokens(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	oldPayload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+	otherPayload, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	err = store.RevokeUserTokens(context.Background(), oldPayload.Username, time.Now(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+
+	newPayload, err := NewPayload(oldPayload.Username, util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	revoked, err := store.IsRevoked(context.Background(), oldPayload)
+	require.NoError(t, err)
+	require.True(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), newPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+
+	revoked, err = store.IsRevoked(context.Background(), otherPayload)
+	require.NoError(t, err)
+	require.False(t, revoked)
+}
+
+func TestMemoryRevokeUserTokensKeepsLongest(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	username := util.RandomOwner()
+
+	issuedBefore := time.Now()
+	expiresAt := issuedBefore.Add(time.Hour)
+	err := store.RevokeUserTokens(context.Background(), username, issuedBefore, expiresAt)
+	require.NoError(t, err)
+
+	// A shorter revocation of earlier tokens does not cut the previous one down
+	err = store.RevokeUserTokens(context.Background(), username, issuedBefore.Add(-time.Second), issuedBefore.Add(time.Minute))
+	require.NoError(t, err)
+
+	revocation := store.users[username]
+	require.Equal(t, issuedBefore, revocation.issuedBefore)
+	require.Equal(t, expiresAt, revocation.expiresAt)
+}
+
+func TestMemoryPruneRevocations(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	expired, err := NewPayload(util.RandomOwner(), util.DepositorRole, -time.Minute)
+	require.NoError(t, err)
+	active, err := NewPayload(util.RandomOwner(), util.DepositorRole, time.Minute)
+	require.NoError(t, err)
+
+	require.NoError(t, store.RevokeToken(context.Background(), expired.ID, expired.Username, expired.ExpiredAt))
+	require.NoError(t, store.RevokeToken(context.Background(), active.ID, active.Username, active.ExpiredAt))
+	require.NoError(t, store.RevokeUserTokens(context.Background(), expired.Username, time.Now(), time.Now().Add(-time.Second)))
+
+	err = store.Prune(context.Background())
+	require.NoError(t, err)
+
+	require.NotContains(t, store.tokens, expired.ID)
+	require.Contains(t, store.tokens, active.ID)
+	require.NotContains(t, store.users, expired.Username)
+}
@@ -11,11 +11,66 @@ Config stores all configuration of the application.
 The values are read by viper from a config file or environment variables.
 */
 type Config struct {
-	DBDriver            string        `mapstructure:"DB_DRIVER"`
-	DBSource            string        `mapstructure:"DB_SOURCE"`
-	ServerAddress       string        `mapstructure:"SERVER_ADDRESS"`
-	TokenSymmetricKey   string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
-	AccessTokenDuration time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	DBDriver                    string        `mapstructure:"DB_DRIVER"`
+	DBSource                    string        `mapstructure:"DB_SOURCE"`
+	ServerAddress               string        `mapstructure:"SERVER_ADDRESS"`
+	TokenMaker                  string        `mapstructure:"TOKEN_MAKER"`
+	TokenSymmetricKey           string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	TokenPrivateKey             string        `mapstructure:"TOKEN_PRIVATE_KEY"`
+	TokenKeys                   string        `mapstructure:"TOKEN_KEYS"`
+	TokenActiveKeyID            string        `mapstructure:"TOKEN_ACTIVE_KEY_ID"`
+	TokenIssuer                 string        `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience               string        `mapstructure:"TOKEN_AUDIENCE"`
+	TokenClockSkew              time.Duration `mapstructure:"TOKEN_CLOCK_SKEW"`
+	AccessTokenDuration         time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration        time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	PasswordHashAlgorithm       string        `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	Argon2Memory                uint32        `mapstructure:"ARGON2_MEMORY"`
+	Argon2Iterations            uint32        `mapstructure:"ARGON2_ITERATIONS"`
+	Argon2Parallelism           uint8         `mapstructure:"ARGON2_PARALLELISM"`
+	BcryptCost                  int           `mapstructure:"BCRYPT_COST"`
+	TOTPEncryptionKey           string        `mapstructure:"TOTP_ENCRYPTION_KEY"`
+	TOTPIssuer                  string        `mapstructure:"TOTP_ISSUER"`
+	MFATokenDuration            time.Duration `mapstructure:"MFA_TOKEN_DURATION"`
+	LoginMaxFailedAttempts      int32         `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS"`
+	LoginMaxFailedAttemptsPerIP int32         `mapstructure:"LOGIN_MAX_FAILED_ATTEMPTS_PER_IP"`
+	LoginBaseDelay              time.Duration `mapstructure:"LOGIN_BASE_DELAY"`
+	LoginLockoutDuration        time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	PasswordResetTokenDuration  time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_DURATION"`
+	PasswordResetURL            string        `mapstructure:"PASSWORD_RESET_URL"`
+	VerifyEmailDuration         time.Duration `mapstructure:"VERIFY_EMAIL_DURATION"`
+	VerifyEmailURL              string        `mapstructure:"VERIFY_EMAIL_URL"`
+	RequireVerifiedEmail        bool          `mapstructure:"REQUIRE_VERIFIED_EMAIL"`
+	MailSender                  string        `mapstructure:"MAIL_SENDER"`
+	MailFrom                    string        `mapstructure:"MAIL_FROM"`
+	MailFileDir                 string        `mapstructure:"MAIL_FILE_DIR"`
+	MailDispatchInterval        time.Duration `mapstructure:"MAIL_DISPATCH_INTERVAL"`
+	SMTPAddress                 string        `mapstructure:"SMTP_ADDRESS"`
+	SMTPUsername                string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword                string        `mapstructure:"SMTP_PASSWORD"`
+	OAuthCodeDuration           time.Duration `mapstructure:"OAUTH_CODE_DURATION"`
+	OAuthAccessTokenDuration    time.Duration `mapstructure:"OAUTH_ACCESS_TOKEN_DURATION"`
+	WebAuthnRPID                string        `mapstructure:"WEBAUTHN_RP_ID"`
+	WebAuthnRPName              string        `mapstructure:"WEBAUTHN_RP_NAME"`
+	WebAuthnOrigins             string        `mapstructure:"WEBAUTHN_ORIGINS"`
+	WebAuthnTimeout             time.Duration `mapstructure:"WEBAUTHN_TIMEOUT"`
+	StepUpThresholds            string        `mapstructure:"STEP_UP_THRESHOLDS"`
+	StepUpWindow                time.Duration `mapstructure:"STEP_UP_WINDOW"`
+	IdempotencyKeyDuration      time.Duration `mapstructure:"IDEMPOTENCY_KEY_DURATION"`
+	FXSpreadBps                 int32         `mapstructure:"FX_SPREAD_BPS"`
+	FXQuoteDuration             time.Duration `mapstructure:"FX_QUOTE_DURATION"`
+	ScheduledTransferInterval   time.Duration `mapstructure:"SCHEDULED_TRANSFER_INTERVAL"`
+	DPoPProofMaxAge             time.Duration `mapstructure:"DPOP_PROOF_MAX_AGE"`
+	DPoPReplayCache             string        `mapstructure:"DPOP_REPLAY_CACHE"`
+	DPoPExternalURL             string        `mapstructure:"DPOP_EXTERNAL_URL"`
+	TLSCertFile                 string        `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile                  string        `mapstructure:"TLS_KEY_FILE"`
+	TLSClientCAFile             string        `mapstructure:"TLS_CLIENT_CA_FILE"`
+	TLSClientAuth               string        `mapstructure:"TLS_CLIENT_AUTH"`
+	TLSServiceIdentities        string        `mapstructure:"TLS_SERVICE_IDENTITIES"`
+	TLSReloadInterval           time.Duration `mapstructure:"TLS_RELOAD_INTERVAL"`
+	RevocationStore             string        `mapstructure:"REVOCATION_STORE"`
+	RevocationPruneInterval     time.Duration `mapstructure:"REVOCATION_PRUNE_INTERVAL"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
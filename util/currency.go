@@ -1,5 +1,11 @@
 package util
 
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
 const (
 	USD = "USD"
 	EUR = "EUR"
@@ -13,3 +19,20 @@ func IsSupportedCurrency(currency string) bool {
 	}
 	return false
 }
+
+// ParseCurrencyAmounts parses a space separated list of amounts per currency, like "USD:1000000 EUR:900000"
+func ParseCurrencyAmounts(s string) (map[string]int64, error) {
+	amounts := make(map[string]int64)
+	for _, field := range strings.Fields(s) {
+		currency, value, found := strings.Cut(field, ":")
+		if !found || !IsSupportedCurrency(currency) {
+			return nil, fmt.Errorf("invalid currency amount: %s", field)
+		}
+		amount, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || amount < 0 {
+			return nil, fmt.Errorf("invalid currency amount: %s", field)
+		}
+		amounts[currency] = amount
+	}
+	return amounts, nil
+}
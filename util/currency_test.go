@@ -0,0 +1,22 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCurrencyAmounts(t *testing.T) {
+	amounts, err := ParseCurrencyAmounts("USD:1000000  EUR:900000\tCAD:0")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int64{USD: 1000000, EUR: 900000, CAD: 0}, amounts)
+
+	amounts, err = ParseCurrencyAmounts("")
+	require.NoError(t, err)
+	require.Empty(t, amounts)
+
+	for _, s := range []string{"USD", "USD:", "GBP:100", "USD:-1", "USD:1.5", ":100"} {
+		_, err = ParseCurrencyAmounts(s)
+		require.Error(t, err, s)
+	}
+}
@@ -0,0 +1,30 @@
+package util
+
+import (
+	"fmt"
+	"math/big"
+)
+
+const (
+	// FXRateScale is the fixed-point scale of exchange rates, so that a rate of 1.0825 is stored as 108250000
+	FXRateScale = 100_000_000
+	// MaxSpreadBps is the spread taking the whole amount, in basis points
+	MaxSpreadBps = 10_000
+)
+
+// ApplySpread lowers the rate by the spread, in basis points, rounding down in favor of the bank
+func ApplySpread(rate int64, spreadBps int32) int64 {
+	result := new(big.Int).Mul(big.NewInt(rate), big.NewInt(int64(MaxSpreadBps-spreadBps)))
+	return result.Quo(result, big.NewInt(MaxSpreadBps)).Int64()
+}
+
+// ConvertAmount converts an amount at the rate, rounding down. All supported currencies have two decimals,
+// so that the amounts of both currencies are in cents.
+func ConvertAmount(amount int64, rate int64) (int64, error) {
+	result := new(big.Int).Mul(big.NewInt(amount), big.NewInt(rate))
+	result.Quo(result, big.NewInt(FXRateScale))
+	if !result.IsInt64() {
+		return 0, fmt.Errorf("converted amount of %d at rate %d is out of range", amount, rate)
+	}
+	return result.Int64(), nil
+}
@@ -0,0 +1,34 @@
+package util
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySpread(t *testing.T) {
+	require.Equal(t, int64(108250000), ApplySpread(108250000, 0))
+	require.Equal(t, int64(107708750), ApplySpread(108250000, 50))
+	require.Equal(t, int64(0), ApplySpread(108250000, MaxSpreadBps))
+	// rounds down
+	require.Equal(t, int64(1), ApplySpread(3, 5000))
+}
+
+func TestConvertAmount(t *testing.T) {
+	amount, err := ConvertAmount(10000, 108250000)
+	require.NoError(t, err)
+	require.Equal(t, int64(10825), amount)
+
+	// rounds down
+	amount, err = ConvertAmount(1, 150000000)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), amount)
+
+	amount, err = ConvertAmount(math.MaxInt64, FXRateScale)
+	require.NoError(t, err)
+	require.Equal(t, int64(math.MaxInt64), amount)
+
+	_, err = ConvertAmount(math.MaxInt64, 2*FXRateScale)
+	require.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ServiceUsernamePrefix prefixes the usernames of service identities. Usernames of users are
+// alphanumeric, so a user can never register the username of a service.
+const ServiceUsernamePrefix = "service:"
+
+// ServiceIdentity is the identity, role and scopes granted to an internal caller authenticated by its client certificate
+type ServiceIdentity struct {
+	Name   string
+	Role   string
+	Scopes []string
+}
+
+// Username is the username the service acts as
+func (identity ServiceIdentity) Username() string {
+	return ServiceUsernamePrefix + identity.Name
+}
+
+// ParseServiceIdentities parses a space separated list of certificate common names mapped to service identities,
+// like "reconciliation.oldbank.internal:reconciliation:banker:accounts:read", where each entry is
+// common_name:identity:role:scopes and scopes is a comma separated list of at least one scope.
+func ParseServiceIdentities(s string) (map[string]ServiceIdentity, error) {
+	identities := make(map[string]ServiceIdentity)
+	for _, field := range strings.Fields(s) {
+		parts := strings.SplitN(field, ":", 4)
+		if len(parts) != 4 || parts[0] == "" || parts[1] == "" || !IsSupportedRole(parts[2]) {
+			return nil, fmt.Errorf("invalid service identity: %s", field)
+		}
+		if _, ok := identities[parts[0]]; ok {
+			return nil, fmt.Errorf("duplicate service identity: %s", parts[0])
+		}
+
+		scopes := strings.Split(parts[3], ",")
+		for _, scope := range scopes {
+			if !IsSupportedScope(scope) {
+				return nil, fmt.Errorf("invalid scope of service identity %s: %s", parts[0], scope)
+			}
+		}
+
+		identities[parts[0]] = ServiceIdentity{
+			Name:   parts[1],
+			Role:   parts[2],
+			Scopes: scopes,
+		}
+	}
+	return identities, nil
+}
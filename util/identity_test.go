@@ -0,0 +1,37 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseServiceIdentities(t *testing.T) {
+	identities, err := ParseServiceIdentities("ledger.internal:ledger:banker:accounts:read,transfers:write  ops.internal:ops:admin:accounts:read")
+	require.NoError(t, err)
+	require.Equal(t, map[string]ServiceIdentity{
+		"ledger.internal": {Name: "ledger", Role: BankerRole, Scopes: []string{AccountsReadScope, TransfersWriteScope}},
+		"ops.internal":    {Name: "ops", Role: AdminRole, Scopes: []string{AccountsReadScope}},
+	}, identities)
+	require.Equal(t, "service:ledger", identities["ledger.internal"].Username())
+
+	identities, err = ParseServiceIdentities("")
+	require.NoError(t, err)
+	require.Empty(t, identities)
+
+	for _, s := range []string{
+		"ledger.internal",
+		"ledger.internal:ledger",
+		"ledger.internal:ledger:banker",
+		"ledger.internal:ledger:banker:",
+		"ledger.internal:ledger:banker:users:write",
+		"ledger.internal:ledger:banker:accounts:read,",
+		"ledger.internal:ledger:owner:accounts:read",
+		":ledger:banker:accounts:read",
+		"ledger.internal::banker:accounts:read",
+		"ledger.internal:ledger:banker:accounts:read ledger.internal:other:admin:accounts:read",
+	} {
+		_, err = ParseServiceIdentities(s)
+		require.Error(t, err, s)
+	}
+}
@@ -1,21 +1,232 @@
 package util
 
 import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword retuns the bcrypt hash of the password
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// Supported password hashing algorithms
+const (
+	Argon2idAlgorithm = "argon2id"
+	BcryptAlgorithm   = "bcrypt"
+)
+
+// Default Argon2id parameters, as recommended by OWASP
+const (
+	DefaultArgon2Memory      uint32 = 19 * 1024
+	DefaultArgon2Iterations  uint32 = 2
+	DefaultArgon2Parallelism uint8  = 1
+
+	argon2SaltLength uint32 = 16
+	argon2KeyLength  uint32 = 32
+)
+
+var (
+	ErrMismatchedPassword    = errors.New("hashed password is not the hash of the given password")
+	ErrUnsupportedPassword   = errors.New("hashed password is in an unsupported format")
+	errInvalidArgon2idFormat = errors.New("invalid argon2id hash format")
+)
+
+// PasswordHasher hashes passwords with a given algorithm and parameters
+type PasswordHasher interface {
+	// HashPassword returns the hash of the password in a self describing format
+	HashPassword(password string) (string, error)
+	// NeedsRehash reports whether the hash was not created with the algorithm and parameters of the hasher
+	NeedsRehash(hashedPassword string) bool
+}
+
+// NewPasswordHasher creates the password hasher configured by PASSWORD_HASH_ALGORITHM.
+// Zero parameters are replaced by their default value.
+func NewPasswordHasher(config Config) (PasswordHasher, error) {
+	switch config.PasswordHashAlgorithm {
+	case "", Argon2idAlgorithm:
+		return NewArgon2idHasher(config.Argon2Memory, config.Argon2Iterations, config.Argon2Parallelism), nil
+	case BcryptAlgorithm:
+		return NewBcryptHasher(config.BcryptCost), nil
+	}
+	return nil, fmt.Errorf("unsupported password hash algorithm: %s", config.PasswordHashAlgorithm)
+}
+
+// Argon2idHasher hashes passwords with Argon2id into the PHC string format
+type Argon2idHasher struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// NewArgon2idHasher creates a new Argon2idHasher, memory being in KiB
+func NewArgon2idHasher(memory uint32, iterations uint32, parallelism uint8) PasswordHasher {
+	if memory == 0 {
+		memory = DefaultArgon2Memory
+	}
+	if iterations == 0 {
+		iterations = DefaultArgon2Iterations
+	}
+	if parallelism == 0 {
+		parallelism = DefaultArgon2Parallelism
+	}
+
+	return &Argon2idHasher{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+	}
+}
+
+// HashPassword returns the Argon2id hash of the password, in the $argon2id$v=19$m=,t=,p=$salt$key format
+func (hasher *Argon2idHasher) HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	hash := argon2idHash{
+		memory:      hasher.memory,
+		iterations:  hasher.iterations,
+		parallelism: hasher.parallelism,
+		salt:        salt,
+	}
+	hash.key = hash.derive(password, argon2KeyLength)
+	return hash.String(), nil
+}
+
+// NeedsRehash reports whether the hash is not an Argon2id hash with the parameters of the hasher
+func (hasher *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	hash, err := parseArgon2idHash(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return hash.memory != hasher.memory ||
+		hash.iterations != hasher.iterations ||
+		hash.parallelism != hasher.parallelism ||
+		uint32(len(hash.key)) != argon2KeyLength
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a new BcryptHasher
+func NewBcryptHasher(cost int) PasswordHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
+}
+
+// HashPassword returns the bcrypt hash of the password.
+// It fails for passwords longer than 72 bytes, which bcrypt would truncate.
+func (hasher *BcryptHasher) HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
 	if err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
 	return string(hashedPassword), nil
 }
 
-// CheckPassword checks if the provided password is correct or not
+// NeedsRehash reports whether the hash is not a bcrypt hash with the cost of the hasher
+func (hasher *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != hasher.cost
+}
+
+var defaultPasswordHasher = NewArgon2idHasher(0, 0, 0)
+
+// HashPassword returns the hash of the password with Argon2id and the default parameters
+func HashPassword(password string) (string, error) {
+	return defaultPasswordHasher.HashPassword(password)
+}
+
+// CheckPassword checks if the provided password is correct or not.
+// It accepts both Argon2id and legacy bcrypt hashes.
 func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	switch {
+	case strings.HasPrefix(hashedPassword, "$"+Argon2idAlgorithm+"$"):
+		hash, err := parseArgon2idHash(hashedPassword)
+		if err != nil {
+			return err
+		}
+
+		key := hash.derive(password, uint32(len(hash.key)))
+		if subtle.ConstantTimeCompare(key, hash.key) != 1 {
+			return ErrMismatchedPassword
+		}
+		return nil
+	case strings.HasPrefix(hashedPassword, "$2"):
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedPassword
+		}
+		return err
+	}
+	return ErrUnsupportedPassword
+}
+
+// argon2idHash is an Argon2id hash and the parameters it was derived with
+type argon2idHash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (hash argon2idHash) derive(password string, keyLength uint32) []byte {
+	return argon2.IDKey([]byte(password), hash.salt, hash.iterations, hash.memory, hash.parallelism, keyLength)
+}
+
+// String encodes the hash in the PHC string format
+func (hash argon2idHash) String() string {
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		Argon2idAlgorithm,
+		argon2.Version,
+		hash.memory,
+		hash.iterations,
+		hash.parallelism,
+		base64.RawStdEncoding.EncodeToString(hash.salt),
+		base64.RawStdEncoding.EncodeToString(hash.key),
+	)
+}
+
+func parseArgon2idHash(hashedPassword string) (argon2idHash, error) {
+	var hash argon2idHash
+
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != Argon2idAlgorithm {
+		return hash, errInvalidArgon2idFormat
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return hash, errInvalidArgon2idFormat
+	}
+
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.memory, &hash.iterations, &hash.parallelism)
+	if err != nil || hash.memory == 0 || hash.iterations == 0 || hash.parallelism == 0 {
+		return hash, errInvalidArgon2idFormat
+	}
+
+	hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return hash, errInvalidArgon2idFormat
+	}
+
+	hash.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash.key) == 0 {
+		return hash, errInvalidArgon2idFormat
+	}
+
+	return hash, nil
 }
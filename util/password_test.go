@@ -1,6 +1,7 @@
 package util
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -13,16 +14,87 @@ func TestPassword(t *testing.T) {
 	hashedPassword1, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword1)
+	require.True(t, strings.HasPrefix(hashedPassword1, "$argon2id$v=19$m=19456,t=2,p=1$"))
 
 	err = CheckPassword(password, hashedPassword1)
 	require.NoError(t, err)
 
 	wrongPassword := RandomString(6)
 	err = CheckPassword(wrongPassword, hashedPassword1)
-	require.EqualError(t, err, bcrypt.ErrMismatchedHashAndPassword.Error())
+	require.EqualError(t, err, ErrMismatchedPassword.Error())
 
 	hashedPassword2, err := HashPassword(password)
 	require.NoError(t, err)
 	require.NotEmpty(t, hashedPassword2)
 	require.NotEqual(t, hashedPassword1, hashedPassword2)
 }
+
+func TestLegacyBcryptPassword(t *testing.T) {
+	password := RandomString(6)
+
+	hashedPassword, err := NewBcryptHasher(bcrypt.MinCost).HashPassword(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$2a$"))
+
+	require.NoError(t, CheckPassword(password, hashedPassword))
+	require.ErrorIs(t, CheckPassword(RandomString(6), hashedPassword), ErrMismatchedPassword)
+}
+
+func TestLongPassword(t *testing.T) {
+	// bcrypt would ignore everything after the 72th byte
+	password := RandomString(100)
+
+	hashedPassword, err := HashPassword(password)
+	require.NoError(t, err)
+	require.NoError(t, CheckPassword(password, hashedPassword))
+	require.ErrorIs(t, CheckPassword(password[:72], hashedPassword), ErrMismatchedPassword)
+
+	_, err = NewBcryptHasher(bcrypt.MinCost).HashPassword(password)
+	require.ErrorIs(t, err, bcrypt.ErrPasswordTooLong)
+}
+
+func TestInvalidHashedPassword(t *testing.T) {
+	password := RandomString(6)
+
+	for _, hashedPassword := range []string{
+		"",
+		"plain text",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+		"$argon2id$v=16$m=19456,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=0,t=2,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$!",
+	} {
+		require.Error(t, CheckPassword(password, hashedPassword), hashedPassword)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	password := RandomString(6)
+
+	argon2idHasher := NewArgon2idHasher(0, 0, 0)
+	hashedPassword, err := argon2idHasher.HashPassword(password)
+	require.NoError(t, err)
+	require.False(t, argon2idHasher.NeedsRehash(hashedPassword))
+	require.True(t, NewArgon2idHasher(DefaultArgon2Memory, DefaultArgon2Iterations+1, DefaultArgon2Parallelism).NeedsRehash(hashedPassword))
+	require.True(t, NewBcryptHasher(bcrypt.MinCost).NeedsRehash(hashedPassword))
+
+	bcryptHasher := NewBcryptHasher(bcrypt.MinCost)
+	legacyHashedPassword, err := bcryptHasher.HashPassword(password)
+	require.NoError(t, err)
+	require.False(t, bcryptHasher.NeedsRehash(legacyHashedPassword))
+	require.True(t, NewBcryptHasher(bcrypt.MinCost+1).NeedsRehash(legacyHashedPassword))
+	require.True(t, argon2idHasher.NeedsRehash(legacyHashedPassword))
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	hasher, err := NewPasswordHasher(Config{})
+	require.NoError(t, err)
+	require.IsType(t, &Argon2idHasher{}, hasher)
+
+	hasher, err = NewPasswordHasher(Config{PasswordHashAlgorithm: BcryptAlgorithm})
+	require.NoError(t, err)
+	require.IsType(t, &BcryptHasher{}, hasher)
+
+	_, err = NewPasswordHasher(Config{PasswordHashAlgorithm: "md5"})
+	require.Error(t, err)
+}
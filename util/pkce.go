@@ -0,0 +1,27 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// PKCE code verifiers must be between 43 and 128 characters long (RFC 7636)
+const (
+	MinCodeVerifierLength = 43
+	MaxCodeVerifierLength = 128
+)
+
+// CodeChallengeS256 derives the S256 PKCE code challenge of a code verifier
+func CodeChallengeS256(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyCodeChallenge checks if the code verifier matches the S256 code challenge
+func VerifyCodeChallenge(codeVerifier string, codeChallenge string) bool {
+	if len(codeVerifier) < MinCodeVerifierLength || len(codeVerifier) > MaxCodeVerifierLength {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(CodeChallengeS256(codeVerifier)), []byte(codeChallenge)) == 1
+}
@@ -0,0 +1,27 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeChallengeS256(t *testing.T) {
+	// Test vector of RFC 7636, appendix B
+	codeVerifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	codeChallenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	require.Equal(t, codeChallenge, CodeChallengeS256(codeVerifier))
+	require.True(t, VerifyCodeChallenge(codeVerifier, codeChallenge))
+	require.False(t, VerifyCodeChallenge(RandomString(43), codeChallenge))
+}
+
+func TestCodeVerifierLength(t *testing.T) {
+	for _, codeVerifier := range []string{RandomString(42), RandomString(129)} {
+		require.False(t, VerifyCodeChallenge(codeVerifier, CodeChallengeS256(codeVerifier)))
+	}
+
+	for _, codeVerifier := range []string{RandomString(43), RandomString(128)} {
+		require.True(t, VerifyCodeChallenge(codeVerifier, CodeChallengeS256(codeVerifier)))
+	}
+}
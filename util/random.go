@@ -40,7 +40,7 @@ func RandomString(n int) string {
 // RandomOwner generates a random owner name
 // Now includes a prefix for better test identification
 func RandomOwner() string {
-	return "user_" + RandomString(6)
+	return "user" + RandomString(6)
 }
 
 // RandomMoney generates a random amount of money
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+// Frequencies of standing orders
+const (
+	// WeeklyFrequency repeats on the weekday of the start date
+	WeeklyFrequency = "weekly"
+	// MonthlyFrequency repeats on a day of the month, or on the last day of shorter months
+	MonthlyFrequency = "monthly"
+	// LastBusinessDayFrequency repeats on the last weekday of the month. Bank holidays are not taken into account.
+	LastBusinessDayFrequency = "last_business_day"
+)
+
+func IsSupportedFrequency(frequency string) bool {
+	switch frequency {
+	case WeeklyFrequency, MonthlyFrequency, LastBusinessDayFrequency:
+		return true
+	}
+	return false
+}
+
+// Recurrence is a calendar rule, repeating every Interval weeks or months from a start date.
+// Occurrences are at the time of day of the start date.
+type Recurrence struct {
+	Frequency string
+	Interval  int
+	// DayOfMonth is the day of monthly recurrences
+	DayOfMonth int
+}
+
+// Validate returns an error if the rule cannot produce occurrences
+func (recurrence Recurrence) Validate() error {
+	if !IsSupportedFrequency(recurrence.Frequency) {
+		return fmt.Errorf("unsupported frequency: %s", recurrence.Frequency)
+	}
+	if recurrence.Interval < 1 {
+		return fmt.Errorf("invalid interval: %d", recurrence.Interval)
+	}
+	if recurrence.Frequency == MonthlyFrequency && (recurrence.DayOfMonth < 1 || recurrence.DayOfMonth > 31) {
+		return fmt.Errorf("invalid day of month: %d", recurrence.DayOfMonth)
+	}
+	return nil
+}
+
+// First returns the first occurrence at or after the start date.
+func (recurrence Recurrence) First(start time.Time) time.Time {
+	return recurrence.Next(start, start.Add(-time.Nanosecond))
+}
+
+// Next returns the first occurrence of the rule started at start that is after the given time.
+// Occurrences are computed from the start date rather than from each other,
+// so that a monthly rule on the 31st falls back to the 28th in February only.
+func (recurrence Recurrence) Next(start time.Time, after time.Time) time.Time {
+	// skip the periods that are entirely before the given time
+	k := 0
+	if after.After(start) {
+		switch recurrence.Frequency {
+		case WeeklyFrequency:
+			k = int(after.Sub(start)/(7*24*time.Hour)) / recurrence.Interval
+		default:
+			months := (after.Year()-start.Year())*12 + int(after.Month()-start.Month())
+			k = max(months/recurrence.Interval-1, 0)
+		}
+	}
+
+	for ; ; k++ {
+		occurrence := recurrence.occurrence(start, k)
+		if occurrence.After(after) && !occurrence.Before(start) {
+			return occurrence
+		}
+	}
+}
+
+// occurrence returns the occurrence of the k-th period of the rule, which may be before the start date
+func (recurrence Recurrence) occurrence(start time.Time, k int) time.Time {
+	if recurrence.Frequency == WeeklyFrequency {
+		return start.AddDate(0, 0, 7*recurrence.Interval*k)
+	}
+
+	// normalized by time.Date when the month overflows the year
+	year, month := start.Year(), start.Month()+time.Month(recurrence.Interval*k)
+	hour, minute, second := start.Clock()
+	lastDay := time.Date(year, month+1, 0, hour, minute, second, start.Nanosecond(), start.Location())
+
+	if recurrence.Frequency == MonthlyFrequency {
+		if recurrence.DayOfMonth >= lastDay.Day() {
+			return lastDay
+		}
+		return lastDay.AddDate(0, 0, recurrence.DayOfMonth-lastDay.Day())
+	}
+
+	for lastDay.Weekday() == time.Saturday || lastDay.Weekday() == time.Sunday {
+		lastDay = lastDay.AddDate(0, 0, -1)
+	}
+	return lastDay
+}
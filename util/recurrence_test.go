@@ -0,0 +1,62 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+}
+
+func TestRecurrenceValidate(t *testing.T) {
+	require.NoError(t, Recurrence{Frequency: WeeklyFrequency, Interval: 1}.Validate())
+	require.NoError(t, Recurrence{Frequency: MonthlyFrequency, Interval: 1, DayOfMonth: 31}.Validate())
+	require.NoError(t, Recurrence{Frequency: LastBusinessDayFrequency, Interval: 3}.Validate())
+
+	require.Error(t, Recurrence{Frequency: "daily", Interval: 1}.Validate())
+	require.Error(t, Recurrence{Frequency: WeeklyFrequency}.Validate())
+	require.Error(t, Recurrence{Frequency: MonthlyFrequency, Interval: 1}.Validate())
+	require.Error(t, Recurrence{Frequency: MonthlyFrequency, Interval: 1, DayOfMonth: 32}.Validate())
+}
+
+func TestWeeklyRecurrence(t *testing.T) {
+	recurrence := Recurrence{Frequency: WeeklyFrequency, Interval: 2}
+	start := date(2026, time.January, 5)
+
+	require.Equal(t, start, recurrence.First(start))
+	require.Equal(t, date(2026, time.January, 19), recurrence.Next(start, start))
+	require.Equal(t, date(2026, time.February, 2), recurrence.Next(start, date(2026, time.January, 19)))
+	require.Equal(t, date(2026, time.March, 16), recurrence.Next(start, date(2026, time.March, 10)))
+}
+
+func TestMonthlyRecurrence(t *testing.T) {
+	recurrence := Recurrence{Frequency: MonthlyFrequency, Interval: 1, DayOfMonth: 31}
+	start := date(2026, time.January, 10)
+
+	require.Equal(t, date(2026, time.January, 31), recurrence.First(start))
+	// shorter months fall back to their last day, without shifting the following occurrences
+	require.Equal(t, date(2026, time.February, 28), recurrence.Next(start, date(2026, time.January, 31)))
+	require.Equal(t, date(2026, time.March, 31), recurrence.Next(start, date(2026, time.February, 28)))
+	require.Equal(t, date(2027, time.January, 31), recurrence.Next(start, date(2026, time.December, 31)))
+
+	// the day of the start month is skipped once it has passed
+	recurrence = Recurrence{Frequency: MonthlyFrequency, Interval: 3, DayOfMonth: 5}
+	require.Equal(t, date(2026, time.April, 5), recurrence.First(start))
+	require.Equal(t, date(2026, time.July, 5), recurrence.Next(start, date(2026, time.April, 5)))
+	require.Equal(t, date(2027, time.January, 5), recurrence.Next(start, date(2026, time.November, 20)))
+}
+
+func TestLastBusinessDayRecurrence(t *testing.T) {
+	recurrence := Recurrence{Frequency: LastBusinessDayFrequency, Interval: 1}
+	start := date(2026, time.January, 1)
+
+	// Saturday 31 January 2026 falls back to Friday 30
+	require.Equal(t, date(2026, time.January, 30), recurrence.First(start))
+	require.Equal(t, date(2026, time.February, 27), recurrence.Next(start, date(2026, time.January, 30)))
+	// Sunday 31 May 2026 falls back to Friday 29
+	require.Equal(t, date(2026, time.May, 29), recurrence.Next(start, date(2026, time.May, 1)))
+	require.Equal(t, date(2026, time.June, 30), recurrence.Next(start, date(2026, time.May, 29)))
+}
@@ -0,0 +1,28 @@
+package util
+
+import "strings"
+
+const (
+	AccountsReadScope   = "accounts:read"
+	AccountsWriteScope  = "accounts:write"
+	TransfersWriteScope = "transfers:write"
+)
+
+// IsSupportedScope returns true if the scope is supported
+func IsSupportedScope(scope string) bool {
+	switch scope {
+	case AccountsReadScope, AccountsWriteScope, TransfersWriteScope:
+		return true
+	}
+	return false
+}
+
+// JoinScopes encodes scopes as a space separated list
+func JoinScopes(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// SplitScopes decodes a space separated list of scopes
+func SplitScopes(scopes string) []string {
+	return strings.Fields(scopes)
+}
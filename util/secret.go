@@ -0,0 +1,85 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// SecretKeySize is the size of the keys encrypting secrets at rest
+const SecretKeySize = 32
+
+// EncryptSecret encrypts a secret with AES-256-GCM, prepending the random nonce to the ciphertext
+func EncryptSecret(key string, secret []byte) ([]byte, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, secret, nil), nil
+}
+
+// DecryptSecret decrypts a secret encrypted by EncryptSecret
+func DecryptSecret(key string, encryptedSecret []byte) ([]byte, error) {
+	gcm, err := newSecretCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encryptedSecret) < gcm.NonceSize() {
+		return nil, errors.New("encrypted secret is too short")
+	}
+
+	nonce, ciphertext := encryptedSecret[:gcm.NonceSize()], encryptedSecret[gcm.NonceSize():]
+	secret, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return secret, nil
+}
+
+func newSecretCipher(key string) (cipher.AEAD, error) {
+	if len(key) != SecretKeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", SecretKeySize)
+	}
+
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// HashSecret returns the SHA-256 hash of a random secret such as a recovery code.
+// Unlike passwords, such secrets have enough entropy not to need a slow hash.
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// secretAlphabet has 32 characters, so that random bytes map to it without bias
+const secretAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// RandomSecret generates a random secret of n characters using a cryptographic source
+func RandomSecret(n int) (string, error) {
+	bytes := make([]byte, n)
+	_, err := rand.Read(bytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+
+	for i := range bytes {
+		bytes[i] = secretAlphabet[bytes[i]%byte(len(secretAlphabet))]
+	}
+	return string(bytes), nil
+}
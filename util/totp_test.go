@@ -0,0 +1,81 @@
+package util
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// Test vectors of RFC 6238 appendix B, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	testCases := []struct {
+		time int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tc := range testCases {
+		code, err := GenerateTOTPCode(secret, TOTPStep(time.Unix(tc.time, 0)))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code)
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	require.NotEmpty(t, secret)
+
+	now := time.Now()
+	code, err := GenerateTOTPCode(secret, TOTPStep(now))
+	require.NoError(t, err)
+
+	step, ok := ValidateTOTPCode(secret, code, now)
+	require.True(t, ok)
+	require.Equal(t, TOTPStep(now), step)
+
+	// Codes of the previous period are accepted to tolerate clock drift
+	_, ok = ValidateTOTPCode(secret, code, now.Add(30*time.Second))
+	require.True(t, ok)
+
+	_, ok = ValidateTOTPCode(secret, code, now.Add(2*time.Minute))
+	require.False(t, ok)
+
+	_, ok = ValidateTOTPCode(secret, "abcdef", now)
+	require.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("OldBank", "alice", "SECRET")
+	require.True(t, strings.HasPrefix(uri, "otpauth://totp/OldBank:alice?"))
+	require.Contains(t, uri, "secret=SECRET")
+	require.Contains(t, uri, "issuer=OldBank")
+}
+
+func TestEncryptSecret(t *testing.T) {
+	key := RandomString(SecretKeySize)
+	secret := []byte(RandomString(16))
+
+	encryptedSecret, err := EncryptSecret(key, secret)
+	require.NoError(t, err)
+	require.NotEqual(t, secret, encryptedSecret)
+
+	decryptedSecret, err := DecryptSecret(key, encryptedSecret)
+	require.NoError(t, err)
+	require.Equal(t, secret, decryptedSecret)
+
+	_, err = DecryptSecret(RandomString(SecretKeySize), encryptedSecret)
+	require.Error(t, err)
+
+	_, err = EncryptSecret(RandomString(16), secret)
+	require.Error(t, err)
+}
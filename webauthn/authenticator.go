@@ -0,0 +1,144 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+)
+
+// SoftwareAuthenticator is an authenticator keeping a single ES256 credential in memory.
+// It runs the ceremonies like a browser and a security key would, to test relying parties.
+type SoftwareAuthenticator struct {
+	CredentialID []byte
+	// SignCount is incremented before each assertion
+	SignCount uint32
+	// SkipUserVerification leaves the user verified flag unset, like an authenticator without PIN
+	SkipUserVerification bool
+	privateKey           *ecdsa.PrivateKey
+}
+
+// NewSoftwareAuthenticator creates an authenticator with a new random credential
+func NewSoftwareAuthenticator() (*SoftwareAuthenticator, error) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticator := &SoftwareAuthenticator{
+		CredentialID: credentialID,
+		privateKey:   privateKey,
+	}
+	return authenticator, nil
+}
+
+// PublicKey returns the COSE encoded public key of the credential
+func (authenticator *SoftwareAuthenticator) PublicKey() []byte {
+	// The coordinates are padded to the size of the curve
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	authenticator.privateKey.X.FillBytes(x)
+	authenticator.privateKey.Y.FillBytes(y)
+
+	key, _ := encodeCBOR(map[interface{}]interface{}{
+		int64(coseKeyType):   int64(coseKeyTypeEC2),
+		int64(coseAlgorithm): int64(AlgES256),
+		int64(coseCurve):     int64(coseCurveP256),
+		int64(coseX):         x,
+		int64(coseY):         y,
+	})
+	return key
+}
+
+// Register creates the credential as navigator.credentials.create() would on the origin
+func (authenticator *SoftwareAuthenticator) Register(origin string, options CreationOptions) (RegistrationCredential, error) {
+	clientDataJSON, err := newClientDataJSON(ceremonyCreate, options.Challenge, origin)
+	if err != nil {
+		return RegistrationCredential{}, err
+	}
+
+	// The attested credential data starts with an AAGUID, all zeros for software authenticators
+	attestedCredentialData := make([]byte, 16)
+	attestedCredentialData = binary.BigEndian.AppendUint16(attestedCredentialData, uint16(len(authenticator.CredentialID)))
+	attestedCredentialData = append(attestedCredentialData, authenticator.CredentialID...)
+	attestedCredentialData = append(attestedCredentialData, authenticator.PublicKey()...)
+
+	authData := authenticator.authenticatorData(options.RP.ID, flagAttestedCredentialData)
+	authData = append(authData, attestedCredentialData...)
+
+	attestationObject, err := encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": authData,
+	})
+	if err != nil {
+		return RegistrationCredential{}, err
+	}
+
+	credential := RegistrationCredential{
+		RawID: authenticator.CredentialID,
+		Type:  "public-key",
+		Response: AttestationResponse{
+			ClientDataJSON:    clientDataJSON,
+			AttestationObject: attestationObject,
+		},
+	}
+	return credential, nil
+}
+
+// Login signs the challenge as navigator.credentials.get() would on the origin
+func (authenticator *SoftwareAuthenticator) Login(origin string, options RequestOptions) (AssertionCredential, error) {
+	clientDataJSON, err := newClientDataJSON(ceremonyGet, options.Challenge, origin)
+	if err != nil {
+		return AssertionCredential{}, err
+	}
+
+	authenticator.SignCount++
+	authData := authenticator.authenticatorData(options.RPID, 0)
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, authenticator.privateKey, digest[:])
+	if err != nil {
+		return AssertionCredential{}, err
+	}
+
+	credential := AssertionCredential{
+		RawID: authenticator.CredentialID,
+		Type:  "public-key",
+		Response: AssertionResponse{
+			ClientDataJSON:    clientDataJSON,
+			AuthenticatorData: authData,
+			Signature:         signature,
+		},
+	}
+	return credential, nil
+}
+
+func (authenticator *SoftwareAuthenticator) authenticatorData(rpID string, flags byte) []byte {
+	flags |= flagUserPresent
+	if !authenticator.SkipUserVerification {
+		flags |= flagUserVerified
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	authData := append(rpIDHash[:], flags)
+	return binary.BigEndian.AppendUint32(authData, authenticator.SignCount)
+}
+
+func newClientDataJSON(ceremony string, challenge []byte, origin string) ([]byte, error) {
+	return json.Marshal(clientData{
+		Type:      ceremony,
+		Challenge: base64.RawURLEncoding.EncodeToString(challenge),
+		Origin:    origin,
+	})
+}
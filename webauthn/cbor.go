@@ -0,0 +1,272 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// CBOR major types (RFC 8949)
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborText     = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+)
+
+// cborMaxDepth limits the nesting of decoded values, authenticators never send deep structures
+const cborMaxDepth = 16
+
+var errInvalidCBOR = errors.New("invalid CBOR data")
+
+// decodeCBOR decodes the first CBOR value of data and returns the bytes following it.
+// Only the subset used by WebAuthn is supported: integers are returned as int64,
+// byte strings as []byte, text strings as string, arrays as []interface{}
+// and maps as map[interface{}]interface{}.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	decoder := &cborDecoder{data: data}
+	value, err := decoder.decode(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, decoder.data[decoder.offset:], nil
+}
+
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+func (decoder *cborDecoder) next(n uint64) ([]byte, error) {
+	if n > uint64(len(decoder.data)-decoder.offset) {
+		return nil, errInvalidCBOR
+	}
+	b := decoder.data[decoder.offset : decoder.offset+int(n)]
+	decoder.offset += int(n)
+	return b, nil
+}
+
+// head reads the major type and argument of the next data item
+func (decoder *cborDecoder) head() (byte, uint64, error) {
+	b, err := decoder.next(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	majorType := b[0] >> 5
+	info := b[0] & 0x1f
+
+	switch {
+	case info < 24:
+		return majorType, uint64(info), nil
+	case info == 24:
+		b, err = decoder.next(1)
+		if err != nil {
+			return 0, 0, err
+		}
+		return majorType, uint64(b[0]), nil
+	case info == 25:
+		b, err = decoder.next(2)
+		if err != nil {
+			return 0, 0, err
+		}
+		return majorType, uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err = decoder.next(4)
+		if err != nil {
+			return 0, 0, err
+		}
+		return majorType, uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err = decoder.next(8)
+		if err != nil {
+			return 0, 0, err
+		}
+		return majorType, binary.BigEndian.Uint64(b), nil
+	}
+	// Indefinite lengths are not allowed in the canonical CBOR sent by authenticators
+	return 0, 0, errInvalidCBOR
+}
+
+func (decoder *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errInvalidCBOR
+	}
+
+	majorType, arg, err := decoder.head()
+	if err != nil {
+		return nil, err
+	}
+
+	switch majorType {
+	case cborUnsigned:
+		if arg > 1<<63-1 {
+			return nil, errInvalidCBOR
+		}
+		return int64(arg), nil
+	case cborNegative:
+		if arg > 1<<63-1 {
+			return nil, errInvalidCBOR
+		}
+		return -1 - int64(arg), nil
+	case cborBytes:
+		b, err := decoder.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case cborText:
+		b, err := decoder.next(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case cborArray:
+		if arg > uint64(len(decoder.data)) {
+			return nil, errInvalidCBOR
+		}
+		array := make([]interface{}, arg)
+		for i := range array {
+			array[i], err = decoder.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case cborMap:
+		if arg > uint64(len(decoder.data)) {
+			return nil, errInvalidCBOR
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := decoder.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, errInvalidCBOR
+			}
+			value, err := decoder.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	case cborTag:
+		// Tags only add semantics to the tagged value
+		return decoder.decode(depth + 1)
+	case cborSimple:
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+	}
+	return nil, errInvalidCBOR
+}
+
+// encodeCBOR encodes values of the types returned by decodeCBOR, as well as int and bool.
+// Map keys are sorted as required by the CTAP2 canonical CBOR encoding.
+func encodeCBOR(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := writeCBOR(&buf, value)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCBORHead(buf *bytes.Buffer, majorType byte, arg uint64) {
+	switch {
+	case arg < 24:
+		buf.WriteByte(majorType<<5 | byte(arg))
+	case arg <= 0xff:
+		buf.WriteByte(majorType<<5 | 24)
+		buf.WriteByte(byte(arg))
+	case arg <= 0xffff:
+		buf.WriteByte(majorType<<5 | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	case arg <= 0xffffffff:
+		buf.WriteByte(majorType<<5 | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	default:
+		buf.WriteByte(majorType<<5 | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, arg))
+	}
+}
+
+func writeCBOR(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case int:
+		return writeCBOR(buf, int64(v))
+	case int64:
+		if v >= 0 {
+			writeCBORHead(buf, cborUnsigned, uint64(v))
+		} else {
+			writeCBORHead(buf, cborNegative, uint64(-1-v))
+		}
+	case []byte:
+		writeCBORHead(buf, cborBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeCBORHead(buf, cborText, uint64(len(v)))
+		buf.WriteString(v)
+	case bool:
+		if v {
+			buf.WriteByte(cborSimple<<5 | 21)
+		} else {
+			buf.WriteByte(cborSimple<<5 | 20)
+		}
+	case nil:
+		buf.WriteByte(cborSimple<<5 | 22)
+	case []interface{}:
+		writeCBORHead(buf, cborArray, uint64(len(v)))
+		for _, item := range v {
+			err := writeCBOR(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+	case map[interface{}]interface{}:
+		keys := make([][]byte, 0, len(v))
+		values := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			encodedKey, err := encodeCBOR(key)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, encodedKey)
+			values[string(encodedKey)] = item
+		}
+		// Shorter keys first, then in bytewise lexical order
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return bytes.Compare(keys[i], keys[j]) < 0
+		})
+
+		writeCBORHead(buf, cborMap, uint64(len(v)))
+		for _, key := range keys {
+			buf.Write(key)
+			err := writeCBOR(buf, values[string(key)])
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T to CBOR", value)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package webauthn
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCBOR(t *testing.T) {
+	value := map[interface{}]interface{}{
+		"fmt":         "none",
+		int64(-1):     int64(1),
+		int64(3):      int64(-257),
+		"authData":    []byte{1, 2, 3},
+		"list":        []interface{}{int64(1000), int64(70000), int64(5000000000), true, false, nil},
+		"nested":      map[interface{}]interface{}{},
+		int64(1 << 8): "x",
+	}
+
+	encoded, err := encodeCBOR(value)
+	require.NoError(t, err)
+
+	decoded, rest, err := decodeCBOR(append(encoded, 0xff))
+	require.NoError(t, err)
+	require.Equal(t, value, decoded)
+	require.Equal(t, []byte{0xff}, rest)
+}
+
+func TestCBORCanonicalKeys(t *testing.T) {
+	encoded, err := encodeCBOR(map[interface{}]interface{}{
+		"b":       int64(0),
+		int64(-1): int64(0),
+		int64(1):  int64(0),
+		"a":       int64(0),
+	})
+	require.NoError(t, err)
+	// 1, -1, "a", "b"
+	require.Equal(t, []byte{0xa4, 0x01, 0x00, 0x20, 0x00, 0x61, 'a', 0x00, 0x61, 'b', 0x00}, encoded)
+}
+
+func TestInvalidCBOR(t *testing.T) {
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{
+			name: "Empty",
+			data: []byte{},
+		},
+		{
+			name: "TruncatedBytes",
+			data: []byte{0x44, 1, 2},
+		},
+		{
+			name: "TruncatedArgument",
+			data: []byte{0x19, 1},
+		},
+		{
+			name: "IndefiniteLength",
+			data: []byte{0x5f, 0x41, 1, 0xff},
+		},
+		{
+			name: "HugeArray",
+			data: []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		},
+		{
+			name: "ByteStringKey",
+			data: []byte{0xa1, 0x41, 1, 0x00},
+		},
+		{
+			name: "TooDeep",
+			data: append(bytes.Repeat([]byte{0x81}, 20), 0x00),
+		},
+		{
+			name: "Float",
+			data: []byte{0xf9, 0x3c, 0x00},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := decodeCBOR(tc.data)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithms supported for credentials, in order of preference (RFC 9053)
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+// SupportedAlgorithms lists the COSE algorithms accepted when registering a credential
+var SupportedAlgorithms = []int64{AlgES256, AlgEdDSA, AlgRS256}
+
+// COSE key parameters (RFC 9052)
+const (
+	coseKeyType   = 1
+	coseAlgorithm = 3
+	coseCurve     = -1
+	coseX         = -2
+	coseY         = -3
+	coseModulus   = -1
+	coseExponent  = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+var errUnsupportedKey = errors.New("unsupported credential public key")
+
+// publicKey verifies the signatures of a credential
+type publicKey interface {
+	algorithm() int64
+	verify(data []byte, signature []byte) bool
+}
+
+type es256Key struct {
+	key *ecdsa.PublicKey
+}
+
+func (key es256Key) algorithm() int64 {
+	return AlgES256
+}
+
+func (key es256Key) verify(data []byte, signature []byte) bool {
+	digest := sha256.Sum256(data)
+	return ecdsa.VerifyASN1(key.key, digest[:], signature)
+}
+
+type eddsaKey struct {
+	key ed25519.PublicKey
+}
+
+func (key eddsaKey) algorithm() int64 {
+	return AlgEdDSA
+}
+
+func (key eddsaKey) verify(data []byte, signature []byte) bool {
+	return ed25519.Verify(key.key, data, signature)
+}
+
+type rs256Key struct {
+	key *rsa.PublicKey
+}
+
+func (key rs256Key) algorithm() int64 {
+	return AlgRS256
+}
+
+func (key rs256Key) verify(data []byte, signature []byte) bool {
+	digest := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(key.key, crypto.SHA256, digest[:], signature) == nil
+}
+
+// parsePublicKey parses a COSE encoded public key, returning the bytes following it
+func parsePublicKey(data []byte) (publicKey, []byte, error) {
+	value, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, nil, errUnsupportedKey
+	}
+
+	keyType, _ := m[int64(coseKeyType)].(int64)
+	alg, _ := m[int64(coseAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && alg == AlgES256:
+		curve, _ := m[int64(coseCurve)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, nil, errUnsupportedKey
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, nil, errUnsupportedKey
+		}
+		return es256Key{key: key}, rest, nil
+	case keyType == coseKeyTypeOKP && alg == AlgEdDSA:
+		curve, _ := m[int64(coseCurve)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, nil, errUnsupportedKey
+		}
+		return eddsaKey{key: ed25519.PublicKey(x)}, rest, nil
+	case keyType == coseKeyTypeRSA && alg == AlgRS256:
+		n, _ := m[int64(coseModulus)].([]byte)
+		e, _ := m[int64(coseExponent)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, nil, errUnsupportedKey
+		}
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return rs256Key{key: key}, rest, nil
+	}
+	return nil, nil, errUnsupportedKey
+}
@@ -0,0 +1,418 @@
+// Package webauthn verifies the registration and authentication ceremonies of
+// WebAuthn credentials (https://www.w3.org/TR/webauthn-3/), so that users can
+// log in with passkeys instead of passwords.
+package webauthn
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Types of the ceremonies in the client data
+const (
+	ceremonyCreate = "webauthn.create"
+	ceremonyGet    = "webauthn.get"
+)
+
+// Flags of the authenticator data
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+)
+
+const (
+	// ChallengeSize is the minimal size of the challenges, in bytes
+	ChallengeSize = 16
+	// maxCredentialIDLength is the maximal length of credential IDs, in bytes
+	maxCredentialIDLength = 1023
+	// userVerificationRequired asks authenticators to verify the user, with a PIN or biometrics,
+	// so that a passkey is enough to log in without a password or second factor
+	userVerificationRequired = "required"
+)
+
+// Different types of error returned when verifying a ceremony
+var (
+	ErrInvalidClientData  = errors.New("invalid WebAuthn client data")
+	ErrInvalidAttestation = errors.New("invalid WebAuthn attestation")
+	ErrInvalidAssertion   = errors.New("invalid WebAuthn assertion")
+	ErrUserNotVerified    = errors.New("WebAuthn user was not verified")
+	ErrSignCountRegressed = errors.New("WebAuthn signature counter did not increase, the authenticator may be cloned")
+)
+
+// RelyingParty is the website credentials are registered with
+type RelyingParty struct {
+	// ID is the domain of the website, credentials can only be used on this domain and its subdomains
+	ID string
+	// Name is the name of the website shown by authenticators
+	Name string
+	// Origins are the origins allowed to run the ceremonies, like https://oldbank.example
+	Origins []string
+	// Timeout is how long the user has to complete a ceremony
+	Timeout time.Duration
+}
+
+// Base64URL is a byte slice encoded in JSON as unpadded base64url, like the buffers of WebAuthn
+type Base64URL []byte
+
+// MarshalJSON encodes the bytes as an unpadded base64url string
+func (b Base64URL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(base64.RawURLEncoding.EncodeToString(b))
+}
+
+// UnmarshalJSON decodes a base64url string, with or without padding
+func (b *Base64URL) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return err
+	}
+	*b = decoded
+	return nil
+}
+
+// User is the account a credential is registered for
+type User struct {
+	ID          Base64URL `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"displayName"`
+}
+
+// CredentialDescriptor identifies a credential registered before
+type CredentialDescriptor struct {
+	Type string    `json:"type"`
+	ID   Base64URL `json:"id"`
+}
+
+type relyingPartyEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// CreationOptions are the options of navigator.credentials.create() to register a credential
+type CreationOptions struct {
+	Challenge              Base64URL              `json:"challenge"`
+	RP                     relyingPartyEntity     `json:"rp"`
+	User                   User                   `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions are the options of navigator.credentials.get() to authenticate with a credential
+type RequestOptions struct {
+	Challenge        Base64URL              `json:"challenge"`
+	RPID             string                 `json:"rpId"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// AttestationResponse is the response of an authenticator registering a credential
+type AttestationResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" binding:"required"`
+	AttestationObject Base64URL `json:"attestationObject" binding:"required"`
+}
+
+// AssertionResponse is the response of an authenticator authenticating with a credential
+type AssertionResponse struct {
+	ClientDataJSON    Base64URL `json:"clientDataJSON" binding:"required"`
+	AuthenticatorData Base64URL `json:"authenticatorData" binding:"required"`
+	Signature         Base64URL `json:"signature" binding:"required"`
+	UserHandle        Base64URL `json:"userHandle"`
+}
+
+// RegistrationCredential is the JSON encoding of the PublicKeyCredential returned by navigator.credentials.create()
+type RegistrationCredential struct {
+	RawID    Base64URL           `json:"rawId" binding:"required"`
+	Type     string              `json:"type" binding:"required,eq=public-key"`
+	Response AttestationResponse `json:"response"`
+}
+
+// AssertionCredential is the JSON encoding of the PublicKeyCredential returned by navigator.credentials.get()
+type AssertionCredential struct {
+	RawID    Base64URL         `json:"rawId" binding:"required"`
+	Type     string            `json:"type" binding:"required,eq=public-key"`
+	Response AssertionResponse `json:"response"`
+}
+
+// Credential is a credential verified by a registration ceremony
+type Credential struct {
+	ID []byte
+	// PublicKey is the COSE encoded public key of the credential
+	PublicKey []byte
+	SignCount uint32
+}
+
+// CreationOptions returns the options to register a credential for the user,
+// excluding the credentials the user already registered.
+func (rp RelyingParty) CreationOptions(challenge []byte, user User, excludeCredentials [][]byte) CreationOptions {
+	params := make([]credentialParameter, len(SupportedAlgorithms))
+	for i, alg := range SupportedAlgorithms {
+		params[i] = credentialParameter{Type: "public-key", Alg: alg}
+	}
+
+	return CreationOptions{
+		Challenge:          challenge,
+		RP:                 relyingPartyEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		PubKeyCredParams:   params,
+		Timeout:            rp.Timeout.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(excludeCredentials),
+		AuthenticatorSelection: authenticatorSelection{
+			// logins never list the credentials of a user, so they must be discoverable
+			ResidentKey:      "required",
+			UserVerification: userVerificationRequired,
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options to authenticate with one of the allowed credentials.
+// Without allowed credentials, the user picks any passkey registered on the website.
+func (rp RelyingParty) RequestOptions(challenge []byte, allowCredentials [][]byte) RequestOptions {
+	return RequestOptions{
+		Challenge:        challenge,
+		RPID:             rp.ID,
+		Timeout:          rp.Timeout.Milliseconds(),
+		AllowCredentials: credentialDescriptors(allowCredentials),
+		UserVerification: userVerificationRequired,
+	}
+}
+
+func credentialDescriptors(ids [][]byte) []CredentialDescriptor {
+	descriptors := make([]CredentialDescriptor, len(ids))
+	for i, id := range ids {
+		descriptors[i] = CredentialDescriptor{Type: "public-key", ID: id}
+	}
+	return descriptors
+}
+
+// VerifyRegistration checks the response of an authenticator to the registration challenge
+// and returns the new credential (https://www.w3.org/TR/webauthn-3/#sctn-registering-a-new-credential).
+func (rp RelyingParty) VerifyRegistration(challenge []byte, response AttestationResponse) (*Credential, error) {
+	err := rp.verifyClientData(response.ClientDataJSON, ceremonyCreate, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	value, _, err := decodeCBOR(response.AttestationObject)
+	if err != nil {
+		return nil, ErrInvalidAttestation
+	}
+	attestation, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return nil, ErrInvalidAttestation
+	}
+	format, _ := attestation["fmt"].(string)
+	statement, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+
+	authData, err := rp.parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, ErrInvalidAttestation
+	}
+	if authData.flags&flagUserVerified == 0 {
+		return nil, ErrUserNotVerified
+	}
+	if authData.flags&flagAttestedCredentialData == 0 || authData.publicKey == nil {
+		return nil, ErrInvalidAttestation
+	}
+
+	clientDataHash := sha256.Sum256(response.ClientDataJSON)
+	err = verifyAttestationStatement(format, statement, authData, rawAuthData, clientDataHash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &Credential{
+		ID:        authData.credentialID,
+		PublicKey: authData.rawPublicKey,
+		SignCount: authData.signCount,
+	}
+	return credential, nil
+}
+
+// verifyAttestationStatement checks the attestation of the authenticator. The options ask for no attestation,
+// so attestation certificates are only used to check the signature, not trusted to identify the authenticator.
+func verifyAttestationStatement(format string, statement map[interface{}]interface{}, authData *authenticatorData, rawAuthData []byte, clientDataHash []byte) error {
+	switch format {
+	case "none":
+		if len(statement) != 0 {
+			return ErrInvalidAttestation
+		}
+		return nil
+	case "packed":
+		alg, _ := statement["alg"].(int64)
+		signature, _ := statement["sig"].([]byte)
+		signedData := append(append([]byte{}, rawAuthData...), clientDataHash...)
+
+		chain, hasCertificates := statement["x5c"].([]interface{})
+		if !hasCertificates {
+			// Self attestation is signed by the credential itself
+			if alg != authData.publicKey.algorithm() || !authData.publicKey.verify(signedData, signature) {
+				return ErrInvalidAttestation
+			}
+			return nil
+		}
+
+		if len(chain) == 0 {
+			return ErrInvalidAttestation
+		}
+		der, _ := chain[0].([]byte)
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return ErrInvalidAttestation
+		}
+		signatureAlgorithm, ok := map[int64]x509.SignatureAlgorithm{
+			AlgES256: x509.ECDSAWithSHA256,
+			AlgEdDSA: x509.PureEd25519,
+			AlgRS256: x509.SHA256WithRSA,
+		}[alg]
+		if !ok || certificate.CheckSignature(signatureAlgorithm, signedData, signature) != nil {
+			return ErrInvalidAttestation
+		}
+		return nil
+	}
+	return ErrInvalidAttestation
+}
+
+// VerifyAssertion checks the response of an authenticator to the authentication challenge,
+// with the public key and signature counter stored for the credential. It returns the new
+// signature counter (https://www.w3.org/TR/webauthn-3/#sctn-verifying-assertion).
+func (rp RelyingParty) VerifyAssertion(challenge []byte, credentialPublicKey []byte, storedSignCount uint32, response AssertionResponse) (uint32, error) {
+	err := rp.verifyClientData(response.ClientDataJSON, ceremonyGet, challenge)
+	if err != nil {
+		return 0, err
+	}
+
+	authData, err := rp.parseAuthenticatorData(response.AuthenticatorData)
+	if err != nil {
+		return 0, ErrInvalidAssertion
+	}
+	if authData.flags&flagUserVerified == 0 {
+		return 0, ErrUserNotVerified
+	}
+
+	key, _, err := parsePublicKey(credentialPublicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(response.ClientDataJSON)
+	signedData := append(append([]byte{}, response.AuthenticatorData...), clientDataHash[:]...)
+	if !key.verify(signedData, response.Signature) {
+		return 0, ErrInvalidAssertion
+	}
+
+	// Authenticators without a counter, like most synced passkeys, always send 0
+	if (authData.signCount != 0 || storedSignCount != 0) && authData.signCount <= storedSignCount {
+		return 0, ErrSignCountRegressed
+	}
+	return authData.signCount, nil
+}
+
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+func (rp RelyingParty) verifyClientData(clientDataJSON []byte, ceremony string, challenge []byte) error {
+	var data clientData
+	err := json.Unmarshal(clientDataJSON, &data)
+	if err != nil {
+		return ErrInvalidClientData
+	}
+	if data.Type != ceremony || data.CrossOrigin {
+		return ErrInvalidClientData
+	}
+
+	expectedChallenge := base64.RawURLEncoding.EncodeToString(challenge)
+	if len(challenge) < ChallengeSize || subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(expectedChallenge)) != 1 {
+		return ErrInvalidClientData
+	}
+
+	for _, origin := range rp.Origins {
+		if data.Origin == origin {
+			return nil
+		}
+	}
+	return ErrInvalidClientData
+}
+
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	credentialID []byte
+	rawPublicKey []byte
+	publicKey    publicKey
+}
+
+// parseAuthenticatorData parses the authenticator data and checks it was created for the relying party
+// by a user who was present (https://www.w3.org/TR/webauthn-3/#sctn-authenticator-data).
+func (rp RelyingParty) parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, ErrInvalidAssertion
+	}
+
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(data[:32], rpIDHash[:]) {
+		return nil, ErrInvalidAssertion
+	}
+
+	authData := &authenticatorData{
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return nil, ErrInvalidAssertion
+	}
+
+	if authData.flags&flagAttestedCredentialData != 0 {
+		// The AAGUID of the authenticator is followed by the length of the credential ID
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, ErrInvalidAttestation
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLength == 0 || idLength > maxCredentialIDLength || len(rest) < idLength {
+			return nil, ErrInvalidAttestation
+		}
+		authData.credentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		key, extensions, err := parsePublicKey(rest)
+		if err != nil {
+			return nil, err
+		}
+		authData.publicKey = key
+		authData.rawPublicKey = rest[:len(rest)-len(extensions)]
+	}
+	return authData, nil
+}
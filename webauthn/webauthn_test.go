@@ -0,0 +1,340 @@
+package webauthn
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testOrigin = "https://oldbank.example"
+
+func newRelyingParty() RelyingParty {
+	return RelyingParty{
+		ID:      "oldbank.example",
+		Name:    "OldBank",
+		Origins: []string{testOrigin},
+		Timeout: time.Minute,
+	}
+}
+
+func randomChallenge(t *testing.T) []byte {
+	challenge := make([]byte, 32)
+	_, err := rand.Read(challenge)
+	require.NoError(t, err)
+	return challenge
+}
+
+func registerAuthenticator(t *testing.T, rp RelyingParty) (*SoftwareAuthenticator, *Credential) {
+	authenticator, err := NewSoftwareAuthenticator()
+	require.NoError(t, err)
+
+	challenge := randomChallenge(t)
+	options := rp.CreationOptions(challenge, User{ID: []byte("user"), Name: "user"}, nil)
+	response, err := authenticator.Register(testOrigin, options)
+	require.NoError(t, err)
+
+	credential, err := rp.VerifyRegistration(challenge, response.Response)
+	require.NoError(t, err)
+	return authenticator, credential
+}
+
+func TestRegistration(t *testing.T) {
+	rp := newRelyingParty()
+
+	testCases := []struct {
+		name          string
+		buildResponse func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse
+		checkResult   func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error)
+	}{
+		{
+			name: "OK",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				response, err := authenticator.Register(testOrigin, rp.CreationOptions(challenge, User{}, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.NoError(t, err)
+				require.Equal(t, authenticator.CredentialID, credential.ID)
+				require.Equal(t, authenticator.PublicKey(), credential.PublicKey)
+				require.Zero(t, credential.SignCount)
+			},
+		},
+		{
+			name: "PackedSelfAttestation",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				response, err := authenticator.Register(testOrigin, rp.CreationOptions(challenge, User{}, nil))
+				require.NoError(t, err)
+
+				value, _, err := decodeCBOR(response.Response.AttestationObject)
+				require.NoError(t, err)
+				attestation := value.(map[interface{}]interface{})
+				authData := attestation["authData"].([]byte)
+
+				clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+				digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+				signature, err := ecdsa.SignASN1(rand.Reader, authenticator.privateKey, digest[:])
+				require.NoError(t, err)
+
+				attestation["fmt"] = "packed"
+				attestation["attStmt"] = map[interface{}]interface{}{
+					"alg": int64(AlgES256),
+					"sig": signature,
+				}
+				response.Response.AttestationObject, err = encodeCBOR(attestation)
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.NoError(t, err)
+				require.Equal(t, authenticator.CredentialID, credential.ID)
+			},
+		},
+		{
+			name: "PackedInvalidSignature",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				response, err := authenticator.Register(testOrigin, rp.CreationOptions(challenge, User{}, nil))
+				require.NoError(t, err)
+
+				value, _, err := decodeCBOR(response.Response.AttestationObject)
+				require.NoError(t, err)
+				attestation := value.(map[interface{}]interface{})
+				attestation["fmt"] = "packed"
+				attestation["attStmt"] = map[interface{}]interface{}{
+					"alg": int64(AlgES256),
+					"sig": []byte("signature"),
+				}
+				response.Response.AttestationObject, err = encodeCBOR(attestation)
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.EqualError(t, err, ErrInvalidAttestation.Error())
+			},
+		},
+		{
+			name: "WrongOrigin",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				response, err := authenticator.Register("https://oldbank.example.evil", rp.CreationOptions(challenge, User{}, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.EqualError(t, err, ErrInvalidClientData.Error())
+			},
+		},
+		{
+			name: "WrongChallenge",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				response, err := authenticator.Register(testOrigin, rp.CreationOptions(randomChallenge(t), User{}, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.EqualError(t, err, ErrInvalidClientData.Error())
+			},
+		},
+		{
+			name: "WrongRelyingParty",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				options := rp.CreationOptions(challenge, User{}, nil)
+				options.RP.ID = "evil.example"
+				response, err := authenticator.Register(testOrigin, options)
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.Error(t, err)
+			},
+		},
+		{
+			name: "UserNotVerified",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				authenticator.SkipUserVerification = true
+				response, err := authenticator.Register(testOrigin, rp.CreationOptions(challenge, User{}, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.EqualError(t, err, ErrUserNotVerified.Error())
+			},
+		},
+		{
+			name: "InvalidAttestationObject",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AttestationResponse {
+				response, err := authenticator.Register(testOrigin, rp.CreationOptions(challenge, User{}, nil))
+				require.NoError(t, err)
+				response.Response.AttestationObject = response.Response.AttestationObject[:40]
+				return response.Response
+			},
+			checkResult: func(t *testing.T, authenticator *SoftwareAuthenticator, credential *Credential, err error) {
+				require.EqualError(t, err, ErrInvalidAttestation.Error())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			authenticator, err := NewSoftwareAuthenticator()
+			require.NoError(t, err)
+
+			challenge := randomChallenge(t)
+			response := tc.buildResponse(t, authenticator, challenge)
+
+			credential, err := rp.VerifyRegistration(challenge, response)
+			tc.checkResult(t, authenticator, credential, err)
+		})
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	rp := newRelyingParty()
+
+	testCases := []struct {
+		name            string
+		storedSignCount uint32
+		buildResponse   func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse
+		checkResult     func(t *testing.T, signCount uint32, err error)
+	}{
+		{
+			name: "OK",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				response, err := authenticator.Login(testOrigin, rp.RequestOptions(challenge, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.NoError(t, err)
+				require.Equal(t, uint32(1), signCount)
+			},
+		},
+		{
+			name:            "SignCountRegressed",
+			storedSignCount: 5,
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				authenticator.SignCount = 4
+				response, err := authenticator.Login(testOrigin, rp.RequestOptions(challenge, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.EqualError(t, err, ErrSignCountRegressed.Error())
+			},
+		},
+		{
+			name: "NoSignCount",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				authenticator.SignCount = 0
+				response, err := authenticator.Login(testOrigin, rp.RequestOptions(challenge, nil))
+				require.NoError(t, err)
+				// Synced passkeys do not count their signatures
+				authData := response.Response.AuthenticatorData
+				copy(authData[33:37], []byte{0, 0, 0, 0})
+
+				clientDataHash := sha256.Sum256(response.Response.ClientDataJSON)
+				digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+				response.Response.Signature, err = ecdsa.SignASN1(rand.Reader, authenticator.privateKey, digest[:])
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.NoError(t, err)
+				require.Zero(t, signCount)
+			},
+		},
+		{
+			name: "InvalidSignature",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				response, err := authenticator.Login(testOrigin, rp.RequestOptions(challenge, nil))
+				require.NoError(t, err)
+				response.Response.AuthenticatorData[36]++
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.EqualError(t, err, ErrInvalidAssertion.Error())
+			},
+		},
+		{
+			name: "OtherAuthenticator",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				other, err := NewSoftwareAuthenticator()
+				require.NoError(t, err)
+				response, err := other.Login(testOrigin, rp.RequestOptions(challenge, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.EqualError(t, err, ErrInvalidAssertion.Error())
+			},
+		},
+		{
+			name: "RegistrationClientData",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				response, err := authenticator.Login(testOrigin, rp.RequestOptions(challenge, nil))
+				require.NoError(t, err)
+				response.Response.ClientDataJSON, err = newClientDataJSON(ceremonyCreate, challenge, testOrigin)
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.EqualError(t, err, ErrInvalidClientData.Error())
+			},
+		},
+		{
+			name: "WrongChallenge",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				response, err := authenticator.Login(testOrigin, rp.RequestOptions(randomChallenge(t), nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.EqualError(t, err, ErrInvalidClientData.Error())
+			},
+		},
+		{
+			name: "WrongRelyingParty",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				options := rp.RequestOptions(challenge, nil)
+				options.RPID = "evil.example"
+				response, err := authenticator.Login(testOrigin, options)
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.EqualError(t, err, ErrInvalidAssertion.Error())
+			},
+		},
+		{
+			name: "UserNotVerified",
+			buildResponse: func(t *testing.T, authenticator *SoftwareAuthenticator, challenge []byte) AssertionResponse {
+				authenticator.SkipUserVerification = true
+				response, err := authenticator.Login(testOrigin, rp.RequestOptions(challenge, nil))
+				require.NoError(t, err)
+				return response.Response
+			},
+			checkResult: func(t *testing.T, signCount uint32, err error) {
+				require.EqualError(t, err, ErrUserNotVerified.Error())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			authenticator, credential := registerAuthenticator(t, rp)
+
+			challenge := randomChallenge(t)
+			response := tc.buildResponse(t, authenticator, challenge)
+
+			signCount, err := rp.VerifyAssertion(challenge, credential.PublicKey, tc.storedSignCount, response)
+			tc.checkResult(t, signCount, err)
+		})
+	}
+}